@@ -11,6 +11,7 @@ import (
 
 	"spotiflac/backend"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -18,6 +19,13 @@ import (
 
 type App struct {
 	ctx context.Context
+
+	analysisMu     sync.Mutex
+	analysisCancel context.CancelFunc
+	analysisReport *backend.BatchAnalysisReport
+
+	convertMu     sync.Mutex
+	convertCancel context.CancelFunc
 }
 
 func NewApp() *App {
@@ -43,10 +51,21 @@ func (a *App) startup(ctx context.Context) {
 	if err := backend.InitHistoryDB("SpotiFLAC"); err != nil {
 		fmt.Printf("Failed to init history DB: %v\n", err)
 	}
+
+	go func() {
+		if removed, err := backend.PruneAnalysisCache(); err != nil {
+			fmt.Printf("Failed to prune analysis cache: %v\n", err)
+		} else if removed > 0 {
+			fmt.Printf("Pruned %d stale analysis cache entries\n", removed)
+		}
+	}()
 }
 
 func (a *App) shutdown(ctx context.Context) {
 	backend.CloseHistoryDB()
+	backend.CloseAnalysisCacheDB()
+	backend.CloseMirrorDB()
+	backend.CloseRenameJournalDB()
 }
 
 type SpotifyMetadataRequest struct {
@@ -88,6 +107,7 @@ type DownloadRequest struct {
 	PlaylistOwner        string `json:"playlist_owner,omitempty"`
 	AllowFallback        bool   `json:"allow_fallback"`
 	UseFirstArtistOnly   bool   `json:"use_first_artist_only,omitempty"`
+	FallbackPending      bool   `json:"fallback_pending,omitempty"`
 }
 
 type DownloadResponse struct {
@@ -230,12 +250,6 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 	if req.OutputDir == "" {
 		req.OutputDir = "."
 	} else {
-
-		if req.PlaylistName != "" {
-			sanitizedPlaylist := backend.SanitizeFilename(req.PlaylistName)
-			req.OutputDir = filepath.Join(req.OutputDir, sanitizedPlaylist)
-		}
-
 		req.OutputDir = backend.SanitizeFolderPath(req.OutputDir)
 	}
 
@@ -243,6 +257,8 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		req.AudioFormat = "LOSSLESS"
 	}
 
+	coverOptions := a.coverImageOptions()
+
 	var err error
 	var filename string
 
@@ -315,8 +331,13 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}
 	}
 
+	templateData := backend.DownloadTemplateData(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.PlaylistName, req.PlaylistOwner, req.Position, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.UseAlbumTrackNumber, req.UseFirstArtistOnly)
+	if folder := backend.BuildFolderPath(a.folderLayout(), templateData); folder != "" {
+		req.OutputDir = filepath.Join(req.OutputDir, filepath.FromSlash(folder))
+	}
+
 	if req.TrackName != "" && req.ArtistName != "" {
-		expectedFilename := backend.BuildExpectedFilename(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.SpotifyDiscNumber, req.UseAlbumTrackNumber)
+		expectedFilename := backend.BuildTrackFilename(req.FilenameFormat, templateData, req.TrackNumber, ".flac")
 		expectedPath := filepath.Join(req.OutputDir, expectedFilename)
 
 		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 {
@@ -361,43 +382,44 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		close(isrcChan)
 	}
 
+	var isrc string
 	switch req.Service {
 	case "amazon":
 
-		downloader := backend.NewAmazonDownloader()
+		downloader := backend.NewAmazonDownloader().WithCoverOptions(coverOptions)
 		if req.ServiceURL != "" {
-			filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseFirstArtistOnly)
+			filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseAlbumTrackNumber, req.UseFirstArtistOnly)
 		} else {
-			filename, err = downloader.DownloadBySpotifyID(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseFirstArtistOnly)
+			filename, err = downloader.DownloadBySpotifyID(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.UseAlbumTrackNumber, req.UseFirstArtistOnly)
 		}
 
 	case "tidal":
 		if req.ApiURL == "" || req.ApiURL == "auto" {
-			downloader := backend.NewTidalDownloader("")
+			downloader := backend.NewTidalDownloader("").WithCoverOptions(coverOptions)
 			if req.ServiceURL != "" {
-				filename, err = downloader.DownloadByURLWithFallback(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
+				filename, err = downloader.DownloadByURLWithFallback(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
 			} else {
-				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
+				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
 			}
 		} else {
-			downloader := backend.NewTidalDownloader(req.ApiURL)
+			downloader := backend.NewTidalDownloader(req.ApiURL).WithCoverOptions(coverOptions)
 			if req.ServiceURL != "" {
-				filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
+				filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
 			} else {
-				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
+				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
 			}
 		}
 
 	case "qobuz":
 
 		fmt.Println("Waiting for ISRC (Qobuz dependency)...")
-		isrc := <-isrcChan
-		downloader := backend.NewQobuzDownloader()
+		isrc = <-isrcChan
+		downloader := backend.NewQobuzDownloader().WithCoverOptions(coverOptions)
 		quality := req.AudioFormat
 		if quality == "" {
 			quality = "6"
 		}
-		filename, err = downloader.DownloadTrackWithISRC(isrc, req.SpotifyID, req.OutputDir, quality, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
+		filename, err = downloader.DownloadTrackWithISRC(isrc, req.SpotifyID, req.OutputDir, quality, req.FilenameFormat, req.PlaylistName, req.PlaylistOwner, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL, req.AllowFallback, req.UseFirstArtistOnly)
 
 	default:
 		return DownloadResponse{
@@ -408,6 +430,21 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 
 	if err != nil {
 		backend.FailDownloadItem(itemID, fmt.Sprintf("Download failed: %v", err))
+		if !req.FallbackPending {
+			backend.AddHistoryItem(backend.HistoryItem{
+				SpotifyID:   req.SpotifyID,
+				Title:       req.TrackName,
+				Artists:     req.ArtistName,
+				Album:       req.AlbumName,
+				AlbumArtist: req.AlbumArtist,
+				CoverURL:    req.CoverURL,
+				Format:      req.AudioFormat,
+				Provider:    req.Service,
+				ISRC:        isrc,
+				Status:      backend.HistoryStatusFailed,
+				Error:       err.Error(),
+			}, "SpotiFLAC")
+		}
 
 		if filename != "" && !strings.HasPrefix(filename, "EXISTS:") {
 
@@ -472,13 +509,20 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			backend.CompleteDownloadItem(itemID, filename, 0)
 		}
 
-		go func(fPath, track, artist, album, sID, cover, format string) {
+		go func(fPath, track, artist, album, albumArtist, sID, cover, format, provider, isrc string) {
 			quality := "Unknown"
 			durationStr := "--:--"
+			codec := ""
+			lossy := false
 
 			meta, err := backend.GetTrackMetadata(fPath)
 			if err == nil && meta != nil {
-				quality = fmt.Sprintf("%d-bit/%.1fkHz", meta.BitsPerSample, float64(meta.SampleRate)/1000.0)
+				codec, lossy = meta.Codec, meta.Lossy
+				bitDepth := meta.BitDepth
+				if lossy {
+					bitDepth = strings.ToUpper(codec)
+				}
+				quality = fmt.Sprintf("%s/%.1fkHz", bitDepth, float64(meta.SampleRate)/1000.0)
 				d := int(meta.Duration)
 				durationStr = fmt.Sprintf("%d:%02d", d/60, d%60)
 			} else {
@@ -493,8 +537,22 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 				DurationStr: durationStr,
 				CoverURL:    cover,
 				Quality:     quality,
+				Codec:       codec,
+				Lossy:       lossy,
 				Format:      format,
 				Path:        fPath,
+				AlbumArtist: albumArtist,
+				Provider:    provider,
+				ISRC:        isrc,
+				Status:      backend.HistoryStatusCompleted,
+			}
+
+			if item.ISRC == "" {
+				select {
+				case v := <-isrcChan:
+					item.ISRC = v
+				case <-time.After(5 * time.Second):
+				}
 			}
 
 			if item.Format == "" || item.Format == "LOSSLESS" {
@@ -509,8 +567,35 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 				item.Format = "FLAC"
 			}
 
+			settings, _ := a.LoadSettings()
+			if settingBool(settings, "autoQualityCheck", true) && strings.EqualFold(filepath.Ext(fPath), ".flac") {
+				analysis, err := backend.AnalyzeTrackCached(fPath)
+				if err != nil {
+					fmt.Printf("Warning: quality check failed: %v\n", err)
+				} else if analysis.Verdict != nil {
+					item.Verdict = analysis.Verdict.Status
+					item.VerdictNote = analysis.Verdict.Summary
+					item.Suspicious = analysis.Verdict.Suspicious
+					if item.Suspicious {
+						fmt.Printf("Quality check flagged %s: %s\n", fPath, item.VerdictNote)
+					}
+				}
+			}
+
+			if settingBool(settings, "replayGainAfterDownload", false) {
+				for _, result := range backend.ApplyReplayGain([]string{fPath}, false) {
+					if result.Error != "" {
+						fmt.Printf("Warning: failed to apply ReplayGain: %s\n", result.Error)
+					}
+				}
+			}
+
+			if fileInfo, err := os.Stat(fPath); err == nil {
+				item.FileSize = fileInfo.Size()
+			}
+
 			backend.AddHistoryItem(item, "SpotiFLAC")
-		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID, req.CoverURL, req.AudioFormat)
+		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.SpotifyID, req.CoverURL, req.AudioFormat, req.Service, isrc)
 	}
 
 	return DownloadResponse{
@@ -656,6 +741,24 @@ func (a *App) GetDownloadHistory() ([]backend.HistoryItem, error) {
 	return backend.GetHistoryItems("SpotiFLAC")
 }
 
+func (a *App) QueryDownloadHistory(query backend.HistoryQuery) (backend.HistoryQueryResult, error) {
+	return backend.QueryHistory(query, "SpotiFLAC")
+}
+
+func (a *App) GetDownloadHistoryStats(query backend.HistoryQuery) (backend.HistoryStats, error) {
+	return backend.GetHistoryStats(query, "SpotiFLAC")
+}
+
+func (a *App) ReconcileDownloadHistory(extraRoots []string) (backend.HistoryReconcileResult, error) {
+	settings, _ := a.LoadSettings()
+	roots := append([]string{settingString(settings, "downloadPath", backend.GetDefaultMusicPath())}, extraRoots...)
+	return backend.ReconcileHistory(a.ctx, roots, "SpotiFLAC")
+}
+
+func (a *App) GetMissingHistoryTracks(ids []string) ([]backend.TrackMetadata, error) {
+	return backend.MissingHistoryTracks(a.ctx, ids, "SpotiFLAC")
+}
+
 func (a *App) ClearDownloadHistory() error {
 	return backend.ClearHistory("SpotiFLAC")
 }
@@ -689,7 +792,7 @@ func (a *App) AnalyzeTrack(filePath string) (string, error) {
 		return "", fmt.Errorf("file path is required")
 	}
 
-	result, err := backend.AnalyzeTrack(filePath)
+	result, err := backend.AnalyzeTrackCached(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to analyze track: %v", err)
 	}
@@ -702,22 +805,90 @@ func (a *App) AnalyzeTrack(filePath string) (string, error) {
 	return string(jsonData), nil
 }
 
+func (a *App) ClearAnalysisCache() error {
+	return backend.ClearAnalysisCache()
+}
+
+func (a *App) AnalyzeSpectrum(filePath string, perChannel bool) (string, error) {
+	if filePath == "" {
+		return "", fmt.Errorf("file path is required")
+	}
+
+	opts := backend.DefaultSpectrumOptions()
+	opts.PerChannel = perChannel
+
+	result, err := backend.AnalyzeSpectrumWithOptions(filePath, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to analyze spectrum: %v", err)
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return string(jsonData), nil
+}
+
 func (a *App) AnalyzeMultipleTracks(filePaths []string) (string, error) {
 	if len(filePaths) == 0 {
 		return "", fmt.Errorf("at least one file path is required")
 	}
 
-	results := make([]*backend.AnalysisResult, 0, len(filePaths))
+	ctx, cancel := context.WithCancel(context.Background())
+	a.analysisMu.Lock()
+	if a.analysisCancel != nil {
+		a.analysisMu.Unlock()
+		cancel()
+		return "", fmt.Errorf("a batch analysis is already running")
+	}
+	a.analysisCancel = cancel
+	a.analysisMu.Unlock()
 
-	for _, filePath := range filePaths {
-		result, err := backend.AnalyzeTrack(filePath)
-		if err != nil {
+	defer func() {
+		a.analysisMu.Lock()
+		a.analysisCancel = nil
+		a.analysisMu.Unlock()
+		cancel()
+	}()
 
-			continue
+	report, err := backend.AnalyzeBatch(ctx, filePaths, backend.BatchAnalysisOptions{}, func(progress backend.BatchAnalysisProgress) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "analysis:progress", progress)
 		}
-		results = append(results, result)
+	})
+	if err != nil && report == nil {
+		return "", fmt.Errorf("failed to analyze tracks: %v", err)
+	}
+
+	a.analysisMu.Lock()
+	a.analysisReport = report
+	a.analysisMu.Unlock()
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	return string(jsonData), nil
+}
+
+func (a *App) CancelBatchAnalysis() {
+	a.analysisMu.Lock()
+	defer a.analysisMu.Unlock()
+
+	if a.analysisCancel != nil {
+		a.analysisCancel()
+	}
+}
+
+func (a *App) ApplyReplayGain(filePaths []string, album bool) (string, error) {
+	if len(filePaths) == 0 {
+		return "", fmt.Errorf("at least one file path is required")
 	}
 
+	results := backend.ApplyReplayGain(filePaths, album)
+
 	jsonData, err := json.Marshal(results)
 	if err != nil {
 		return "", fmt.Errorf("failed to encode response: %v", err)
@@ -726,6 +897,77 @@ func (a *App) AnalyzeMultipleTracks(filePaths []string) (string, error) {
 	return string(jsonData), nil
 }
 
+func (a *App) ExportAnalysisReport(format string) (string, error) {
+	a.analysisMu.Lock()
+	report := a.analysisReport
+	a.analysisMu.Unlock()
+
+	if report == nil {
+		return "No analysis report to export.", nil
+	}
+
+	ext := "csv"
+	displayName := "CSV Files (*.csv)"
+	if strings.EqualFold(format, "json") {
+		ext = "json"
+		displayName = "JSON Files (*.json)"
+	}
+
+	defaultFilename := fmt.Sprintf("SpotiFLAC_%s_Analysis.%s", time.Now().Format("20060102_150405"), ext)
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: defaultFilename,
+		Title:           "Export Analysis Report",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: displayName,
+				Pattern:     "*." + ext,
+			},
+		},
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to open save dialog: %v", err)
+	}
+
+	if path == "" {
+		return "Export cancelled", nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	defer file.Close()
+
+	if ext == "json" {
+		err = report.WriteJSON(file)
+	} else {
+		err = report.WriteCSV(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+
+	return fmt.Sprintf("Successfully exported %d analyzed files to %s", len(report.Items), path), nil
+}
+
+func (a *App) ExportSpectrogram(filePath string, outputPath string) (string, error) {
+	if filePath == "" {
+		return "", fmt.Errorf("file path is required")
+	}
+
+	return backend.ExportSpectrogramPNG(filePath, outputPath)
+}
+
+func (a *App) ExportSpectrograms(filePaths []string, outputDir string) ([]backend.SpectrogramExportResult, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("at least one file is required")
+	}
+
+	return backend.ExportSpectrograms(a.ctx, filePaths, outputDir), nil
+}
+
 type LyricsDownloadRequest struct {
 	SpotifyID           string `json:"spotify_id"`
 	TrackName           string `json:"track_name"`
@@ -739,6 +981,8 @@ type LyricsDownloadRequest struct {
 	Position            int    `json:"position"`
 	UseAlbumTrackNumber bool   `json:"use_album_track_number"`
 	DiscNumber          int    `json:"disc_number"`
+	TotalDiscs          int    `json:"total_discs"`
+	PlaylistName        string `json:"playlist_name"`
 }
 
 func (a *App) DownloadLyrics(req LyricsDownloadRequest) (backend.LyricsDownloadResponse, error) {
@@ -749,6 +993,11 @@ func (a *App) DownloadLyrics(req LyricsDownloadRequest) (backend.LyricsDownloadR
 		}, fmt.Errorf("spotify ID is required")
 	}
 
+	templateData := backend.DownloadTemplateData(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.PlaylistName, "", req.Position, 0, req.DiscNumber, 0, req.TotalDiscs, false, false)
+	if folder := backend.BuildFolderPath(a.folderLayout(), templateData); folder != "" && req.OutputDir != "" {
+		req.OutputDir = filepath.Join(req.OutputDir, filepath.FromSlash(folder))
+	}
+
 	client := backend.NewLyricsClient()
 	backendReq := backend.LyricsDownloadRequest{
 		SpotifyID:           req.SpotifyID,
@@ -788,6 +1037,8 @@ type CoverDownloadRequest struct {
 	TrackNumber    bool   `json:"track_number"`
 	Position       int    `json:"position"`
 	DiscNumber     int    `json:"disc_number"`
+	TotalDiscs     int    `json:"total_discs"`
+	PlaylistName   string `json:"playlist_name"`
 }
 
 func (a *App) DownloadCover(req CoverDownloadRequest) (backend.CoverDownloadResponse, error) {
@@ -798,6 +1049,11 @@ func (a *App) DownloadCover(req CoverDownloadRequest) (backend.CoverDownloadResp
 		}, fmt.Errorf("cover URL is required")
 	}
 
+	templateData := backend.DownloadTemplateData(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.PlaylistName, "", req.Position, 0, req.DiscNumber, 0, req.TotalDiscs, false, false)
+	if folder := backend.BuildFolderPath(a.folderLayout(), templateData); folder != "" && req.OutputDir != "" {
+		req.OutputDir = filepath.Join(req.OutputDir, filepath.FromSlash(folder))
+	}
+
 	client := backend.NewCoverClient()
 	backendReq := backend.CoverDownloadRequest{
 		CoverURL:       req.CoverURL,
@@ -943,6 +1199,36 @@ func (a *App) DownloadAvatar(req AvatarDownloadRequest) (backend.AvatarDownloadR
 	return *resp, nil
 }
 
+func (a *App) WriteArtworkSet(req backend.ArtworkSetRequest) (backend.ArtworkSetResponse, error) {
+	if len(req.Tracks) == 0 {
+		return backend.ArtworkSetResponse{
+			Success: false,
+			Error:   "No tracks provided",
+		}, fmt.Errorf("no tracks provided")
+	}
+
+	if layout := a.folderLayout(); layout.DiscFolders && req.DiscFolderFormat == "" {
+		req.DiscFolderFormat = layout.DiscFolderFormat
+		if req.DiscFolderFormat == "" {
+			req.DiscFolderFormat = "CD{disc}"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	client := backend.NewCoverClient()
+	resp, err := client.WriteArtworkSet(ctx, req)
+	if err != nil {
+		return backend.ArtworkSetResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, err
+	}
+
+	return *resp, nil
+}
+
 func (a *App) CheckTrackAvailability(spotifyTrackID string) (string, error) {
 	if spotifyTrackID == "" {
 		return "", fmt.Errorf("spotify track ID is required")
@@ -1003,20 +1289,119 @@ func (a *App) DownloadFFmpeg() DownloadFFmpegResponse {
 }
 
 type ConvertAudioRequest struct {
-	InputFiles   []string `json:"input_files"`
-	OutputFormat string   `json:"output_format"`
-	Bitrate      string   `json:"bitrate"`
-	Codec        string   `json:"codec"`
+	InputFiles       []string `json:"input_files"`
+	OutputFormat     string   `json:"output_format"`
+	Bitrate          string   `json:"bitrate"`
+	Codec            string   `json:"codec"`
+	Preset           string   `json:"preset,omitempty"`
+	Mode             string   `json:"mode,omitempty"`
+	Quality          string   `json:"quality,omitempty"`
+	SampleRate       int      `json:"sample_rate,omitempty"`
+	BitDepth         int      `json:"bit_depth,omitempty"`
+	CompressionLevel *int     `json:"compression_level,omitempty"`
+	OutputDir        string   `json:"output_dir,omitempty"`
+	OutputTemplate   string   `json:"output_template,omitempty"`
+	OnlyIfAbove      bool     `json:"only_if_above,omitempty"`
 }
 
 func (a *App) ConvertAudio(req ConvertAudioRequest) ([]backend.ConvertAudioResult, error) {
+	settings, _ := a.LoadSettings()
+	outputTemplate := req.OutputTemplate
+	if outputTemplate == "" {
+		outputTemplate = settingString(settings, "convertOutputTemplate", "")
+	}
+
+	a.convertMu.Lock()
+	if a.convertCancel != nil {
+		a.convertMu.Unlock()
+		return nil, fmt.Errorf("a conversion is already running")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.convertCancel = cancel
+	a.convertMu.Unlock()
+
+	defer func() {
+		a.convertMu.Lock()
+		a.convertCancel = nil
+		a.convertMu.Unlock()
+		cancel()
+	}()
+
 	backendReq := backend.ConvertAudioRequest{
-		InputFiles:   req.InputFiles,
-		OutputFormat: req.OutputFormat,
-		Bitrate:      req.Bitrate,
-		Codec:        req.Codec,
+		InputFiles:       req.InputFiles,
+		OutputFormat:     req.OutputFormat,
+		Bitrate:          req.Bitrate,
+		Codec:            req.Codec,
+		Preset:           req.Preset,
+		Mode:             req.Mode,
+		Quality:          req.Quality,
+		SampleRate:       req.SampleRate,
+		BitDepth:         req.BitDepth,
+		CompressionLevel: req.CompressionLevel,
+		OutputDir:        req.OutputDir,
+		OutputTemplate:   outputTemplate,
+		Workers:          settingInt(settings, "convertWorkers", 0),
+		OnlyIfAbove:      req.OnlyIfAbove,
+	}
+	return backend.ConvertAudio(ctx, backendReq)
+}
+
+type MirrorLibraryRequest struct {
+	SourceRoot string `json:"source_root"`
+	DestRoot   string `json:"dest_root"`
+	Preset     string `json:"preset"`
+}
+
+func (a *App) MirrorLibrary(req MirrorLibraryRequest) (*backend.MirrorResult, error) {
+	settings, _ := a.LoadSettings()
+
+	a.convertMu.Lock()
+	if a.convertCancel != nil {
+		a.convertMu.Unlock()
+		return nil, fmt.Errorf("a conversion is already running")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.convertCancel = cancel
+	a.convertMu.Unlock()
+
+	defer func() {
+		a.convertMu.Lock()
+		a.convertCancel = nil
+		a.convertMu.Unlock()
+		cancel()
+	}()
+
+	result, err := backend.MirrorLibrary(ctx, backend.MirrorRequest{
+		SourceRoot: req.SourceRoot,
+		DestRoot:   req.DestRoot,
+		Preset:     req.Preset,
+		Workers:    settingInt(settings, "convertWorkers", 0),
+	})
+	if err != nil && result == nil {
+		return nil, err
 	}
-	return backend.ConvertAudio(backendReq)
+	return result, nil
+}
+
+func (a *App) CancelConversion() {
+	a.convertMu.Lock()
+	defer a.convertMu.Unlock()
+
+	if a.convertCancel != nil {
+		a.convertCancel()
+	}
+}
+
+func (a *App) GetConvertQueue() backend.ConvertQueueInfo {
+	return backend.GetConvertQueue()
+}
+
+func (a *App) ClearConvertQueue() {
+	backend.ClearConvertQueue()
+}
+
+func (a *App) GetConvertPresets() []backend.ConvertPreset {
+	return backend.GetConvertPresets()
 }
 
 func (a *App) SelectAudioFiles() ([]string, error) {
@@ -1052,12 +1437,67 @@ func (a *App) ReadFileMetadata(filePath string) (*backend.AudioMetadata, error)
 	return backend.ReadAudioMetadata(filePath)
 }
 
-func (a *App) PreviewRenameFiles(files []string, format string) []backend.RenamePreview {
-	return backend.PreviewRename(files, format)
+func (a *App) ReadFileTags(filePath string) (map[backend.TagField]string, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+	return backend.ReadTags(filePath)
+}
+
+func (a *App) EditFileTags(req backend.TagEditRequest) ([]backend.TagEditResult, error) {
+	if len(req.Files) == 0 {
+		return nil, fmt.Errorf("no files provided")
+	}
+	return backend.EditTags(req)
+}
+
+func (a *App) PreviewTagsFromFilename(files []string, pattern string, onlyEmpty bool) ([]backend.TagEditResult, error) {
+	return backend.TagsFromFilenames(files, pattern, onlyEmpty, true)
+}
+
+func (a *App) ApplyTagsFromFilename(files []string, pattern string, onlyEmpty bool) ([]backend.TagEditResult, error) {
+	return backend.TagsFromFilenames(files, pattern, onlyEmpty, false)
+}
+
+func (a *App) MatchFilesForRetag(files []string) ([]backend.RetagProposal, error) {
+	return backend.MatchFilesForRetag(a.ctx, files)
+}
+
+func (a *App) ApplyRetag(items []backend.RetagApplyItem) []backend.RetagApplyResult {
+	return backend.ApplyRetag(a.ctx, items, a.coverImageOptions())
+}
+
+func (a *App) PreviewFilenameTemplate(template string) string {
+	return filepath.ToSlash(backend.BuildTrackFilename(template, backend.SampleTemplateData, false, ".flac"))
+}
+
+func (a *App) PreviewFolderTemplate(template string, discSubfolders bool, discFolderFormat string, compilationFolder bool) string {
+	data := backend.SampleTemplateData
+	if discSubfolders {
+		data.TotalDiscs = 2
+	}
+	return backend.BuildFolderPath(backend.FolderLayout{
+		Template:          template,
+		DiscFolders:       discSubfolders,
+		DiscFolderFormat:  discFolderFormat,
+		CompilationFolder: compilationFolder,
+	}, data)
+}
+
+func (a *App) PreviewRenameFiles(files []string, format string, strategy string) []backend.RenamePreview {
+	return backend.PreviewRename(files, format, backend.RenameCollisionStrategy(strategy))
+}
+
+func (a *App) RenameFilesByMetadata(files []string, format string, strategy string) backend.RenameBatchResult {
+	return backend.RenameFiles(files, format, backend.RenameCollisionStrategy(strategy))
+}
+
+func (a *App) GetRenameJournal(limit int) ([]backend.RenameBatch, error) {
+	return backend.ListRenameBatches(limit)
 }
 
-func (a *App) RenameFilesByMetadata(files []string, format string) []backend.RenameResult {
-	return backend.RenameFiles(files, format)
+func (a *App) UndoRename(batchID string) (backend.UndoRenameResult, error) {
+	return backend.UndoRenameBatch(batchID)
 }
 
 func (a *App) ReadTextFile(filePath string) (string, error) {
@@ -1068,11 +1508,26 @@ func (a *App) ReadTextFile(filePath string) (string, error) {
 	return string(content), nil
 }
 
-func (a *App) RenameFileTo(oldPath, newName string) error {
+func (a *App) RenameFileTo(oldPath, newName string) (string, error) {
 	dir := filepath.Dir(oldPath)
 	ext := filepath.Ext(oldPath)
 	newPath := filepath.Join(dir, newName+ext)
-	return os.Rename(oldPath, newPath)
+	if newPath == oldPath {
+		return "", nil
+	}
+	if info, err := os.Stat(newPath); err == nil {
+		if current, err := os.Stat(oldPath); err != nil || !os.SameFile(info, current) {
+			return "", fmt.Errorf("file already exists: %s", filepath.Base(newPath))
+		}
+	}
+	if err := backend.MoveFile(oldPath, newPath); err != nil {
+		return "", err
+	}
+	ops := []backend.RenameOperation{{OldPath: oldPath, NewPath: newPath}}
+	if err := backend.UpdateHistoryPaths(ops); err != nil {
+		fmt.Printf("Failed to update history paths: %v\n", err)
+	}
+	return backend.RecordRenameBatch(ops)
 }
 
 func (a *App) UploadImage(filePath string) (string, error) {
@@ -1130,12 +1585,17 @@ type CheckFileExistenceRequest struct {
 	ReleaseDate         string `json:"release_date,omitempty"`
 	TrackNumber         int    `json:"track_number,omitempty"`
 	DiscNumber          int    `json:"disc_number,omitempty"`
+	TotalTracks         int    `json:"total_tracks,omitempty"`
+	TotalDiscs          int    `json:"total_discs,omitempty"`
 	Position            int    `json:"position,omitempty"`
 	UseAlbumTrackNumber bool   `json:"use_album_track_number,omitempty"`
 	FilenameFormat      string `json:"filename_format,omitempty"`
 	IncludeTrackNumber  bool   `json:"include_track_number,omitempty"`
+	PlaylistName        string `json:"playlist_name,omitempty"`
+	PlaylistOwner       string `json:"playlist_owner,omitempty"`
 	AudioFormat         string `json:"audio_format,omitempty"`
 	RelativePath        string `json:"relative_path,omitempty"`
+	UseFirstArtistOnly  bool   `json:"use_first_artist_only,omitempty"`
 }
 
 type CheckFileExistenceResult struct {
@@ -1157,6 +1617,7 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 	}
 
 	defaultFilenameFormat := "title-artist"
+	layout := a.folderLayout()
 
 	type result struct {
 		index  int
@@ -1208,37 +1669,21 @@ func (a *App) CheckFilesExistence(outputDir string, rootDir string, tracks []Che
 				filenameFormat = defaultFilenameFormat
 			}
 
-			trackNumber := t.Position
-			if t.UseAlbumTrackNumber && t.TrackNumber > 0 {
-				trackNumber = t.TrackNumber
-			}
-
 			fileExt := ".flac"
 			if t.AudioFormat == "mp3" {
 				fileExt = ".mp3"
 			}
 
-			expectedFilenameBase := backend.BuildExpectedFilename(
-				t.TrackName,
-				t.ArtistName,
-				t.AlbumName,
-				t.AlbumArtist,
-				t.ReleaseDate,
-				filenameFormat,
-				"",
-				"",
-				t.IncludeTrackNumber,
-				trackNumber,
-				t.DiscNumber,
-				t.UseAlbumTrackNumber,
-			)
-
-			expectedFilename := strings.TrimSuffix(expectedFilenameBase, ".flac") + fileExt
+			templateData := backend.DownloadTemplateData(t.TrackName, t.ArtistName, t.AlbumName, t.AlbumArtist, t.ReleaseDate, t.PlaylistName, t.PlaylistOwner, t.Position, t.TrackNumber, t.DiscNumber, t.TotalTracks, t.TotalDiscs, t.UseAlbumTrackNumber, t.UseFirstArtistOnly)
+			expectedFilename := backend.BuildTrackFilename(filenameFormat, templateData, t.IncludeTrackNumber, fileExt)
 
 			targetDir := outputDir
 			if t.RelativePath != "" {
 				targetDir = filepath.Join(outputDir, t.RelativePath)
 			}
+			if folder := backend.BuildFolderPath(layout, templateData); folder != "" {
+				targetDir = filepath.Join(targetDir, filepath.FromSlash(folder))
+			}
 
 			expectedPath := filepath.Join(targetDir, expectedFilename)
 
@@ -1353,6 +1798,65 @@ func (a *App) LoadSettings() (map[string]interface{}, error) {
 	return settings, nil
 }
 
+func (a *App) folderLayout() backend.FolderLayout {
+	settings, _ := a.LoadSettings()
+	return backend.FolderLayout{
+		Template:          settingString(settings, "folderTemplate", ""),
+		DiscFolders:       settingBool(settings, "discSubfolders", false),
+		DiscFolderFormat:  settingString(settings, "discFolderFormat", "CD{disc}"),
+		CompilationFolder: settingBool(settings, "compilationFolder", false),
+		FirstArtistOnly:   settingBool(settings, "useFirstArtistOnly", false),
+	}
+}
+
+func settingString(settings map[string]interface{}, key, fallback string) string {
+	if v, ok := settings[key].(string); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func settingInt(settings map[string]interface{}, key string, fallback int) int {
+	if v, ok := settings[key].(float64); ok {
+		return int(v)
+	}
+	return fallback
+}
+
+func settingBool(settings map[string]interface{}, key string, fallback bool) bool {
+	if v, ok := settings[key].(bool); ok {
+		return v
+	}
+	return fallback
+}
+
+func (a *App) coverImageOptions() backend.CoverImageOptions {
+	settings, err := a.LoadSettings()
+	if err != nil || settings == nil {
+		return backend.CoverImageOptions{}
+	}
+
+	format := settingString(settings, "coverFormat", "")
+	if format == "original" {
+		format = ""
+	}
+
+	return backend.CoverImageOptions{
+		MaxSize:        settingInt(settings, "coverMaxSize", 0),
+		Format:         format,
+		Quality:        settingInt(settings, "coverQuality", 0),
+		SaveAlbumCover: settingBool(settings, "saveAlbumCover", false),
+		SourcePolicy:   settingString(settings, "coverSourcePolicy", backend.CoverPolicySpotify),
+		SourceURLs: backend.CoverSourceURLs{
+			QobuzAPI:        settingString(settings, "coverQobuzApiUrl", ""),
+			TidalResources:  settingString(settings, "coverTidalResourcesUrl", ""),
+			ITunesAPI:       settingString(settings, "coverItunesApiUrl", ""),
+			MusicBrainzAPI:  settingString(settings, "coverMusicBrainzApiUrl", ""),
+			CoverArtArchive: settingString(settings, "coverArtArchiveUrl", ""),
+		},
+	}
+}
+
 func (a *App) CheckFFmpegInstalled() (bool, error) {
 	return backend.IsFFmpegInstalled()
 }
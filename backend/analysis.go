@@ -4,24 +4,26 @@ import (
 	"fmt"
 	"math"
 	"os"
-
-	"github.com/go-flac/go-flac"
-	mewflac "github.com/mewkiz/flac"
 )
 
 type AnalysisResult struct {
-	FilePath      string        `json:"file_path"`
-	FileSize      int64         `json:"file_size"`
-	SampleRate    uint32        `json:"sample_rate"`
-	Channels      uint8         `json:"channels"`
-	BitsPerSample uint8         `json:"bits_per_sample"`
-	TotalSamples  uint64        `json:"total_samples"`
-	Duration      float64       `json:"duration"`
-	BitDepth      string        `json:"bit_depth"`
-	DynamicRange  float64       `json:"dynamic_range"`
-	PeakAmplitude float64       `json:"peak_amplitude"`
-	RMSLevel      float64       `json:"rms_level"`
-	Spectrum      *SpectrumData `json:"spectrum,omitempty"`
+	FilePath      string              `json:"file_path"`
+	FileSize      int64               `json:"file_size"`
+	SampleRate    uint32              `json:"sample_rate"`
+	Channels      uint8               `json:"channels"`
+	BitsPerSample uint8               `json:"bits_per_sample"`
+	TotalSamples  uint64              `json:"total_samples"`
+	Duration      float64             `json:"duration"`
+	BitDepth      string              `json:"bit_depth"`
+	Lossy         bool                `json:"lossy,omitempty"`
+	DynamicRange  float64             `json:"dynamic_range"`
+	PeakAmplitude float64             `json:"peak_amplitude"`
+	RMSLevel      float64             `json:"rms_level"`
+	Spectrum      *SpectrumData       `json:"spectrum,omitempty"`
+	Verdict       *QualityVerdict     `json:"verdict,omitempty"`
+	DR            *DynamicRangeResult `json:"dr,omitempty"`
+	Codec         string              `json:"codec,omitempty"`
+	Loudness      *LoudnessResult     `json:"loudness,omitempty"`
 }
 
 func AnalyzeTrack(filepath string) (*AnalysisResult, error) {
@@ -34,42 +36,16 @@ func AnalyzeTrack(filepath string) (*AnalysisResult, error) {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	f, err := flac.ParseFile(filepath)
+	format, err := ProbePCMFormat(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
+		return nil, fmt.Errorf("failed to read audio format: %w", err)
 	}
 
 	result := &AnalysisResult{
 		FilePath: filepath,
 		FileSize: fileInfo.Size(),
 	}
-
-	if len(f.Meta) > 0 {
-		streamInfo := f.Meta[0]
-		if streamInfo.Type == flac.StreamInfo {
-
-			data := streamInfo.Data
-			if len(data) >= 18 {
-
-				result.SampleRate = uint32(data[10])<<12 | uint32(data[11])<<4 | uint32(data[12])>>4
-
-				result.Channels = ((data[12] >> 1) & 0x07) + 1
-
-				result.BitsPerSample = ((data[12]&0x01)<<4 | data[13]>>4) + 1
-
-				result.TotalSamples = uint64(data[13]&0x0F)<<32 |
-					uint64(data[14])<<24 |
-					uint64(data[15])<<16 |
-					uint64(data[16])<<8 |
-					uint64(data[17])
-
-				if result.SampleRate > 0 {
-					result.Duration = float64(result.TotalSamples) / float64(result.SampleRate)
-				}
-
-			}
-		}
-	}
+	applyPCMFormat(result, format)
 
 	spectrum, err := AnalyzeSpectrum(filepath)
 	if err != nil {
@@ -78,81 +54,110 @@ func AnalyzeTrack(filepath string) (*AnalysisResult, error) {
 	} else {
 		result.Spectrum = spectrum
 
-		calculateRealAudioMetrics(result, filepath)
+		stats := calculateRealAudioMetrics(result, filepath)
+		if format.Lossless {
+			result.Verdict = EvaluateQuality(result, stats)
+		}
 	}
 
-	result.BitDepth = fmt.Sprintf("%d-bit", result.BitsPerSample)
-
 	return result, nil
 }
 
-func calculateRealAudioMetrics(result *AnalysisResult, filepath string) {
-
-	samples, err := decodeFLACForMetrics(filepath)
-	if err != nil {
-		return
+func applyPCMFormat(result *AnalysisResult, format PCMFormat) {
+	result.SampleRate = uint32(format.SampleRate)
+	result.Channels = uint8(format.Channels)
+	result.TotalSamples = format.TotalSamples
+	result.Codec = format.Codec
+	if format.SampleRate > 0 {
+		result.Duration = float64(format.TotalSamples) / float64(format.SampleRate)
 	}
 
-	var peak float64
-	var sumSquares float64
-
-	for _, sample := range samples {
-		absVal := sample
-		if absVal < 0 {
-			absVal = -absVal
-		}
-		if absVal > peak {
-			peak = absVal
-		}
-		sumSquares += sample * sample
+	if format.Lossless {
+		result.BitsPerSample = uint8(format.BitsPerSample)
+		result.BitDepth = fmt.Sprintf("%d-bit", result.BitsPerSample)
+	} else {
+		result.Lossy = true
 	}
-
-	peakDB := 20.0 * math.Log10(peak)
-	result.PeakAmplitude = peakDB
-
-	rms := math.Sqrt(sumSquares / float64(len(samples)))
-	rmsDB := 20.0 * math.Log10(rms)
-	result.RMSLevel = rmsDB
-
-	result.DynamicRange = peakDB - rmsDB
 }
 
-func decodeFLACForMetrics(filepath string) ([]float64, error) {
-	stream, err := mewflac.ParseFile(filepath)
+func calculateRealAudioMetrics(result *AnalysisResult, filepath string) *sampleStats {
+	dec, err := OpenPCMDecoder(filepath)
 	if err != nil {
-		return nil, err
+		return nil
 	}
-	defer stream.Close()
+	defer dec.Close()
+
+	format := dec.Format()
+	maxVal := format.maxValue()
+	stats := &sampleStats{bitsPerSample: format.BitsPerSample}
+	dr := newDRMeter(format.SampleRate, format.Channels)
+	loudness := newLoudnessMeter(format.SampleRate, format.Channels)
+	buf := make([][]float64, format.Channels)
 
-	maxSamples := 10000000
-	samples := make([]float64, 0, maxSamples)
+	var peak float64
+	var sumSquares float64
+	var count int64
 
 	for {
-		frame, err := stream.ParseNext()
+		samples, err := dec.ReadFrames()
 		if err != nil {
 			break
 		}
-
-		var channelSamples []int32
-		if len(frame.Subframes) > 0 {
-			channelSamples = frame.Subframes[0].Samples
+		if len(samples) == 0 || len(samples[0]) == 0 {
+			continue
 		}
 
-		maxVal := float64(int64(1) << (stream.Info.BitsPerSample - 1))
-		for _, sample := range channelSamples {
-			if len(samples) >= maxSamples {
-				return samples, nil
+		for ch := range buf {
+			buf[ch] = normalizeSamples(buf[ch], samples[ch], maxVal)
+			if format.Lossless {
+				for _, s := range samples[ch] {
+					stats.add(s)
+				}
 			}
-			normalized := float64(sample) / maxVal
-			samples = append(samples, normalized)
 		}
 
-		if len(samples) >= maxSamples {
-			break
+		for _, sample := range buf[0] {
+			absVal := math.Abs(sample)
+			if absVal > peak {
+				peak = absVal
+			}
+			sumSquares += sample * sample
 		}
+		count += int64(len(buf[0]))
+
+		dr.addFrames(buf)
+		loudness.addFrames(buf)
+	}
+
+	if count == 0 {
+		return stats
+	}
+
+	peakDB := 20.0 * math.Log10(peak)
+	result.PeakAmplitude = peakDB
+
+	rms := math.Sqrt(sumSquares / float64(count))
+	rmsDB := 20.0 * math.Log10(rms)
+	result.RMSLevel = rmsDB
+
+	result.DR = dr.result()
+	result.Loudness = loudness.result()
+	if result.DR != nil {
+		result.DynamicRange = result.DR.Raw
 	}
 
-	return samples, nil
+	return stats
+}
+
+func normalizeSamples(dst []float64, samples []int32, maxVal float64) []float64 {
+	if cap(dst) < len(samples) {
+		dst = make([]float64, len(samples))
+	}
+	dst = dst[:len(samples)]
+	for i, s := range samples {
+		dst[i] = float64(s) / maxVal
+	}
+	return dst
 }
 
 func GetFileSize(filepath string) (int64, error) {
@@ -173,35 +178,15 @@ func GetTrackMetadata(filepath string) (*AnalysisResult, error) {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	f, err := flac.ParseFile(filepath)
+	format, err := ProbePCMFormat(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
+		return nil, fmt.Errorf("failed to read audio format: %w", err)
 	}
 
 	result := &AnalysisResult{
 		FilePath: filepath,
 		FileSize: fileInfo.Size(),
 	}
-
-	if len(f.Meta) > 0 {
-		streamInfo := f.Meta[0]
-		if streamInfo.Type == flac.StreamInfo {
-			data := streamInfo.Data
-			if len(data) >= 18 {
-				result.SampleRate = uint32(data[10])<<12 | uint32(data[11])<<4 | uint32(data[12])>>4
-				result.BitsPerSample = ((data[12]&0x01)<<4 | data[13]>>4) + 1
-				result.TotalSamples = uint64(data[13]&0x0F)<<32 |
-					uint64(data[14])<<24 |
-					uint64(data[15])<<16 |
-					uint64(data[16])<<8 |
-					uint64(data[17])
-
-				if result.SampleRate > 0 {
-					result.Duration = float64(result.TotalSamples) / float64(result.SampleRate)
-				}
-			}
-		}
-	}
-	result.BitDepth = fmt.Sprintf("%d-bit", result.BitsPerSample)
+	applyPCMFormat(result, format)
 	return result, nil
 }
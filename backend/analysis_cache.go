@@ -0,0 +1,361 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	analysisCacheBucket   = "AnalysisCache"
+	analysisCacheVersion  = 3
+	analysisCacheFreqBins = 512
+	analysisHashChunk     = 64 * 1024
+)
+
+var (
+	analysisCacheDB     *bolt.DB
+	analysisCacheDBLock sync.Mutex
+)
+
+type analysisCacheEntry struct {
+	Version  int             `json:"version"`
+	Size     int64           `json:"size"`
+	ModTime  int64           `json:"mod_time"`
+	Hash     string          `json:"hash"`
+	Result   *AnalysisResult `json:"result"`
+	Spectrum *cachedSpectrum `json:"spectrum,omitempty"`
+}
+
+type cachedSpectrum struct {
+	SampleRate int       `json:"sample_rate"`
+	FreqBins   int       `json:"freq_bins"`
+	Duration   float64   `json:"duration"`
+	MaxFreq    float64   `json:"max_freq"`
+	Times      []float64 `json:"times"`
+	MinDB      float64   `json:"min_db"`
+	MaxDB      float64   `json:"max_db"`
+	Data       []byte    `json:"data"`
+}
+
+type fileSignature struct {
+	size    int64
+	modTime int64
+	hash    string
+}
+
+func InitAnalysisCacheDB() error {
+	analysisCacheDBLock.Lock()
+	defer analysisCacheDBLock.Unlock()
+
+	if analysisCacheDB != nil {
+		return nil
+	}
+
+	appDir, err := GetFFmpegDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		os.MkdirAll(appDir, 0755)
+	}
+	dbPath := filepath.Join(appDir, "analysis_cache.db")
+
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(analysisCacheBucket))
+		return err
+	})
+
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	analysisCacheDB = db
+	return nil
+}
+
+func CloseAnalysisCacheDB() {
+	analysisCacheDBLock.Lock()
+	defer analysisCacheDBLock.Unlock()
+
+	if analysisCacheDB != nil {
+		analysisCacheDB.Close()
+		analysisCacheDB = nil
+	}
+}
+
+func analysisCacheKey(filePath string) []byte {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	return []byte(filepath.Clean(filePath))
+}
+
+func computeFileSignature(filePath string) (fileSignature, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fileSignature{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fileSignature{}, err
+	}
+
+	h := sha256.New()
+	var sizeBuf [8]byte
+	binary.LittleEndian.PutUint64(sizeBuf[:], uint64(info.Size()))
+	h.Write(sizeBuf[:])
+
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, analysisHashChunk)); err != nil {
+		return fileSignature{}, err
+	}
+	if info.Size() > 2*analysisHashChunk {
+		if _, err := io.Copy(h, io.NewSectionReader(f, info.Size()-analysisHashChunk, analysisHashChunk)); err != nil {
+			return fileSignature{}, err
+		}
+	}
+
+	return fileSignature{
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+		hash:    hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+func AnalyzeTrackCached(filePath string) (*AnalysisResult, error) {
+	if err := InitAnalysisCacheDB(); err != nil {
+		fmt.Printf("Warning: analysis cache unavailable: %v\n", err)
+		return AnalyzeTrack(filePath)
+	}
+
+	sig, err := computeFileSignature(filePath)
+	if err != nil {
+		return AnalyzeTrack(filePath)
+	}
+
+	if cached := loadCachedAnalysis(filePath, sig); cached != nil {
+		return cached, nil
+	}
+
+	result, err := AnalyzeTrack(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeCachedAnalysis(filePath, sig, result, compactSpectrum(result.Spectrum, analysisCacheFreqBins)); err != nil {
+		fmt.Printf("Warning: failed to cache analysis: %v\n", err)
+	}
+
+	return result, nil
+}
+
+func loadCachedAnalysis(filePath string, sig fileSignature) *AnalysisResult {
+	var entry analysisCacheEntry
+	found := false
+
+	analysisCacheDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(analysisCacheBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get(analysisCacheKey(filePath))
+		if v == nil {
+			return nil
+		}
+		found = json.Unmarshal(v, &entry) == nil
+		return nil
+	})
+
+	if !found || entry.Result == nil || entry.Version != analysisCacheVersion ||
+		entry.Size != sig.size || entry.ModTime != sig.modTime || entry.Hash != sig.hash {
+		return nil
+	}
+
+	result := entry.Result
+	result.FilePath = filePath
+	if entry.Spectrum != nil {
+		result.Spectrum = entry.Spectrum.expand()
+	}
+	return result
+}
+
+func storeCachedAnalysis(filePath string, sig fileSignature, result *AnalysisResult, spectrum *cachedSpectrum) error {
+	stored := *result
+	stored.Spectrum = nil
+
+	entry := analysisCacheEntry{
+		Version:  analysisCacheVersion,
+		Size:     sig.size,
+		ModTime:  sig.modTime,
+		Hash:     sig.hash,
+		Result:   &stored,
+		Spectrum: spectrum,
+	}
+
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return analysisCacheDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(analysisCacheBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put(analysisCacheKey(filePath), buf)
+	})
+}
+
+func compactSpectrum(spectrum *SpectrumData, maxBins int) *cachedSpectrum {
+	if spectrum == nil || len(spectrum.TimeSlices) == 0 || spectrum.FreqBins == 0 {
+		return nil
+	}
+
+	group := (spectrum.FreqBins + maxBins - 1) / maxBins
+	bins := (spectrum.FreqBins + group - 1) / group
+
+	pooled := make([][]float64, len(spectrum.TimeSlices))
+	minDB, maxDB := math.Inf(1), math.Inf(-1)
+	for i, slice := range spectrum.TimeSlices {
+		row := make([]float64, bins)
+		for j := range row {
+			row[j] = math.Inf(-1)
+		}
+		for k, m := range slice.Magnitudes {
+			if k/group < bins && m > row[k/group] {
+				row[k/group] = m
+			}
+		}
+		for _, m := range row {
+			if math.IsInf(m, 0) {
+				continue
+			}
+			minDB = math.Min(minDB, m)
+			maxDB = math.Max(maxDB, m)
+		}
+		pooled[i] = row
+	}
+	if math.IsInf(minDB, 0) {
+		return nil
+	}
+
+	scale := 0.0
+	if maxDB > minDB {
+		scale = 255 / (maxDB - minDB)
+	}
+
+	compact := &cachedSpectrum{
+		SampleRate: spectrum.SampleRate,
+		FreqBins:   bins,
+		Duration:   spectrum.Duration,
+		MaxFreq:    spectrum.MaxFreq,
+		Times:      make([]float64, len(spectrum.TimeSlices)),
+		MinDB:      minDB,
+		MaxDB:      maxDB,
+		Data:       make([]byte, 0, len(pooled)*bins),
+	}
+	for i, row := range pooled {
+		compact.Times[i] = spectrum.TimeSlices[i].Time
+		for _, m := range row {
+			if math.IsInf(m, 0) {
+				m = minDB
+			}
+			compact.Data = append(compact.Data, byte(math.Round((m-minDB)*scale)))
+		}
+	}
+
+	return compact
+}
+
+func (c *cachedSpectrum) expand() *SpectrumData {
+	if c.FreqBins == 0 || len(c.Data) < len(c.Times)*c.FreqBins {
+		return nil
+	}
+
+	step := (c.MaxDB - c.MinDB) / 255
+	spectrum := &SpectrumData{
+		TimeSlices: make([]TimeSlice, len(c.Times)),
+		SampleRate: c.SampleRate,
+		FreqBins:   c.FreqBins,
+		Duration:   c.Duration,
+		MaxFreq:    c.MaxFreq,
+	}
+	for i, t := range c.Times {
+		row := c.Data[i*c.FreqBins : (i+1)*c.FreqBins]
+		magnitudes := make([]float64, c.FreqBins)
+		for j, q := range row {
+			magnitudes[j] = c.MinDB + float64(q)*step
+		}
+		spectrum.TimeSlices[i] = TimeSlice{Time: t, Magnitudes: magnitudes}
+	}
+	return spectrum
+}
+
+func PruneAnalysisCache() (int, error) {
+	if err := InitAnalysisCacheDB(); err != nil {
+		return 0, err
+	}
+
+	var stale [][]byte
+	err := analysisCacheDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(analysisCacheBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, _ []byte) error {
+			if _, err := os.Stat(string(k)); os.IsNotExist(err) {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+			return nil
+		})
+	})
+	if err != nil || len(stale) == 0 {
+		return 0, err
+	}
+
+	err = analysisCacheDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(analysisCacheBucket))
+		if b == nil {
+			return nil
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(stale), err
+}
+
+func ClearAnalysisCache() error {
+	if err := InitAnalysisCacheDB(); err != nil {
+		return err
+	}
+
+	return analysisCacheDB.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(analysisCacheBucket)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte(analysisCacheBucket))
+		return err
+	})
+}
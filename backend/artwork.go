@@ -0,0 +1,338 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	folderArtworkFilename   = "folder.jpg"
+	artistArtworkFilename   = "artist.jpg"
+	backdropArtworkFilename = "backdrop.jpg"
+)
+
+type ArtworkTrack struct {
+	FilePath string `json:"file_path"`
+	CoverURL string `json:"cover_url"`
+	ArtistID string `json:"artist_id"`
+}
+
+type ArtworkSetRequest struct {
+	Tracks               []ArtworkTrack      `json:"tracks"`
+	RootDir              string              `json:"root_dir"`
+	ArtistInfo           *ArtistInfoMetadata `json:"artist_info,omitempty"`
+	EmbedMaxQualityCover bool                `json:"embed_max_quality_cover"`
+	DiscFolderFormat     string              `json:"disc_folder_format,omitempty"`
+}
+
+type ArtworkSetResult struct {
+	Dir     string   `json:"dir"`
+	Files   []string `json:"files"`
+	Skipped bool     `json:"skipped,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type ArtworkSetResponse struct {
+	Success bool               `json:"success"`
+	Albums  []ArtworkSetResult `json:"albums"`
+	Artists []ArtworkSetResult `json:"artists"`
+	Error   string             `json:"error,omitempty"`
+}
+
+var (
+	artworkSetDirs     = make(map[string]bool)
+	artworkArtistsDone = make(map[string]bool)
+	artworkSetDirsLock sync.Mutex
+)
+
+func claimArtworkDir(dir string) bool {
+	artworkSetDirsLock.Lock()
+	defer artworkSetDirsLock.Unlock()
+
+	if artworkSetDirs[dir] {
+		return false
+	}
+	artworkSetDirs[dir] = true
+	return true
+}
+
+func releaseArtworkDir(dir string) {
+	artworkSetDirsLock.Lock()
+	delete(artworkSetDirs, dir)
+	artworkSetDirsLock.Unlock()
+}
+
+func artistArtworkAttempted(artistDir string) bool {
+	artworkSetDirsLock.Lock()
+	defer artworkSetDirsLock.Unlock()
+	return artworkArtistsDone[artistDir]
+}
+
+func markArtistArtworkAttempted(artistDir string) {
+	artworkSetDirsLock.Lock()
+	artworkArtistsDone[artistDir] = true
+	artworkSetDirsLock.Unlock()
+}
+
+func albumDirForTrack(filePath, discFolderFormat string) string {
+	dir := filepath.Clean(filepath.Dir(filePath))
+	if discFolderFormat == "" {
+		return dir
+	}
+
+	name := filepath.Base(dir)
+	for disc := 1; disc <= 99; disc++ {
+		if RenderTemplate(discFolderFormat, TemplateData{Disc: disc, TotalDiscs: 99}) == name {
+			return filepath.Dir(dir)
+		}
+	}
+	return dir
+}
+
+func artistDirForAlbum(albumDir, rootDir string) string {
+	if rootDir == "" {
+		return ""
+	}
+
+	root := filepath.Clean(rootDir)
+	if albumDir == root {
+		return ""
+	}
+
+	parent := filepath.Dir(albumDir)
+	if parent == root {
+		return ""
+	}
+
+	rel, err := filepath.Rel(root, parent)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return parent
+}
+
+func (c *CoverClient) WriteArtworkSet(ctx context.Context, req ArtworkSetRequest) (*ArtworkSetResponse, error) {
+	if len(req.Tracks) == 0 {
+		return &ArtworkSetResponse{
+			Success: false,
+			Error:   "No tracks provided",
+		}, fmt.Errorf("no tracks provided")
+	}
+
+	rootDir := req.RootDir
+	if rootDir != "" {
+		rootDir = NormalizePath(rootDir)
+	}
+
+	var albumDirs []string
+	albumTracks := make(map[string]ArtworkTrack)
+	artistDirs := make(map[string]string)
+	var artistOrder []string
+
+	for _, track := range req.Tracks {
+		if track.FilePath == "" {
+			continue
+		}
+
+		albumDir := albumDirForTrack(track.FilePath, req.DiscFolderFormat)
+		if existing, ok := albumTracks[albumDir]; !ok {
+			albumDirs = append(albumDirs, albumDir)
+			albumTracks[albumDir] = track
+		} else if existing.CoverURL == "" && track.CoverURL != "" {
+			albumTracks[albumDir] = track
+		}
+
+		if artistDir := artistDirForAlbum(albumDir, rootDir); artistDir != "" {
+			if _, ok := artistDirs[artistDir]; !ok {
+				artistOrder = append(artistOrder, artistDir)
+				artistDirs[artistDir] = track.ArtistID
+			} else if artistDirs[artistDir] == "" {
+				artistDirs[artistDir] = track.ArtistID
+			}
+		}
+	}
+
+	resp := &ArtworkSetResponse{Success: true}
+
+	for _, albumDir := range albumDirs {
+		if ctx.Err() != nil {
+			return resp, ctx.Err()
+		}
+		resp.Albums = append(resp.Albums, c.writeAlbumArtworkStep(albumDir, albumTracks[albumDir].CoverURL, req.EmbedMaxQualityCover))
+	}
+
+	metadataClient := NewSpotifyMetadataClient()
+	for _, artistDir := range artistOrder {
+		if ctx.Err() != nil {
+			return resp, ctx.Err()
+		}
+
+		info := req.ArtistInfo
+		if len(artistOrder) > 1 {
+			info = nil
+		}
+		resp.Artists = append(resp.Artists, c.writeArtistArtworkStep(ctx, metadataClient, artistDir, artistDirs[artistDir], info))
+	}
+
+	return resp, nil
+}
+
+func (c *CoverClient) writeAlbumArtworkStep(albumDir, coverURL string, embedMaxQualityCover bool) ArtworkSetResult {
+	result := ArtworkSetResult{Dir: albumDir}
+	if !claimArtworkDir(albumDir) {
+		result.Skipped = true
+		return result
+	}
+	defer releaseArtworkDir(albumDir)
+
+	coverPath := filepath.Join(albumDir, albumCoverFilename)
+	folderPath := filepath.Join(albumDir, folderArtworkFilename)
+	if nonEmptyFileExists(coverPath) && nonEmptyFileExists(folderPath) {
+		result.Files = []string{coverPath, folderPath}
+		result.Skipped = true
+		return result
+	}
+
+	files, err := c.writeAlbumArtwork(albumDir, coverURL, embedMaxQualityCover)
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Printf("Warning: Failed to write album artwork for %s: %v\n", albumDir, err)
+	}
+	result.Files = files
+	return result
+}
+
+func (c *CoverClient) writeArtistArtworkStep(ctx context.Context, metadataClient *SpotifyMetadataClient, artistDir, artistID string, info *ArtistInfoMetadata) ArtworkSetResult {
+	result := ArtworkSetResult{Dir: artistDir}
+	if !claimArtworkDir(artistDir) {
+		result.Skipped = true
+		return result
+	}
+	defer releaseArtworkDir(artistDir)
+
+	artistPath := filepath.Join(artistDir, artistArtworkFilename)
+	backdropPath := filepath.Join(artistDir, backdropArtworkFilename)
+	if nonEmptyFileExists(artistPath) && nonEmptyFileExists(backdropPath) {
+		result.Files = []string{artistPath, backdropPath}
+		result.Skipped = true
+		return result
+	}
+	if artistArtworkAttempted(artistDir) {
+		result.Skipped = true
+		return result
+	}
+	defer func() {
+		if ctx.Err() == nil {
+			markArtistArtworkAttempted(artistDir)
+		}
+	}()
+
+	if info == nil && artistID != "" {
+		fetched, err := metadataClient.FetchArtistInfo(ctx, artistID)
+		if err != nil {
+			fmt.Printf("Warning: Failed to fetch artist info: %v\n", err)
+		} else {
+			info = fetched
+		}
+	}
+	if info == nil {
+		result.Skipped = true
+		return result
+	}
+
+	files, err := c.writeArtistArtwork(artistDir, info)
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Printf("Warning: Failed to write artist artwork for %s: %v\n", artistDir, err)
+	}
+	result.Files = files
+	return result
+}
+
+func (c *CoverClient) writeAlbumArtwork(albumDir, coverURL string, embedMaxQualityCover bool) ([]string, error) {
+	coverPath := filepath.Join(albumDir, albumCoverFilename)
+	folderPath := filepath.Join(albumDir, folderArtworkFilename)
+
+	if !nonEmptyFileExists(coverPath) {
+		if coverURL == "" {
+			return nil, fmt.Errorf("cover URL is required")
+		}
+
+		tmpPath := coverPath + ".tmp"
+		if err := c.DownloadCoverToPath(coverURL, tmpPath, embedMaxQualityCover); err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmpPath)
+
+		if _, err := SaveAlbumCover(tmpPath, albumDir); err != nil {
+			return nil, err
+		}
+	}
+
+	files := []string{coverPath}
+	if !nonEmptyFileExists(folderPath) {
+		data, err := os.ReadFile(coverPath)
+		if err != nil {
+			return files, fmt.Errorf("failed to read album cover: %w", err)
+		}
+		if err := os.WriteFile(folderPath, data, 0644); err != nil {
+			return files, fmt.Errorf("failed to write folder artwork: %w", err)
+		}
+	}
+
+	return append(files, folderPath), nil
+}
+
+func (c *CoverClient) writeArtistArtwork(artistDir string, info *ArtistInfoMetadata) ([]string, error) {
+	images := []struct {
+		url      string
+		filename string
+	}{
+		{info.Images, artistArtworkFilename},
+	}
+
+	backdrops := make([]string, 0, len(info.Gallery)+1)
+	if info.Header != "" {
+		backdrops = append(backdrops, info.Header)
+	}
+	backdrops = append(backdrops, info.Gallery...)
+
+	for i, backdropURL := range backdrops {
+		filename := backdropArtworkFilename
+		if i > 0 {
+			filename = fmt.Sprintf("backdrop%d.jpg", i)
+		}
+		images = append(images, struct {
+			url      string
+			filename string
+		}{backdropURL, filename})
+	}
+
+	var files []string
+	var lastErr error
+	for _, img := range images {
+		if img.url == "" {
+			continue
+		}
+
+		path := filepath.Join(artistDir, img.filename)
+		if !nonEmptyFileExists(path) {
+			if err := c.downloadCoverURL(img.url, path); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		files = append(files, path)
+	}
+
+	return files, lastErr
+}
+
+func nonEmptyFileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > 0
+}
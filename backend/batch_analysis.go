@@ -0,0 +1,271 @@
+package backend
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	maxBatchAnalysisWorkers = 8
+	outlierLoudnessLU       = 6.0
+	outlierCutoffHz         = 3000.0
+
+	OutlierLoudness = "loudness"
+	OutlierBitDepth = "bit_depth"
+	OutlierCutoff   = "cutoff"
+)
+
+type BatchAnalysisOptions struct {
+	Workers         int  `json:"workers"`
+	IncludeSpectrum bool `json:"include_spectrum"`
+}
+
+type BatchAnalysisItem struct {
+	FilePath string          `json:"file_path"`
+	Result   *AnalysisResult `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Outliers []string        `json:"outliers,omitempty"`
+}
+
+type BatchAnalysisProgress struct {
+	FilePath  string `json:"file_path"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BatchAnalysisSummary struct {
+	Total          int     `json:"total"`
+	Analyzed       int     `json:"analyzed"`
+	Failed         int     `json:"failed"`
+	Flagged        int     `json:"flagged"`
+	Cancelled      bool    `json:"cancelled"`
+	MedianLoudness float64 `json:"median_loudness"`
+	MedianCutoff   float64 `json:"median_cutoff"`
+}
+
+type BatchAnalysisReport struct {
+	Items   []BatchAnalysisItem  `json:"items"`
+	Albums  []AlbumDynamicRange  `json:"albums"`
+	Summary BatchAnalysisSummary `json:"summary"`
+}
+
+func AnalyzeBatch(ctx context.Context, filePaths []string, opts BatchAnalysisOptions, progress func(BatchAnalysisProgress)) (*BatchAnalysisReport, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > maxBatchAnalysisWorkers {
+		workers = maxBatchAnalysisWorkers
+	}
+
+	items := make([]BatchAnalysisItem, len(filePaths))
+	for i, filePath := range filePaths {
+		items[i].FilePath = filePath
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	completed := 0
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item := &items[i]
+				result, err := AnalyzeTrackCached(item.FilePath)
+				if err != nil {
+					item.Error = err.Error()
+				} else {
+					if !opts.IncludeSpectrum {
+						result.Spectrum = nil
+					}
+					item.Result = result
+				}
+
+				mu.Lock()
+				completed++
+				update := BatchAnalysisProgress{
+					FilePath:  item.FilePath,
+					Completed: completed,
+					Total:     len(items),
+					Error:     item.Error,
+				}
+				if progress != nil {
+					progress(update)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	cancelled := false
+dispatch:
+	for i := range items {
+		select {
+		case <-ctx.Done():
+			cancelled = true
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if cancelled {
+		for i := range items {
+			if items[i].Result == nil && items[i].Error == "" {
+				items[i].Error = "cancelled"
+			}
+		}
+	}
+
+	report := buildBatchReport(items)
+	report.Summary.Cancelled = cancelled
+	if cancelled {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
+
+func buildBatchReport(items []BatchAnalysisItem) *BatchAnalysisReport {
+	report := &BatchAnalysisReport{Items: items}
+	report.Summary.Total = len(items)
+
+	var results []*AnalysisResult
+	var loudness, cutoffs []float64
+	for _, item := range items {
+		if item.Result == nil {
+			report.Summary.Failed++
+			continue
+		}
+		report.Summary.Analyzed++
+		results = append(results, item.Result)
+
+		if item.Result.Loudness != nil {
+			loudness = append(loudness, item.Result.Loudness.IntegratedLoudness)
+		}
+		if item.Result.Verdict != nil {
+			cutoffs = append(cutoffs, item.Result.Verdict.SpectralCutoff)
+		}
+	}
+
+	report.Summary.MedianLoudness = median(loudness)
+	report.Summary.MedianCutoff = median(cutoffs)
+	report.Albums = AlbumDynamicRanges(results)
+
+	for i := range items {
+		items[i].Outliers = detectOutliers(items[i].Result, report.Summary)
+		if len(items[i].Outliers) > 0 {
+			report.Summary.Flagged++
+		}
+	}
+
+	return report
+}
+
+func detectOutliers(result *AnalysisResult, summary BatchAnalysisSummary) []string {
+	if result == nil {
+		return nil
+	}
+
+	var outliers []string
+	if result.Loudness != nil && summary.Analyzed > 1 &&
+		math.Abs(result.Loudness.IntegratedLoudness-summary.MedianLoudness) > outlierLoudnessLU {
+		outliers = append(outliers, OutlierLoudness)
+	}
+
+	if v := result.Verdict; v != nil {
+		if result.BitsPerSample > 0 && v.EffectiveBitDepth > 0 && v.EffectiveBitDepth < int(result.BitsPerSample) {
+			outliers = append(outliers, OutlierBitDepth)
+		}
+		if v.Lowpass != "" || (summary.Analyzed > 1 && v.SpectralCutoff < summary.MedianCutoff-outlierCutoffHz) {
+			outliers = append(outliers, OutlierCutoff)
+		}
+	}
+
+	return outliers
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func (r *BatchAnalysisReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func (r *BatchAnalysisReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{
+		"file_path", "codec", "sample_rate", "bit_depth", "effective_bit_depth", "duration",
+		"integrated_loudness", "loudness_range", "true_peak", "dr", "spectral_cutoff",
+		"verdict", "outliers", "error",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	formatFloat := func(v float64, prec int) string {
+		return strconv.FormatFloat(v, 'f', prec, 64)
+	}
+
+	for _, item := range r.Items {
+		row := make([]string, len(header))
+		row[0] = item.FilePath
+		row[12] = strings.Join(item.Outliers, ";")
+		row[13] = item.Error
+
+		if res := item.Result; res != nil {
+			row[1] = res.Codec
+			row[2] = strconv.Itoa(int(res.SampleRate))
+			row[3] = res.BitDepth
+			row[5] = formatFloat(res.Duration, 2)
+			if res.Loudness != nil {
+				row[6] = formatFloat(res.Loudness.IntegratedLoudness, 2)
+				row[7] = formatFloat(res.Loudness.LoudnessRange, 2)
+				row[8] = formatFloat(res.Loudness.TruePeak, 2)
+			}
+			if res.DR != nil {
+				row[9] = strconv.Itoa(res.DR.Value)
+			}
+			if v := res.Verdict; v != nil {
+				row[4] = strconv.Itoa(v.EffectiveBitDepth)
+				row[10] = formatFloat(v.SpectralCutoff, 0)
+				row[11] = v.Status
+			}
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
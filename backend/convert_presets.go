@@ -0,0 +1,405 @@
+package backend
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-flac/flacpicture"
+)
+
+const (
+	ConvertModeVBR      = "vbr"
+	ConvertModeCBR      = "cbr"
+	ConvertModeLossless = "lossless"
+)
+
+type ConvertPreset struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Format           string `json:"format"`
+	Codec            string `json:"codec"`
+	Mode             string `json:"mode"`
+	Bitrate          string `json:"bitrate,omitempty"`
+	Quality          string `json:"quality,omitempty"`
+	SampleRate       int    `json:"sample_rate,omitempty"`
+	BitDepth         int    `json:"bit_depth,omitempty"`
+	CompressionLevel int    `json:"compression_level,omitempty"`
+	OnlyIfAbove      bool   `json:"only_if_above,omitempty"`
+}
+
+var convertPresets = []ConvertPreset{
+	{ID: "opus-160-vbr", Name: "Opus 160k VBR", Format: "opus", Codec: "libopus", Mode: ConvertModeVBR, Bitrate: "160k"},
+	{ID: "opus-128-vbr", Name: "Opus 128k VBR", Format: "opus", Codec: "libopus", Mode: ConvertModeVBR, Bitrate: "128k"},
+	{ID: "opus-96-vbr", Name: "Opus 96k VBR", Format: "opus", Codec: "libopus", Mode: ConvertModeVBR, Bitrate: "96k"},
+	{ID: "opus-128-cbr", Name: "Opus 128k CBR", Format: "opus", Codec: "libopus", Mode: ConvertModeCBR, Bitrate: "128k"},
+	{ID: "ogg-q8", Name: "Vorbis q8 VBR", Format: "ogg", Codec: "libvorbis", Mode: ConvertModeVBR, Quality: "8"},
+	{ID: "ogg-q6", Name: "Vorbis q6 VBR", Format: "ogg", Codec: "libvorbis", Mode: ConvertModeVBR, Quality: "6"},
+	{ID: "ogg-320-cbr", Name: "Vorbis 320k CBR", Format: "ogg", Codec: "libvorbis", Mode: ConvertModeCBR, Bitrate: "320k"},
+	{ID: "mp3-320-cbr", Name: "MP3 320k CBR", Format: "mp3", Codec: "libmp3lame", Mode: ConvertModeCBR, Bitrate: "320k"},
+	{ID: "mp3-v0", Name: "MP3 V0 VBR", Format: "mp3", Codec: "libmp3lame", Mode: ConvertModeVBR, Quality: "0"},
+	{ID: "m4a-aac-256", Name: "AAC 256k", Format: "m4a", Codec: "aac", Mode: ConvertModeCBR, Bitrate: "256k"},
+	{ID: "m4a-alac", Name: "ALAC", Format: "m4a", Codec: "alac", Mode: ConvertModeLossless},
+	{ID: "flac-8", Name: "FLAC level 8", Format: "flac", Codec: "flac", Mode: ConvertModeLossless, CompressionLevel: 8},
+	{ID: "flac-5", Name: "FLAC level 5", Format: "flac", Codec: "flac", Mode: ConvertModeLossless, CompressionLevel: 5},
+	{ID: "flac-16-44", Name: "FLAC downsample 16/44.1", Format: "flac", Codec: "flac", Mode: ConvertModeLossless, SampleRate: 44100, BitDepth: 16, CompressionLevel: 8},
+	{ID: "flac-24-48", Name: "FLAC downsample 24/48", Format: "flac", Codec: "flac", Mode: ConvertModeLossless, SampleRate: 48000, BitDepth: 24, CompressionLevel: 8},
+	{ID: "wav-16", Name: "WAV 16-bit", Format: "wav", Codec: "pcm", Mode: ConvertModeLossless, BitDepth: 16},
+	{ID: "wav-24", Name: "WAV 24-bit", Format: "wav", Codec: "pcm", Mode: ConvertModeLossless, BitDepth: 24},
+	{ID: "aiff-16", Name: "AIFF 16-bit", Format: "aiff", Codec: "pcm", Mode: ConvertModeLossless, BitDepth: 16},
+	{ID: "aiff-24", Name: "AIFF 24-bit", Format: "aiff", Codec: "pcm", Mode: ConvertModeLossless, BitDepth: 24},
+}
+
+var opusSampleRates = map[int]bool{8000: true, 12000: true, 16000: true, 24000: true, 48000: true}
+
+var (
+	soxrSupport     = make(map[string]bool)
+	soxrSupportLock sync.Mutex
+)
+
+func GetConvertPresets() []ConvertPreset {
+	return append([]ConvertPreset(nil), convertPresets...)
+}
+
+func FindConvertPreset(id string) (ConvertPreset, bool) {
+	for _, p := range convertPresets {
+		if strings.EqualFold(p.ID, id) {
+			return p, true
+		}
+	}
+	return ConvertPreset{}, false
+}
+
+func resolveConvertPreset(req ConvertAudioRequest) (ConvertPreset, error) {
+	var preset ConvertPreset
+	if req.Preset != "" {
+		p, ok := FindConvertPreset(req.Preset)
+		if !ok {
+			return preset, fmt.Errorf("unknown preset: %s", req.Preset)
+		}
+		preset = p
+	} else {
+		preset = ConvertPreset{
+			Format:  strings.ToLower(req.OutputFormat),
+			Codec:   req.Codec,
+			Mode:    ConvertModeCBR,
+			Bitrate: req.Bitrate,
+		}
+		switch preset.Format {
+		case "mp3":
+			preset.Codec = "libmp3lame"
+		case "m4a":
+			if preset.Codec == "" {
+				preset.Codec = "aac"
+			}
+			if preset.Codec == "alac" {
+				preset.Mode = ConvertModeLossless
+			}
+		case "opus":
+			preset.Codec = "libopus"
+			preset.Mode = ConvertModeVBR
+		case "ogg":
+			preset.Codec = "libvorbis"
+			preset.Mode = ConvertModeVBR
+		case "flac":
+			preset.Codec = "flac"
+			preset.Mode = ConvertModeLossless
+			preset.CompressionLevel = 8
+		case "wav", "aiff":
+			preset.Codec = "pcm"
+			preset.Mode = ConvertModeLossless
+		default:
+			return preset, fmt.Errorf("unsupported output format: %s", req.OutputFormat)
+		}
+	}
+
+	if req.Mode != "" && preset.Mode != ConvertModeLossless {
+		preset.Mode = strings.ToLower(req.Mode)
+	}
+	if req.Preset != "" && req.Bitrate != "" {
+		preset.Bitrate = req.Bitrate
+	}
+	if req.Quality != "" {
+		preset.Quality = req.Quality
+	}
+	if req.SampleRate > 0 {
+		preset.SampleRate = req.SampleRate
+	}
+	if req.BitDepth > 0 {
+		preset.BitDepth = req.BitDepth
+	}
+	if req.CompressionLevel != nil {
+		preset.CompressionLevel = *req.CompressionLevel
+	}
+	if req.OnlyIfAbove {
+		preset.OnlyIfAbove = true
+	}
+
+	if preset.Mode != ConvertModeVBR && preset.Mode != ConvertModeCBR && preset.Mode != ConvertModeLossless {
+		return preset, fmt.Errorf("invalid encoding mode: %s", preset.Mode)
+	}
+	if preset.Format == "opus" && preset.SampleRate > 0 && !opusSampleRates[preset.SampleRate] {
+		return preset, fmt.Errorf("opus does not support a sample rate of %d Hz", preset.SampleRate)
+	}
+	if preset.BitDepth != 0 && preset.BitDepth != 16 && preset.BitDepth != 24 && preset.BitDepth != 32 {
+		return preset, fmt.Errorf("unsupported bit depth: %d", preset.BitDepth)
+	}
+	if preset.Format == "flac" && (preset.CompressionLevel < 0 || preset.CompressionLevel > 12) {
+		return preset, fmt.Errorf("invalid FLAC compression level: %d", preset.CompressionLevel)
+	}
+
+	return preset, nil
+}
+
+func (p ConvertPreset) Extension() string {
+	return "." + p.Format
+}
+
+func (p ConvertPreset) forSource(sourceRate, sourceBits int) (ConvertPreset, bool) {
+	if !p.OnlyIfAbove || (p.SampleRate == 0 && p.BitDepth == 0) {
+		return p, true
+	}
+
+	if p.SampleRate > 0 && sourceRate > 0 && sourceRate <= p.SampleRate {
+		p.SampleRate = 0
+	}
+	if p.BitDepth > 0 && sourceBits > 0 && sourceBits <= p.BitDepth {
+		p.BitDepth = 0
+	}
+	return p, p.SampleRate != 0 || p.BitDepth != 0
+}
+
+func (p ConvertPreset) resampleArgs(sourceRate, sourceBits int, soxr bool) []string {
+	var opts []string
+	if p.SampleRate > 0 && p.SampleRate != sourceRate {
+		opts = append(opts, "osr="+strconv.Itoa(p.SampleRate))
+		if soxr {
+			opts = append(opts, "resampler=soxr", "precision=28")
+		}
+	}
+	if p.BitDepth == 16 && (sourceBits == 0 || sourceBits > 16) {
+		opts = append(opts, "osf=s16", "dither_method=triangular")
+	}
+
+	if len(opts) == 0 {
+		return nil
+	}
+	return []string{"-af", "aresample=" + strings.Join(opts, ":")}
+}
+
+func ffmpegHasSoxr(ffmpegPath string) bool {
+	soxrSupportLock.Lock()
+	defer soxrSupportLock.Unlock()
+
+	if supported, ok := soxrSupport[ffmpegPath]; ok {
+		return supported
+	}
+
+	cmd := exec.Command(ffmpegPath, "-hide_banner", "-buildconf")
+	setHideWindow(cmd)
+	output, err := cmd.CombinedOutput()
+	supported := err == nil && strings.Contains(string(output), "--enable-libsoxr")
+	soxrSupport[ffmpegPath] = supported
+	return supported
+}
+
+func (p ConvertPreset) codecArgs(sourceRate, sourceBits int, soxr bool) []string {
+	bits := p.BitDepth
+	if bits == 0 {
+		bits = sourceBits
+	}
+
+	var args []string
+	switch p.Format {
+	case "mp3":
+		args = append(args, "-codec:a", "libmp3lame")
+		if p.Mode == ConvertModeVBR {
+			args = append(args, "-q:a", defaultString(p.Quality, "0"))
+		} else {
+			args = append(args, "-b:a", defaultString(p.Bitrate, "320k"))
+		}
+		args = append(args, "-id3v2_version", "3")
+	case "m4a":
+		if p.Codec == "alac" {
+			args = append(args, "-codec:a", "alac")
+			switch bits {
+			case 16:
+				args = append(args, "-sample_fmt", "s16p")
+			case 24, 32:
+				args = append(args, "-sample_fmt", "s32p")
+			}
+		} else {
+			args = append(args, "-codec:a", "aac", "-b:a", defaultString(p.Bitrate, "256k"))
+		}
+	case "opus":
+		args = append(args, "-codec:a", "libopus", "-b:a", defaultString(p.Bitrate, "128k"))
+		if p.Mode == ConvertModeCBR {
+			args = append(args, "-vbr", "off")
+		} else {
+			args = append(args, "-vbr", "on")
+		}
+	case "ogg":
+		args = append(args, "-codec:a", "libvorbis")
+		if p.Mode == ConvertModeCBR {
+			bitrate := defaultString(p.Bitrate, "320k")
+			args = append(args, "-b:a", bitrate, "-minrate", bitrate, "-maxrate", bitrate)
+		} else {
+			args = append(args, "-q:a", defaultString(p.Quality, "6"))
+		}
+	case "flac":
+		args = append(args, "-codec:a", "flac", "-compression_level", strconv.Itoa(p.CompressionLevel))
+		switch bits {
+		case 16:
+			args = append(args, "-sample_fmt", "s16")
+		case 24:
+			args = append(args, "-sample_fmt", "s32", "-bits_per_raw_sample", "24")
+		}
+	case "wav", "aiff":
+		if bits == 0 {
+			bits = 16
+		}
+		endian := "le"
+		if p.Format == "aiff" {
+			endian = "be"
+		}
+		args = append(args, "-codec:a", fmt.Sprintf("pcm_s%d%s", bits, endian))
+		if p.Format == "aiff" {
+			args = append(args, "-write_id3v2", "1")
+		}
+	}
+
+	args = append(args, p.resampleArgs(sourceRate, sourceBits, soxr)...)
+	if p.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(p.SampleRate))
+	}
+	return args
+}
+
+func (p ConvertPreset) tagsWithFFmpeg() bool {
+	switch p.Format {
+	case "opus", "ogg", "wav", "aiff":
+		return true
+	}
+	return false
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func ConvertOutputPath(inputFile, outputDir, template string, preset ConvertPreset) string {
+	baseName := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+	root := outputDir
+	if root == "" {
+		root = filepath.Dir(inputFile)
+	}
+
+	if strings.TrimSpace(template) == "" {
+		return filepath.Join(root, strings.ToUpper(preset.Format), baseName+preset.Extension())
+	}
+
+	metadata, err := ReadAudioMetadata(inputFile)
+	if err != nil || metadata == nil {
+		metadata = &AudioMetadata{}
+	}
+
+	name := RenderTemplate(template, TemplateDataFromMetadata(metadata))
+	if name == "" {
+		name = baseName
+	}
+
+	return filepath.Join(root, filepath.FromSlash(name)+preset.Extension())
+}
+
+func escapeFFMetadata(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	return replacer.Replace(value)
+}
+
+func writeFFMetadataFile(metadata Metadata, coverPath string, preset ConvertPreset) (string, error) {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+
+	add := func(key, value string) {
+		if value != "" {
+			b.WriteString(key + "=" + escapeFFMetadata(value) + "\n")
+		}
+	}
+
+	add("title", metadata.Title)
+	add("artist", metadata.Artist)
+	add("album", metadata.Album)
+	add("album_artist", metadata.AlbumArtist)
+	date := metadata.Date
+	if date == "" {
+		date = metadata.ReleaseDate
+	}
+	add("date", date)
+	if metadata.TrackNumber > 0 {
+		track := strconv.Itoa(metadata.TrackNumber)
+		if metadata.TotalTracks > 0 {
+			track = fmt.Sprintf("%d/%d", metadata.TrackNumber, metadata.TotalTracks)
+		}
+		add("track", track)
+	}
+	if metadata.DiscNumber > 0 {
+		disc := strconv.Itoa(metadata.DiscNumber)
+		if metadata.TotalDiscs > 0 {
+			disc = fmt.Sprintf("%d/%d", metadata.DiscNumber, metadata.TotalDiscs)
+		}
+		add("disc", disc)
+	}
+	add("copyright", metadata.Copyright)
+	add("publisher", metadata.Publisher)
+	add("isrc", metadata.ISRC)
+	add("comment", metadata.Description)
+	if preset.Format == "opus" || preset.Format == "ogg" {
+		add("lyrics", metadata.Lyrics)
+		if coverPath != "" {
+			if picture, err := vorbisPictureComment(coverPath); err == nil {
+				add("METADATA_BLOCK_PICTURE", picture)
+			} else {
+				fmt.Printf("[FFmpeg] Warning: Failed to prepare cover art: %v\n", err)
+			}
+		}
+	}
+
+	tmpFile, err := os.CreateTemp("", "ffmetadata-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.WriteString(b.String()); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
+
+func vorbisPictureComment(coverPath string) (string, error) {
+	cover, err := LoadCoverImage(coverPath, CoverImageOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	picture := &flacpicture.MetadataBlockPicture{
+		PictureType: flacpicture.PictureTypeFrontCover,
+		MIME:        cover.MIME,
+		Description: "Cover",
+		Width:       uint32(cover.Width),
+		Height:      uint32(cover.Height),
+		ColorDepth:  uint32(cover.Depth),
+		ImageData:   cover.Data,
+	}
+	block := picture.Marshal()
+	return base64.StdEncoding.EncodeToString(block.Data), nil
+}
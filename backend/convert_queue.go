@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"time"
+)
+
+type ConvertQueueInfo struct {
+	IsConverting   bool           `json:"is_converting"`
+	Queue          []DownloadItem `json:"queue"`
+	QueuedCount    int            `json:"queued_count"`
+	CompletedCount int            `json:"completed_count"`
+	FailedCount    int            `json:"failed_count"`
+	SkippedCount   int            `json:"skipped_count"`
+}
+
+func AddToConvertQueue(id, inputFile string) {
+	downloadQueueLock.Lock()
+	defer downloadQueueLock.Unlock()
+
+	downloadQueue = append(downloadQueue, DownloadItem{
+		ID:        id,
+		Kind:      QueueKindConvert,
+		InputFile: inputFile,
+		Status:    StatusQueued,
+	})
+}
+
+func StartConvertItem(id, outputFile string, duration float64) {
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusConverting
+		item.FilePath = outputFile
+		item.Duration = duration
+		item.StartTime = time.Now().Unix()
+		item.Progress = 0
+		item.ErrorMessage = ""
+	})
+}
+
+func UpdateConvertProgress(id string, progress, speed float64) {
+	UpdateItemProgress(id, progress, speed)
+}
+
+func CompleteConvertItem(id, outputFile string) {
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusCompleted
+		item.EndTime = time.Now().Unix()
+		item.FilePath = outputFile
+		item.Progress = 100
+	})
+}
+
+func FailConvertItem(id, errorMsg string) {
+	FailDownloadItem(id, errorMsg)
+}
+
+func SkipConvertItem(id, reason string) {
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusSkipped
+		item.EndTime = time.Now().Unix()
+		item.ErrorMessage = reason
+	})
+}
+
+func GetConvertQueue() ConvertQueueInfo {
+	info := ConvertQueueInfo{Queue: queueItemsOfKind(QueueKindConvert)}
+
+	for _, item := range info.Queue {
+		switch item.Status {
+		case StatusQueued:
+			info.QueuedCount++
+			info.IsConverting = true
+		case StatusConverting:
+			info.IsConverting = true
+		case StatusCompleted:
+			info.CompletedCount++
+		case StatusFailed:
+			info.FailedCount++
+		case StatusSkipped:
+			info.SkippedCount++
+		}
+	}
+
+	return info
+}
+
+func ClearConvertQueue() {
+	clearFinishedQueueItems(QueueKindConvert)
+}
@@ -1,12 +1,18 @@
 package backend
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 )
@@ -53,8 +59,35 @@ type HeaderDownloadResponse struct {
 	AlreadyExists bool   `json:"already_exists,omitempty"`
 }
 
+type CoverImageOptions struct {
+	MaxSize        int             `json:"max_size"`
+	Format         string          `json:"format"`
+	Quality        int             `json:"quality"`
+	SaveAlbumCover bool            `json:"save_album_cover"`
+	SourcePolicy   string          `json:"source_policy"`
+	SourceURLs     CoverSourceURLs `json:"source_urls"`
+}
+
+type CoverImage struct {
+	Data   []byte `json:"-"`
+	MIME   string `json:"mime"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Depth  int    `json:"depth"`
+}
+
+const (
+	albumCoverFilename    = "cover.jpg"
+	defaultCoverQuality   = 90
+	maxCoverDimension     = 10000
+	fullResolutionQuality = 95
+	coverFormatJPEG       = "jpeg"
+	coverFormatPNG        = "png"
+)
+
 type CoverClient struct {
 	httpClient *http.Client
+	options    CoverImageOptions
 }
 
 func NewCoverClient() *CoverClient {
@@ -63,60 +96,9 @@ func NewCoverClient() *CoverClient {
 	}
 }
 
-func buildCoverFilename(trackName, artistName, albumName, albumArtist, releaseDate, filenameFormat string, includeTrackNumber bool, position, discNumber int) string {
-	safeTitle := sanitizeFilename(trackName)
-	safeArtist := sanitizeFilename(artistName)
-	safeAlbum := sanitizeFilename(albumName)
-	safeAlbumArtist := sanitizeFilename(albumArtist)
-
-	year := ""
-	if len(releaseDate) >= 4 {
-		year = releaseDate[:4]
-	}
-
-	var filename string
-
-	if strings.Contains(filenameFormat, "{") {
-		filename = filenameFormat
-		filename = strings.ReplaceAll(filename, "{title}", safeTitle)
-		filename = strings.ReplaceAll(filename, "{artist}", safeArtist)
-		filename = strings.ReplaceAll(filename, "{album}", safeAlbum)
-		filename = strings.ReplaceAll(filename, "{album_artist}", safeAlbumArtist)
-		filename = strings.ReplaceAll(filename, "{year}", year)
-
-		if discNumber > 0 {
-			filename = strings.ReplaceAll(filename, "{disc}", fmt.Sprintf("%d", discNumber))
-		} else {
-			filename = strings.ReplaceAll(filename, "{disc}", "")
-		}
-
-		if position > 0 {
-			filename = strings.ReplaceAll(filename, "{track}", fmt.Sprintf("%02d", position))
-		} else {
-
-			filename = regexp.MustCompile(`\{track\}\.\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*-\s*`).ReplaceAllString(filename, "")
-			filename = regexp.MustCompile(`\{track\}\s*`).ReplaceAllString(filename, "")
-		}
-	} else {
-
-		switch filenameFormat {
-		case "artist-title":
-			filename = fmt.Sprintf("%s - %s", safeArtist, safeTitle)
-		case "title-artist":
-			filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
-		case "title":
-			filename = safeTitle
-		default:
-			filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
-		}
-
-		if includeTrackNumber && position > 0 {
-			filename = fmt.Sprintf("%02d - %s", position, filename)
-		}
-	}
-
-	return filename + ".cover.jpg"
+func (c *CoverClient) WithCoverOptions(opts CoverImageOptions) *CoverClient {
+	c.options = opts
+	return c
 }
 
 func convertSmallToMedium(imageURL string) string {
@@ -145,6 +127,10 @@ func (c *CoverClient) DownloadCoverToPath(coverURL, outputPath string, embedMaxQ
 		downloadURL = c.getMaxResolutionURL(downloadURL)
 	}
 
+	return c.downloadCoverURL(downloadURL, outputPath)
+}
+
+func (c *CoverClient) downloadCoverURL(downloadURL, outputPath string) error {
 	resp, err := c.httpClient.Get(downloadURL)
 	if err != nil {
 		return fmt.Errorf("failed to download cover: %v", err)
@@ -155,20 +141,305 @@ func (c *CoverClient) DownloadCoverToPath(coverURL, outputPath string, embedMaxQ
 		return fmt.Errorf("failed to download cover: HTTP %d", resp.StatusCode)
 	}
 
-	file, err := os.Create(outputPath)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %v", err)
+		return fmt.Errorf("failed to read cover: %v", err)
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
+	if _, _, err := DecodeCoverImage(data); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write cover file: %v", err)
 	}
 
 	return nil
 }
 
+func DecodeCoverImage(data []byte) (image.Image, string, error) {
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("cover image is empty")
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid cover image: %w", err)
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxCoverDimension || cfg.Height > maxCoverDimension {
+		return nil, "", fmt.Errorf("invalid cover image dimensions: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode cover image: %w", err)
+	}
+
+	return img, format, nil
+}
+
+func ProcessCoverImage(data []byte, opts CoverImageOptions) (*CoverImage, error) {
+	img, format, err := DecodeCoverImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	targetFormat := strings.ToLower(opts.Format)
+	if targetFormat == "jpg" {
+		targetFormat = coverFormatJPEG
+	}
+	if targetFormat != coverFormatJPEG && targetFormat != coverFormatPNG {
+		targetFormat = format
+	}
+
+	bounds := img.Bounds()
+	needsResize := opts.MaxSize > 0 && (bounds.Dx() > opts.MaxSize || bounds.Dy() > opts.MaxSize)
+
+	if !needsResize && targetFormat == format && (format == coverFormatJPEG || format == coverFormatPNG) {
+		return &CoverImage{
+			Data:   data,
+			MIME:   "image/" + format,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Depth:  imageColorDepth(img),
+		}, nil
+	}
+
+	if needsResize {
+		img = resizeImage(img, opts.MaxSize)
+	}
+
+	if targetFormat != coverFormatPNG {
+		targetFormat = coverFormatJPEG
+	}
+
+	encoded, err := encodeCoverImage(img, targetFormat, opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds = img.Bounds()
+	depth := imageColorDepth(img)
+	if targetFormat == coverFormatJPEG && depth > 24 {
+		depth = 24
+	}
+
+	return &CoverImage{
+		Data:   encoded,
+		MIME:   "image/" + targetFormat,
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Depth:  depth,
+	}, nil
+}
+
+func LoadCoverImage(coverPath string, opts CoverImageOptions) (*CoverImage, error) {
+	data, err := os.ReadFile(coverPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cover image: %w", err)
+	}
+	return ProcessCoverImage(data, opts)
+}
+
+func encodeCoverImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case coverFormatPNG:
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode PNG cover: %w", err)
+		}
+	default:
+		if quality <= 0 || quality > 100 {
+			quality = defaultCoverQuality
+		}
+		if err := jpeg.Encode(&buf, flattenImage(img), &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode JPEG cover: %w", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func flattenImage(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+	return flat
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func imageColorDepth(img image.Image) int {
+	switch img.ColorModel() {
+	case color.GrayModel:
+		return 8
+	case color.Gray16Model:
+		return 16
+	}
+
+	if isOpaque(img) {
+		return 24
+	}
+	return 32
+}
+
+func resizeImage(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = srcH * maxSize / srcW
+	} else if srcH > srcW {
+		dstW = srcW * maxSize / srcH
+	}
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	xWeights := areaWeights(srcW, dstW)
+	yWeights := areaWeights(srcH, dstH)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	row := make([]float64, dstW*4)
+
+	for dy := 0; dy < dstH; dy++ {
+		for i := range row {
+			row[i] = 0
+		}
+
+		for _, yw := range yWeights[dy] {
+			srcRow := src.Pix[yw.index*src.Stride:]
+			for dx := 0; dx < dstW; dx++ {
+				for _, xw := range xWeights[dx] {
+					w := yw.weight * xw.weight
+					p := srcRow[xw.index*4:]
+					row[dx*4] += float64(p[0]) * w
+					row[dx*4+1] += float64(p[1]) * w
+					row[dx*4+2] += float64(p[2]) * w
+					row[dx*4+3] += float64(p[3]) * w
+				}
+			}
+		}
+
+		dstRow := dst.Pix[dy*dst.Stride:]
+		for i := 0; i < dstW*4; i++ {
+			v := row[i] + 0.5
+			if v > 255 {
+				v = 255
+			}
+			dstRow[i] = uint8(v)
+		}
+	}
+
+	return dst
+}
+
+type pixelWeight struct {
+	index  int
+	weight float64
+}
+
+func areaWeights(srcSize, dstSize int) [][]pixelWeight {
+	scale := float64(srcSize) / float64(dstSize)
+	weights := make([][]pixelWeight, dstSize)
+
+	for d := 0; d < dstSize; d++ {
+		start := float64(d) * scale
+		end := start + scale
+
+		for s := int(start); s < srcSize && float64(s) < end; s++ {
+			lo := float64(s)
+			if lo < start {
+				lo = start
+			}
+			hi := float64(s + 1)
+			if hi > end {
+				hi = end
+			}
+			if hi > lo {
+				weights[d] = append(weights[d], pixelWeight{index: s, weight: (hi - lo) / scale})
+			}
+		}
+	}
+
+	return weights
+}
+
+func SaveAlbumCover(coverPath, albumDir string) (string, error) {
+	targetPath := filepath.Join(albumDir, albumCoverFilename)
+	if fileInfo, err := os.Stat(targetPath); err == nil && fileInfo.Size() > 0 {
+		return targetPath, nil
+	}
+
+	data, err := os.ReadFile(coverPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cover image: %w", err)
+	}
+
+	img, format, err := DecodeCoverImage(data)
+	if err != nil {
+		return "", err
+	}
+
+	if format != coverFormatJPEG {
+		data, err = encodeCoverImage(img, coverFormatJPEG, fullResolutionQuality)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.WriteFile(targetPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write album cover: %w", err)
+	}
+
+	return targetPath, nil
+}
+
+func (c *CoverClient) saveAlbumCoverIfEnabled(lookup CoverLookup, coverPath, albumDir string, embedMaxQualityCover bool) {
+	if coverPath == "" || !c.options.SaveAlbumCover {
+		return
+	}
+	if nonEmptyFileExists(filepath.Join(albumDir, albumCoverFilename)) {
+		return
+	}
+
+	source := coverPath
+	if !embedMaxQualityCover {
+		fullPath := coverPath + ".full"
+		if err := c.DownloadBestCoverToPath(lookup, fullPath, true); err != nil {
+			fmt.Printf("Warning: Failed to download full resolution cover, saving the embedded one: %v\n", err)
+		} else {
+			defer os.Remove(fullPath)
+			source = fullPath
+		}
+	}
+
+	if path, err := SaveAlbumCover(source, albumDir); err != nil {
+		fmt.Printf("Warning: Failed to save album cover: %v\n", err)
+	} else {
+		fmt.Printf("Album cover saved: %s\n", path)
+	}
+}
+
 func (c *CoverClient) DownloadCover(req CoverDownloadRequest) (*CoverDownloadResponse, error) {
 	if req.CoverURL == "" {
 		return &CoverDownloadResponse{
@@ -184,20 +455,28 @@ func (c *CoverClient) DownloadCover(req CoverDownloadRequest) (*CoverDownloadRes
 		outputDir = NormalizePath(outputDir)
 	}
 
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return &CoverDownloadResponse{
-			Success: false,
-			Error:   fmt.Sprintf("failed to create output directory: %v", err),
-		}, err
-	}
-
 	filenameFormat := req.FilenameFormat
 	if filenameFormat == "" {
 		filenameFormat = "title-artist"
 	}
-	filename := buildCoverFilename(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, filenameFormat, req.TrackNumber, req.Position, req.DiscNumber)
+	filename := BuildTrackFilename(filenameFormat, TemplateData{
+		Title:       req.TrackName,
+		Artist:      req.ArtistName,
+		Album:       req.AlbumName,
+		AlbumArtist: req.AlbumArtist,
+		ReleaseDate: req.ReleaseDate,
+		Track:       req.Position,
+		Disc:        req.DiscNumber,
+	}, req.TrackNumber, ".cover.jpg")
 	filePath := filepath.Join(outputDir, filename)
 
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return &CoverDownloadResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to create output directory: %v", err),
+		}, err
+	}
+
 	if fileInfo, err := os.Stat(filePath); err == nil && fileInfo.Size() > 0 {
 		return &CoverDownloadResponse{
 			Success:       true,
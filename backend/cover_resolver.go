@@ -0,0 +1,426 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CoverPolicySpotify       = "spotify"
+	CoverPolicyLargestSquare = "largest_square"
+	CoverPolicyLargest       = "largest"
+
+	coverSourceSpotify     = "spotify"
+	coverSourceQobuz       = "qobuz"
+	coverSourceTidal       = "tidal"
+	coverSourceITunes      = "itunes"
+	coverSourceCoverArtArc = "coverartarchive"
+
+	coverProbeBytes      = 256 * 1024
+	coverSquareTolerance = 0.01
+)
+
+type CoverSourceURLs struct {
+	QobuzAPI        string `json:"qobuz_api"`
+	TidalAPI        string `json:"tidal_api"`
+	TidalResources  string `json:"tidal_resources"`
+	ITunesAPI       string `json:"itunes_api"`
+	MusicBrainzAPI  string `json:"musicbrainz_api"`
+	CoverArtArchive string `json:"cover_art_archive"`
+}
+
+type CoverLookup struct {
+	SpotifyCoverURL string `json:"spotify_cover_url"`
+	ISRC            string `json:"isrc"`
+	QobuzImageURL   string `json:"qobuz_image_url"`
+	TidalTrackID    int64  `json:"tidal_track_id"`
+	TidalAPIURL     string `json:"tidal_api_url"`
+	Artist          string `json:"artist"`
+	Album           string `json:"album"`
+}
+
+type CoverCandidate struct {
+	Source string `json:"source"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type CoverResolver struct {
+	httpClient *http.Client
+	urls       CoverSourceURLs
+	qobuzAppID string
+}
+
+func DefaultCoverSourceURLs() CoverSourceURLs {
+	return CoverSourceURLs{
+		QobuzAPI:        "https://www.qobuz.com/api.json/0.2",
+		TidalResources:  "https://resources.tidal.com/images",
+		ITunesAPI:       "https://itunes.apple.com",
+		MusicBrainzAPI:  "https://musicbrainz.org/ws/2",
+		CoverArtArchive: "https://coverartarchive.org",
+	}
+}
+
+func NewCoverResolver(urls CoverSourceURLs) *CoverResolver {
+	defaults := DefaultCoverSourceURLs()
+	if urls.QobuzAPI == "" {
+		urls.QobuzAPI = defaults.QobuzAPI
+	}
+	if urls.TidalResources == "" {
+		urls.TidalResources = defaults.TidalResources
+	}
+	if urls.ITunesAPI == "" {
+		urls.ITunesAPI = defaults.ITunesAPI
+	}
+	if urls.MusicBrainzAPI == "" {
+		urls.MusicBrainzAPI = defaults.MusicBrainzAPI
+	}
+	if urls.CoverArtArchive == "" {
+		urls.CoverArtArchive = defaults.CoverArtArchive
+	}
+
+	return &CoverResolver{
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		urls:       urls,
+		qobuzAppID: "798273057",
+	}
+}
+
+func (r *CoverResolver) Resolve(lookup CoverLookup, policy string, embedMaxQualityCover bool) []CoverCandidate {
+	var spotifyURL string
+	if lookup.SpotifyCoverURL != "" {
+		spotifyURL = convertSmallToMedium(lookup.SpotifyCoverURL)
+		if embedMaxQualityCover {
+			spotifyURL = NewCoverClient().getMaxResolutionURL(spotifyURL)
+		}
+	}
+
+	if policy != CoverPolicyLargestSquare && policy != CoverPolicyLargest {
+		if spotifyURL == "" {
+			return nil
+		}
+		return []CoverCandidate{{Source: coverSourceSpotify, URL: spotifyURL}}
+	}
+
+	candidates := r.gatherCandidates(lookup, spotifyURL)
+	r.probeCandidates(candidates)
+	return rankCoverCandidates(candidates, policy)
+}
+
+func (r *CoverResolver) gatherCandidates(lookup CoverLookup, spotifyURL string) []CoverCandidate {
+	var candidates []CoverCandidate
+	if spotifyURL != "" {
+		candidates = append(candidates, CoverCandidate{Source: coverSourceSpotify, URL: spotifyURL})
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	add := func(source string, urls []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, u := range urls {
+			if u != "" {
+				candidates = append(candidates, CoverCandidate{Source: source, URL: u})
+			}
+		}
+	}
+
+	sources := []struct {
+		name  string
+		fetch func(CoverLookup) ([]string, error)
+	}{
+		{coverSourceQobuz, r.qobuzCoverURLs},
+		{coverSourceTidal, r.tidalCoverURLs},
+		{coverSourceITunes, r.iTunesCoverURLs},
+		{coverSourceCoverArtArc, r.coverArtArchiveURLs},
+	}
+
+	for _, source := range sources {
+		wg.Add(1)
+		go func(name string, fetch func(CoverLookup) ([]string, error)) {
+			defer wg.Done()
+			urls, err := fetch(lookup)
+			if err != nil {
+				fmt.Printf("Cover source %s: %v\n", name, err)
+				return
+			}
+			add(name, urls)
+		}(source.name, source.fetch)
+	}
+	wg.Wait()
+
+	return candidates
+}
+
+func (r *CoverResolver) qobuzCoverURLs(lookup CoverLookup) ([]string, error) {
+	imageURL := lookup.QobuzImageURL
+	if imageURL == "" {
+		if lookup.ISRC == "" {
+			return nil, nil
+		}
+
+		searchURL := fmt.Sprintf("%s/track/search?query=%s&limit=1&app_id=%s", r.urls.QobuzAPI, url.QueryEscape(lookup.ISRC), r.qobuzAppID)
+		var searchResp QobuzSearchResponse
+		if err := r.getJSON(searchURL, &searchResp); err != nil {
+			return nil, err
+		}
+		if len(searchResp.Tracks.Items) == 0 {
+			return nil, nil
+		}
+		imageURL = searchResp.Tracks.Items[0].Album.Image.Large
+	}
+
+	if imageURL == "" {
+		return nil, nil
+	}
+
+	urls := []string{imageURL}
+	if idx := strings.LastIndex(imageURL, "_"); idx > 0 && strings.HasSuffix(imageURL, ".jpg") {
+		urls = append([]string{imageURL[:idx] + "_org.jpg"}, urls...)
+	}
+	return urls, nil
+}
+
+func (r *CoverResolver) tidalCoverURLs(lookup CoverLookup) ([]string, error) {
+	apiURL := lookup.TidalAPIURL
+	if apiURL == "" {
+		apiURL = r.urls.TidalAPI
+	}
+	if lookup.TidalTrackID == 0 || apiURL == "" {
+		return nil, nil
+	}
+
+	var info struct {
+		Data struct {
+			Album struct {
+				Cover string `json:"cover"`
+			} `json:"album"`
+		} `json:"data"`
+		Album struct {
+			Cover string `json:"cover"`
+		} `json:"album"`
+	}
+	if err := r.getJSON(fmt.Sprintf("%s/info/?id=%d", strings.TrimSuffix(apiURL, "/"), lookup.TidalTrackID), &info); err != nil {
+		return nil, err
+	}
+
+	coverID := info.Data.Album.Cover
+	if coverID == "" {
+		coverID = info.Album.Cover
+	}
+	if coverID == "" {
+		return nil, nil
+	}
+
+	path := strings.ReplaceAll(coverID, "-", "/")
+	return []string{
+		fmt.Sprintf("%s/%s/origin.jpg", r.urls.TidalResources, path),
+		fmt.Sprintf("%s/%s/1280x1280.jpg", r.urls.TidalResources, path),
+	}, nil
+}
+
+func (r *CoverResolver) iTunesCoverURLs(lookup CoverLookup) ([]string, error) {
+	if lookup.Artist == "" || lookup.Album == "" {
+		return nil, nil
+	}
+
+	term := url.QueryEscape(GetFirstArtist(lookup.Artist) + " " + lookup.Album)
+	var searchResp struct {
+		Results []struct {
+			CollectionName string `json:"collectionName"`
+			ArtworkURL100  string `json:"artworkUrl100"`
+		} `json:"results"`
+	}
+	if err := r.getJSON(fmt.Sprintf("%s/search?term=%s&entity=album&limit=5", r.urls.ITunesAPI, term), &searchResp); err != nil {
+		return nil, err
+	}
+
+	for _, result := range searchResp.Results {
+		if !strings.EqualFold(strings.TrimSpace(result.CollectionName), strings.TrimSpace(lookup.Album)) || result.ArtworkURL100 == "" {
+			continue
+		}
+		return []string{strings.Replace(result.ArtworkURL100, "100x100bb", "3000x3000bb", 1)}, nil
+	}
+
+	return nil, nil
+}
+
+func (r *CoverResolver) coverArtArchiveURLs(lookup CoverLookup) ([]string, error) {
+	if lookup.ISRC == "" {
+		return nil, nil
+	}
+
+	var isrcResp struct {
+		Recordings []struct {
+			Releases []struct {
+				ID    string `json:"id"`
+				Title string `json:"title"`
+			} `json:"releases"`
+		} `json:"recordings"`
+	}
+	if err := r.getJSON(fmt.Sprintf("%s/isrc/%s?inc=releases&fmt=json", r.urls.MusicBrainzAPI, url.PathEscape(lookup.ISRC)), &isrcResp); err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, recording := range isrcResp.Recordings {
+		for _, release := range recording.Releases {
+			if lookup.Album != "" && !strings.EqualFold(release.Title, lookup.Album) {
+				continue
+			}
+			urls = append(urls, fmt.Sprintf("%s/release/%s/front", r.urls.CoverArtArchive, release.ID))
+			if len(urls) >= 2 {
+				return urls, nil
+			}
+		}
+	}
+
+	return urls, nil
+}
+
+func (r *CoverResolver) getJSON(requestURL string, target interface{}) error {
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "SpotiFLAC/1.0 ( https://github.com/afkarxyz/SpotiFLAC )")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (r *CoverResolver) probeCandidates(candidates []CoverCandidate) {
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(c *CoverCandidate) {
+			defer wg.Done()
+			width, height, err := r.ProbeDimensions(c.URL)
+			if err != nil {
+				fmt.Printf("Cover probe failed (%s): %v\n", c.Source, err)
+				return
+			}
+			c.Width = width
+			c.Height = height
+		}(&candidates[i])
+	}
+	wg.Wait()
+}
+
+func (r *CoverResolver) ProbeDimensions(imageURL string) (int, int, error) {
+	req, err := http.NewRequest("GET", imageURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", coverProbeBytes-1))
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return 0, 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, coverProbeBytes))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to read image header: %w", err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+func isSquareCover(width, height int) bool {
+	if width <= 0 || height <= 0 {
+		return false
+	}
+	diff := width - height
+	if diff < 0 {
+		diff = -diff
+	}
+	larger := width
+	if height > larger {
+		larger = height
+	}
+	return float64(diff) <= float64(larger)*coverSquareTolerance
+}
+
+func rankCoverCandidates(candidates []CoverCandidate, policy string) []CoverCandidate {
+	var ranked []CoverCandidate
+	var spotify []CoverCandidate
+	for _, c := range candidates {
+		if c.Width > 0 && c.Height > 0 && c.Width <= maxCoverDimension && c.Height <= maxCoverDimension {
+			ranked = append(ranked, c)
+		} else if c.Source == coverSourceSpotify {
+			spotify = append(spotify, c)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if policy == CoverPolicyLargestSquare {
+			si, sj := isSquareCover(ranked[i].Width, ranked[i].Height), isSquareCover(ranked[j].Width, ranked[j].Height)
+			if si != sj {
+				return si
+			}
+		}
+		return ranked[i].Width*ranked[i].Height > ranked[j].Width*ranked[j].Height
+	})
+
+	return append(ranked, spotify...)
+}
+
+func (c *CoverClient) DownloadBestCoverToPath(lookup CoverLookup, outputPath string, embedMaxQualityCover bool) error {
+	opts := c.options
+	if opts.SourcePolicy != CoverPolicyLargestSquare && opts.SourcePolicy != CoverPolicyLargest {
+		return c.DownloadCoverToPath(lookup.SpotifyCoverURL, outputPath, embedMaxQualityCover)
+	}
+
+	candidates := NewCoverResolver(opts.SourceURLs).Resolve(lookup, opts.SourcePolicy, embedMaxQualityCover)
+	if len(candidates) == 0 {
+		return fmt.Errorf("no cover candidates found")
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		if err := c.downloadCoverURL(candidate.URL, outputPath); err != nil {
+			lastErr = err
+			fmt.Printf("Warning: cover from %s failed: %v\n", candidate.Source, err)
+			continue
+		}
+		if candidate.Width > 0 {
+			fmt.Printf("Using %s cover (%dx%d)\n", candidate.Source, candidate.Width, candidate.Height)
+		}
+		return nil
+	}
+
+	return lastErr
+}
@@ -0,0 +1,592 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mewkiz/flac"
+)
+
+const (
+	pcmChunkFrames    = 4096
+	lossyDecodeBits   = 24
+	wavFormatPCM      = 1
+	wavFormatFloat    = 3
+	wavFormatExtended = 0xFFFE
+)
+
+var losslessCodecs = map[string]bool{
+	"flac":    true,
+	"alac":    true,
+	"wavpack": true,
+	"ape":     true,
+	"tta":     true,
+	"mlp":     true,
+	"truehd":  true,
+}
+
+type PCMFormat struct {
+	SampleRate    int    `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	BitsPerSample int    `json:"bits_per_sample"`
+	TotalSamples  uint64 `json:"total_samples"`
+	Codec         string `json:"codec"`
+	Lossless      bool   `json:"lossless"`
+}
+
+type PCMDecoder interface {
+	Format() PCMFormat
+	ReadFrames() ([][]int32, error)
+	Close() error
+}
+
+type SeekablePCMDecoder interface {
+	PCMDecoder
+	Seek(sample uint64) (uint64, error)
+}
+
+func OpenPCMDecoder(filePath string) (PCMDecoder, error) {
+	if !fileExists(filePath) {
+		return nil, fmt.Errorf("file does not exist: %s", filePath)
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		return openFLACDecoder(filePath)
+	case ".wav", ".wave":
+		return openWAVDecoder(filePath)
+	default:
+		return openFFmpegDecoder(filePath)
+	}
+}
+
+func ProbePCMFormat(filePath string) (PCMFormat, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac", ".wav", ".wave":
+		dec, err := OpenPCMDecoder(filePath)
+		if err != nil {
+			return PCMFormat{}, err
+		}
+		defer dec.Close()
+		return dec.Format(), nil
+	default:
+		probe, err := probeAudioStream(filePath)
+		if err != nil {
+			return PCMFormat{}, err
+		}
+		return probe.format(), nil
+	}
+}
+
+func (f PCMFormat) maxValue() float64 {
+	return float64(int64(1) << (f.BitsPerSample - 1))
+}
+
+type flacDecoder struct {
+	file   *os.File
+	stream *flac.Stream
+	format PCMFormat
+	buf    [][]int32
+}
+
+func openFLACDecoder(filePath string) (*flacDecoder, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	stream, err := newSeekableFLAC(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to parse FLAC: %w", err)
+	}
+
+	info := stream.Info
+	if info.SampleRate == 0 || info.NChannels == 0 {
+		f.Close()
+		return nil, fmt.Errorf("invalid stream info")
+	}
+
+	return &flacDecoder{
+		file:   f,
+		stream: stream,
+		format: PCMFormat{
+			SampleRate:    int(info.SampleRate),
+			Channels:      int(info.NChannels),
+			BitsPerSample: int(info.BitsPerSample),
+			TotalSamples:  info.NSamples,
+			Codec:         "flac",
+			Lossless:      true,
+		},
+		buf: make([][]int32, info.NChannels),
+	}, nil
+}
+
+func newSeekableFLAC(f *os.File) (*flac.Stream, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, err
+	}
+
+	var offset int64
+	if string(header[:3]) == "ID3" {
+		size := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+		offset = 10 + size
+		if header[5]&0x10 != 0 {
+			offset += 10
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return flac.NewSeek(io.NewSectionReader(f, offset, info.Size()-offset))
+}
+
+func (d *flacDecoder) Format() PCMFormat {
+	return d.format
+}
+
+func (d *flacDecoder) ReadFrames() ([][]int32, error) {
+	frame, err := d.stream.ParseNext()
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	for ch := range d.buf {
+		if ch < len(frame.Subframes) {
+			d.buf[ch] = frame.Subframes[ch].Samples
+		} else {
+			d.buf[ch] = d.buf[ch][:0]
+		}
+	}
+	return d.buf, nil
+}
+
+func (d *flacDecoder) Seek(sample uint64) (uint64, error) {
+	return d.stream.Seek(sample)
+}
+
+func (d *flacDecoder) Close() error {
+	return d.file.Close()
+}
+
+type wavDecoder struct {
+	file       *os.File
+	format     PCMFormat
+	formatTag  uint16
+	sourceBits int
+	dataStart  int64
+	dataSize   int64
+	pos        uint64
+	reader     *bufio.Reader
+	raw        []byte
+	buf        [][]int32
+}
+
+func openWAVDecoder(filePath string) (*wavDecoder, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	d, err := parseWAVHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to parse WAV: %w", err)
+	}
+
+	d.file = f
+	if _, err := d.Seek(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func parseWAVHeader(f *os.File) (*wavDecoder, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a RIFF/WAVE file")
+	}
+
+	d := &wavDecoder{}
+	var haveFormat bool
+	offset := int64(12)
+	chunk := make([]byte, 8)
+	for {
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return nil, fmt.Errorf("data chunk not found")
+		}
+		id := string(chunk[:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("invalid fmt chunk")
+			}
+			fmtData := make([]byte, size)
+			if _, err := f.ReadAt(fmtData, body); err != nil {
+				return nil, err
+			}
+			d.formatTag = binary.LittleEndian.Uint16(fmtData[0:2])
+			d.format.Channels = int(binary.LittleEndian.Uint16(fmtData[2:4]))
+			d.format.SampleRate = int(binary.LittleEndian.Uint32(fmtData[4:8]))
+			d.sourceBits = int(binary.LittleEndian.Uint16(fmtData[14:16]))
+			if d.formatTag == wavFormatExtended && size >= 26 {
+				d.formatTag = binary.LittleEndian.Uint16(fmtData[24:26])
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("data chunk before fmt chunk")
+			}
+			d.dataStart = body
+			d.dataSize = size
+			if info, err := f.Stat(); err == nil && (size == 0xFFFFFFFF || body+size > info.Size()) {
+				d.dataSize = info.Size() - body
+			}
+			return d, d.finishFormat()
+		}
+
+		offset = body + size + size%2
+	}
+}
+
+func (d *wavDecoder) finishFormat() error {
+	if d.format.Channels == 0 || d.format.SampleRate == 0 {
+		return fmt.Errorf("invalid WAV format")
+	}
+
+	switch d.formatTag {
+	case wavFormatPCM:
+		if d.sourceBits < 8 || d.sourceBits > 32 || d.sourceBits%8 != 0 {
+			return fmt.Errorf("unsupported PCM bit depth: %d", d.sourceBits)
+		}
+		d.format.BitsPerSample = d.sourceBits
+		d.format.Codec = fmt.Sprintf("pcm_s%dle", d.sourceBits)
+	case wavFormatFloat:
+		if d.sourceBits != 32 && d.sourceBits != 64 {
+			return fmt.Errorf("unsupported float bit depth: %d", d.sourceBits)
+		}
+		d.format.BitsPerSample = lossyDecodeBits
+		d.format.Codec = fmt.Sprintf("pcm_f%dle", d.sourceBits)
+	default:
+		return fmt.Errorf("unsupported WAV format tag: %#x", d.formatTag)
+	}
+
+	d.format.Lossless = true
+	frameBytes := int64(d.format.Channels * d.sourceBits / 8)
+	d.format.TotalSamples = uint64(d.dataSize / frameBytes)
+	d.raw = make([]byte, pcmChunkFrames*int(frameBytes))
+	d.buf = make([][]int32, d.format.Channels)
+	for ch := range d.buf {
+		d.buf[ch] = make([]int32, pcmChunkFrames)
+	}
+	return nil
+}
+
+func (d *wavDecoder) Format() PCMFormat {
+	return d.format
+}
+
+func (d *wavDecoder) Seek(sample uint64) (uint64, error) {
+	if sample > d.format.TotalSamples {
+		return 0, fmt.Errorf("unable to seek to sample number %d", sample)
+	}
+
+	frameBytes := int64(d.format.Channels * d.sourceBits / 8)
+	offset := d.dataStart + int64(sample)*frameBytes
+	if _, err := d.file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	d.pos = sample
+	d.reader = bufio.NewReader(io.LimitReader(d.file, d.dataStart+d.dataSize-offset))
+	return sample, nil
+}
+
+func (d *wavDecoder) ReadFrames() ([][]int32, error) {
+	n, err := io.ReadFull(d.reader, d.raw)
+	bytesPerSample := d.sourceBits / 8
+	frameBytes := d.format.Channels * bytesPerSample
+	frames := n / frameBytes
+	if frames == 0 {
+		if err == nil || err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+
+	for ch := range d.buf {
+		d.buf[ch] = d.buf[ch][:frames]
+	}
+
+	floatScale := float64(int64(1) << (lossyDecodeBits - 1))
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < d.format.Channels; ch++ {
+			b := d.raw[i*frameBytes+ch*bytesPerSample:]
+			var s int32
+			switch {
+			case d.formatTag == wavFormatFloat && d.sourceBits == 32:
+				s = floatToPCM(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), floatScale)
+			case d.formatTag == wavFormatFloat:
+				s = floatToPCM(math.Float64frombits(binary.LittleEndian.Uint64(b)), floatScale)
+			case bytesPerSample == 1:
+				s = int32(b[0]) - 128
+			case bytesPerSample == 2:
+				s = int32(int16(binary.LittleEndian.Uint16(b)))
+			case bytesPerSample == 3:
+				s = int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			default:
+				s = int32(binary.LittleEndian.Uint32(b))
+			}
+			d.buf[ch][i] = s
+		}
+	}
+
+	d.pos += uint64(frames)
+	return d.buf, nil
+}
+
+func floatToPCM(x, scale float64) int32 {
+	v := math.Round(x * scale)
+	if v > scale-1 {
+		v = scale - 1
+	} else if v < -scale {
+		v = -scale
+	}
+	return int32(v)
+}
+
+func (d *wavDecoder) Close() error {
+	return d.file.Close()
+}
+
+type ffprobeAudioStream struct {
+	CodecName        string `json:"codec_name"`
+	SampleRate       string `json:"sample_rate"`
+	Channels         int    `json:"channels"`
+	BitsPerRawSample string `json:"bits_per_raw_sample"`
+	BitsPerSample    int    `json:"bits_per_sample"`
+	Duration         string `json:"duration"`
+}
+
+type ffprobeAudioInfo struct {
+	Streams []ffprobeAudioStream `json:"streams"`
+	Format  struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func probeAudioStream(filePath string) (*ffprobeAudioInfo, error) {
+	ffprobePath, err := GetFFprobePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateExecutable(ffprobePath); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe executable: %w", err)
+	}
+
+	cmd := exec.Command(ffprobePath,
+		"-v", "quiet",
+		"-print_format", "json",
+		"-select_streams", "a:0",
+		"-show_streams",
+		"-show_format",
+		filePath,
+	)
+
+	setHideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var probe ffprobeAudioInfo
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, err
+	}
+	if len(probe.Streams) == 0 {
+		return nil, fmt.Errorf("no audio stream found")
+	}
+	if sampleRate, _ := strconv.Atoi(probe.Streams[0].SampleRate); sampleRate == 0 || probe.Streams[0].Channels == 0 {
+		return nil, fmt.Errorf("invalid audio stream info")
+	}
+
+	return &probe, nil
+}
+
+func (p *ffprobeAudioInfo) format() PCMFormat {
+	stream := p.Streams[0]
+	sampleRate, _ := strconv.Atoi(stream.SampleRate)
+
+	codec := stream.CodecName
+	lossless := losslessCodecs[codec] || strings.HasPrefix(codec, "pcm_")
+
+	bits, _ := strconv.Atoi(stream.BitsPerRawSample)
+	if bits == 0 {
+		bits = stream.BitsPerSample
+	}
+	if !lossless || bits <= 0 || bits > 32 {
+		bits = lossyDecodeBits
+	}
+
+	duration, err := strconv.ParseFloat(stream.Duration, 64)
+	if err != nil || duration <= 0 {
+		duration, _ = strconv.ParseFloat(p.Format.Duration, 64)
+	}
+
+	return PCMFormat{
+		SampleRate:    sampleRate,
+		Channels:      stream.Channels,
+		BitsPerSample: bits,
+		TotalSamples:  uint64(duration * float64(sampleRate)),
+		Codec:         codec,
+		Lossless:      lossless,
+	}
+}
+
+type ffmpegDecoder struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	reader *bufio.Reader
+	format PCMFormat
+	shift  uint
+	raw    []byte
+	buf    [][]int32
+	done   bool
+}
+
+func openFFmpegDecoder(filePath string) (*ffmpegDecoder, error) {
+	probe, err := probeAudioStream(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe audio: %w", err)
+	}
+	format := probe.format()
+
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	if err := ValidateExecutable(ffmpegPath); err != nil {
+		return nil, fmt.Errorf("invalid ffmpeg executable: %w", err)
+	}
+
+	cmd := exec.Command(ffmpegPath,
+		"-v", "error",
+		"-i", filePath,
+		"-map", "0:a:0",
+		"-f", "s32le",
+		"-acodec", "pcm_s32le",
+		"-",
+	)
+
+	setHideWindow(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	d := &ffmpegDecoder{
+		cmd:    cmd,
+		stdout: stdout,
+		reader: bufio.NewReader(stdout),
+		format: format,
+		shift:  uint(32 - format.BitsPerSample),
+		raw:    make([]byte, pcmChunkFrames*4*format.Channels),
+		buf:    make([][]int32, format.Channels),
+	}
+	for ch := range d.buf {
+		d.buf[ch] = make([]int32, pcmChunkFrames)
+	}
+	return d, nil
+}
+
+func (d *ffmpegDecoder) Format() PCMFormat {
+	return d.format
+}
+
+func (d *ffmpegDecoder) ReadFrames() ([][]int32, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	n, err := io.ReadFull(d.reader, d.raw)
+	frameBytes := 4 * d.format.Channels
+	frames := n / frameBytes
+	if err != nil {
+		d.done = true
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		if waitErr := d.wait(); waitErr != nil {
+			return nil, waitErr
+		}
+		if frames == 0 {
+			return nil, io.EOF
+		}
+	}
+
+	for ch := range d.buf {
+		d.buf[ch] = d.buf[ch][:frames]
+	}
+	for i := 0; i < frames; i++ {
+		for ch := 0; ch < d.format.Channels; ch++ {
+			s := int32(binary.LittleEndian.Uint32(d.raw[i*frameBytes+ch*4:]))
+			d.buf[ch][i] = s >> d.shift
+		}
+	}
+	return d.buf, nil
+}
+
+func (d *ffmpegDecoder) wait() error {
+	if d.cmd == nil {
+		return nil
+	}
+	cmd := d.cmd
+	d.cmd = nil
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed to decode audio: %w", err)
+	}
+	return nil
+}
+
+func (d *ffmpegDecoder) Close() error {
+	if d.cmd == nil {
+		return nil
+	}
+	d.stdout.Close()
+	if d.cmd.Process != nil {
+		d.cmd.Process.Kill()
+	}
+	d.cmd.Wait()
+	d.cmd = nil
+	return nil
+}
@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	drBlockSeconds = 3
+	drTopFraction  = 0.2
+)
+
+type DynamicRangeResult struct {
+	Value    int       `json:"value"`
+	Raw      float64   `json:"raw"`
+	Channels []float64 `json:"channels"`
+	Blocks   int       `json:"blocks"`
+}
+
+type AlbumDynamicRange struct {
+	Dir    string   `json:"dir"`
+	Value  int      `json:"value"`
+	Tracks []string `json:"tracks"`
+}
+
+type drMeter struct {
+	blockLen  int
+	fill      int
+	sumSq     []float64
+	peak      []float64
+	blockRMS  [][]float64
+	blockPeak [][]float64
+}
+
+func newDRMeter(sampleRate, channels int) *drMeter {
+	return &drMeter{
+		blockLen:  sampleRate * drBlockSeconds,
+		sumSq:     make([]float64, channels),
+		peak:      make([]float64, channels),
+		blockRMS:  make([][]float64, channels),
+		blockPeak: make([][]float64, channels),
+	}
+}
+
+func (m *drMeter) addFrames(samples [][]float64) {
+	if len(samples) == 0 {
+		return
+	}
+
+	n := len(samples[0])
+	for i := 0; i < n; i++ {
+		for ch := range m.sumSq {
+			if ch >= len(samples) {
+				break
+			}
+			x := samples[ch][i]
+			m.sumSq[ch] += x * x
+			if a := math.Abs(x); a > m.peak[ch] {
+				m.peak[ch] = a
+			}
+		}
+
+		m.fill++
+		if m.fill == m.blockLen {
+			m.finishBlock()
+		}
+	}
+}
+
+func (m *drMeter) finishBlock() {
+	if m.fill == 0 {
+		return
+	}
+	for ch := range m.sumSq {
+		m.blockRMS[ch] = append(m.blockRMS[ch], math.Sqrt(2*m.sumSq[ch]/float64(m.fill)))
+		m.blockPeak[ch] = append(m.blockPeak[ch], m.peak[ch])
+		m.sumSq[ch] = 0
+		m.peak[ch] = 0
+	}
+	m.fill = 0
+}
+
+func (m *drMeter) result() *DynamicRangeResult {
+	m.finishBlock()
+
+	if len(m.blockRMS) == 0 || len(m.blockRMS[0]) == 0 {
+		return nil
+	}
+
+	result := &DynamicRangeResult{Blocks: len(m.blockRMS[0])}
+	var sum float64
+	for ch := range m.blockRMS {
+		dr, ok := channelDynamicRange(m.blockRMS[ch], m.blockPeak[ch])
+		if !ok {
+			return nil
+		}
+		result.Channels = append(result.Channels, dr)
+		sum += dr
+	}
+
+	result.Raw = sum / float64(len(result.Channels))
+	result.Value = int(math.Round(result.Raw))
+	return result
+}
+
+func channelDynamicRange(rms, peaks []float64) (float64, bool) {
+	rmsSorted := append([]float64(nil), rms...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(rmsSorted)))
+
+	top := int(float64(len(rmsSorted)) * drTopFraction)
+	if top < 1 {
+		top = 1
+	}
+
+	var sumSq float64
+	for _, r := range rmsSorted[:top] {
+		sumSq += r * r
+	}
+	rmsTop := math.Sqrt(sumSq / float64(top))
+
+	peaksSorted := append([]float64(nil), peaks...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(peaksSorted)))
+	peak := peaksSorted[0]
+	if len(peaksSorted) > 1 {
+		peak = peaksSorted[1]
+	}
+
+	if rmsTop <= 0 || peak <= 0 {
+		return 0, false
+	}
+	return 20 * math.Log10(peak/rmsTop), true
+}
+
+func AlbumDynamicRanges(results []*AnalysisResult) []AlbumDynamicRange {
+	var dirs []string
+	values := make(map[string][]int)
+	tracks := make(map[string][]string)
+
+	for _, result := range results {
+		if result == nil || result.DR == nil {
+			continue
+		}
+		dir := filepath.Dir(result.FilePath)
+		if _, ok := values[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		values[dir] = append(values[dir], result.DR.Value)
+		tracks[dir] = append(tracks[dir], result.FilePath)
+	}
+
+	albums := make([]AlbumDynamicRange, 0, len(dirs))
+	for _, dir := range dirs {
+		var sum int
+		for _, v := range values[dir] {
+			sum += v
+		}
+		albums = append(albums, AlbumDynamicRange{
+			Dir:    dir,
+			Value:  int(math.Round(float64(sum) / float64(len(values[dir])))),
+			Tracks: tracks[dir],
+		})
+	}
+	return albums
+}
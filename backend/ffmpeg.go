@@ -3,14 +3,19 @@ package backend
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -492,184 +497,312 @@ func extractTarXz(tarXzPath, destDir string) error {
 }
 
 type ConvertAudioRequest struct {
-	InputFiles   []string `json:"input_files"`
-	OutputFormat string   `json:"output_format"`
-	Bitrate      string   `json:"bitrate"`
-	Codec        string   `json:"codec"`
+	InputFiles       []string `json:"input_files"`
+	OutputFormat     string   `json:"output_format"`
+	Bitrate          string   `json:"bitrate"`
+	Codec            string   `json:"codec"`
+	Preset           string   `json:"preset,omitempty"`
+	Mode             string   `json:"mode,omitempty"`
+	Quality          string   `json:"quality,omitempty"`
+	SampleRate       int      `json:"sample_rate,omitempty"`
+	BitDepth         int      `json:"bit_depth,omitempty"`
+	CompressionLevel *int     `json:"compression_level,omitempty"`
+	OutputDir        string   `json:"output_dir,omitempty"`
+	OutputTemplate   string   `json:"output_template,omitempty"`
+	Workers          int      `json:"workers,omitempty"`
+	OnlyIfAbove      bool     `json:"only_if_above,omitempty"`
 }
 
 type ConvertAudioResult struct {
 	InputFile  string `json:"input_file"`
 	OutputFile string `json:"output_file"`
 	Success    bool   `json:"success"`
+	Skipped    bool   `json:"skipped,omitempty"`
 	Error      string `json:"error,omitempty"`
 }
 
-func ConvertAudio(req ConvertAudioRequest) ([]ConvertAudioResult, error) {
+func getConvertFFmpegPath() (string, error) {
 	ffmpegPath, err := GetFFmpegPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ffmpeg path: %w", err)
+		return "", fmt.Errorf("failed to get ffmpeg path: %w", err)
 	}
 
 	if err := ValidateExecutable(ffmpegPath); err != nil {
-		return nil, fmt.Errorf("invalid ffmpeg executable: %w", err)
+		return "", fmt.Errorf("invalid ffmpeg executable: %w", err)
 	}
 
 	installed, err := IsFFmpegInstalled()
 	if err != nil || !installed {
-		return nil, fmt.Errorf("ffmpeg is not installed")
+		return "", fmt.Errorf("ffmpeg is not installed")
 	}
 
+	return ffmpegPath, nil
+}
+
+func ConvertAudio(ctx context.Context, req ConvertAudioRequest) ([]ConvertAudioResult, error) {
+	ffmpegPath, err := getConvertFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+
+	preset, err := resolveConvertPreset(req)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := req.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(req.InputFiles) {
+		workers = len(req.InputFiles)
+	}
+
+	batchID := time.Now().UnixNano()
 	results := make([]ConvertAudioResult, len(req.InputFiles))
+	itemIDs := make([]string, len(req.InputFiles))
+	for i, inputFile := range req.InputFiles {
+		results[i].InputFile = inputFile
+		itemIDs[i] = fmt.Sprintf("convert-%d-%d", batchID, i)
+		AddToConvertQueue(itemIDs[i], inputFile)
+	}
+
+	jobs := make(chan int)
 	var wg sync.WaitGroup
-	var mu sync.Mutex
 
-	for i, inputFile := range req.InputFiles {
+	for w := 0; w < workers; w++ {
 		wg.Add(1)
-		go func(idx int, inputFile string) {
+		go func() {
 			defer wg.Done()
+			for idx := range jobs {
+				inputFile := req.InputFiles[idx]
+				outputFile := ConvertOutputPath(inputFile, req.OutputDir, req.OutputTemplate, preset)
+				results[idx] = convertAudioFile(ctx, ffmpegPath, itemIDs[idx], inputFile, outputFile, preset)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range req.InputFiles {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
 
-			result := ConvertAudioResult{
-				InputFile: inputFile,
+	if ctx.Err() != nil {
+		for i := range results {
+			if !results[i].Success && results[i].Error == "" {
+				results[i].Error = "cancelled"
+				SkipConvertItem(itemIDs[i], "Cancelled")
 			}
+		}
+	}
 
-			inputExt := strings.ToLower(filepath.Ext(inputFile))
-			baseName := strings.TrimSuffix(filepath.Base(inputFile), inputExt)
-			inputDir := filepath.Dir(inputFile)
+	return results, nil
+}
 
-			outputFormatUpper := strings.ToUpper(req.OutputFormat)
-			outputDir := filepath.Join(inputDir, outputFormatUpper)
+func convertAudioFile(ctx context.Context, ffmpegPath, itemID, inputFile, outputFile string, preset ConvertPreset) ConvertAudioResult {
+	result := convertAudioFileWithProgress(ctx, ffmpegPath, itemID, inputFile, outputFile, preset)
+	switch {
+	case result.Skipped:
+		SkipConvertItem(itemID, "Already at or below target quality")
+	case result.Success:
+		CompleteConvertItem(itemID, result.OutputFile)
+	case ctx.Err() != nil:
+		result.Error = "cancelled"
+		SkipConvertItem(itemID, "Cancelled")
+	default:
+		FailConvertItem(itemID, result.Error)
+	}
+	return result
+}
 
-			if err := os.MkdirAll(outputDir, 0755); err != nil {
-				result.Error = fmt.Sprintf("failed to create output directory: %v", err)
-				result.Success = false
-				mu.Lock()
-				results[idx] = result
-				mu.Unlock()
-				return
-			}
+func convertAudioFileWithProgress(ctx context.Context, ffmpegPath, itemID, inputFile, outputFile string, preset ConvertPreset) ConvertAudioResult {
+	result := ConvertAudioResult{
+		InputFile: inputFile,
+	}
 
-			outputExt := "." + strings.ToLower(req.OutputFormat)
-			outputFile := filepath.Join(outputDir, baseName+outputExt)
+	if ctx.Err() != nil {
+		return result
+	}
 
-			if inputExt == outputExt {
-				result.Error = "Input and output formats are the same"
-				result.Success = false
-				mu.Lock()
-				results[idx] = result
-				mu.Unlock()
-				return
-			}
+	sourceBits := 0
+	sourceRate := 0
+	duration := 0.0
+	if format, err := ProbePCMFormat(inputFile); err == nil {
+		sourceRate = format.SampleRate
+		if format.Lossless {
+			sourceBits = format.BitsPerSample
+		}
+		if format.SampleRate > 0 && format.TotalSamples > 0 {
+			duration = float64(format.TotalSamples) / float64(format.SampleRate)
+		}
+	}
+	if duration <= 0 {
+		duration, _ = GetAudioDuration(inputFile)
+	}
 
-			result.OutputFile = outputFile
+	preset, needed := preset.forSource(sourceRate, sourceBits)
+	if !needed {
+		fmt.Printf("[FFmpeg] Skipping %s: already at or below target quality\n", inputFile)
+		result.Success = true
+		result.Skipped = true
+		return result
+	}
 
-			var coverArtPath string
-			var lyrics string
-			var inputMetadata Metadata
+	if absIn, err := filepath.Abs(inputFile); err == nil {
+		if absOut, err := filepath.Abs(outputFile); err == nil && strings.EqualFold(absIn, absOut) {
+			result.Error = "Output file would overwrite the input file"
+			return result
+		}
+	}
 
-			inputMetadata, err = ExtractFullMetadataFromFile(inputFile)
-			if err != nil {
-				fmt.Printf("[FFmpeg] Warning: Failed to extract metadata from %s: %v\n", inputFile, err)
-			}
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		result.Error = fmt.Sprintf("failed to create output directory: %v", err)
+		return result
+	}
 
-			coverArtPath, _ = ExtractCoverArt(inputFile)
-			lyrics, err = ExtractLyrics(inputFile)
-			if err != nil {
-				fmt.Printf("[FFmpeg] Warning: Failed to extract lyrics from %s: %v\n", inputFile, err)
-			} else if lyrics != "" {
-				fmt.Printf("[FFmpeg] Lyrics extracted from %s: %d characters\n", inputFile, len(lyrics))
-			} else {
-				fmt.Printf("[FFmpeg] No lyrics found in %s\n", inputFile)
-			}
+	result.OutputFile = outputFile
 
-			inputMetadata.Lyrics = lyrics
+	var coverArtPath string
+	var lyrics string
+	var inputMetadata Metadata
 
-			args := []string{
-				"-i", inputFile,
-				"-y",
-			}
+	inputMetadata, err := ExtractFullMetadataFromFile(inputFile)
+	if err != nil {
+		fmt.Printf("[FFmpeg] Warning: Failed to extract metadata from %s: %v\n", inputFile, err)
+	}
 
-			switch req.OutputFormat {
-			case "mp3":
-				args = append(args,
-					"-codec:a", "libmp3lame",
-					"-b:a", req.Bitrate,
-					"-map", "0:a",
-					"-id3v2_version", "3",
-				)
-			case "m4a":
-
-				codec := req.Codec
-				if codec == "" {
-					codec = "aac"
-				}
+	coverArtPath, _ = ExtractCoverArt(inputFile)
+	if coverArtPath != "" {
+		defer os.Remove(coverArtPath)
+	}
+	lyrics, err = ExtractLyrics(inputFile)
+	if err != nil {
+		fmt.Printf("[FFmpeg] Warning: Failed to extract lyrics from %s: %v\n", inputFile, err)
+	} else if lyrics != "" {
+		fmt.Printf("[FFmpeg] Lyrics extracted from %s: %d characters\n", inputFile, len(lyrics))
+	} else {
+		fmt.Printf("[FFmpeg] No lyrics found in %s\n", inputFile)
+	}
 
-				if codec == "alac" {
+	inputMetadata.Lyrics = lyrics
 
-					args = append(args,
-						"-codec:a", "alac",
-						"-map", "0:a",
-					)
-				} else {
+	StartConvertItem(itemID, outputFile, duration)
 
-					args = append(args,
-						"-codec:a", "aac",
-						"-b:a", req.Bitrate,
-						"-map", "0:a",
-					)
-				}
+	args := []string{
+		"-hide_banner",
+		"-nostats",
+		"-progress", "pipe:1",
+		"-i", inputFile,
+	}
+
+	if preset.tagsWithFFmpeg() {
+		metadataPath, err := writeFFMetadataFile(inputMetadata, coverArtPath, preset)
+		if err != nil {
+			fmt.Printf("[FFmpeg] Warning: Failed to prepare metadata: %v\n", err)
+			args = append(args, "-map", "0:a", "-map_metadata", "0")
+		} else {
+			defer os.Remove(metadataPath)
+			args = append(args, "-f", "ffmetadata", "-i", metadataPath, "-map", "0:a", "-map_metadata", "1")
+			if preset.Format == "aiff" && coverArtPath != "" {
+				args = append(args, "-i", coverArtPath, "-map", "2:v", "-c:v", "copy", "-disposition:v", "attached_pic")
 			}
+		}
+	} else {
+		args = append(args, "-map", "0:a")
+	}
 
-			args = append(args, outputFile)
+	args = append(args, "-y")
+	args = append(args, preset.codecArgs(sourceRate, sourceBits, ffmpegHasSoxr(ffmpegPath))...)
+	args = append(args, outputFile)
 
-			fmt.Printf("[FFmpeg] Converting: %s -> %s\n", inputFile, outputFile)
+	fmt.Printf("[FFmpeg] Converting: %s -> %s\n", inputFile, outputFile)
 
-			cmd := exec.Command(ffmpegPath, args...)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 
-			setHideWindow(cmd)
-			output, err := cmd.CombinedOutput()
-			if err != nil {
-				result.Error = fmt.Sprintf("conversion failed: %s - %s", err.Error(), string(output))
-				result.Success = false
-				mu.Lock()
-				results[idx] = result
-				mu.Unlock()
+	setHideWindow(cmd)
+	cmd.WaitDelay = 5 * time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to start ffmpeg: %v", err)
+		return result
+	}
+	if err := cmd.Start(); err != nil {
+		result.Error = fmt.Sprintf("failed to start ffmpeg: %v", err)
+		return result
+	}
 
-				if coverArtPath != "" {
-					os.Remove(coverArtPath)
-				}
-				return
-			}
+	parseFFmpegProgress(stdout, duration, func(progress, speed float64) {
+		UpdateConvertProgress(itemID, progress, speed)
+	})
 
-			if err := EmbedMetadataToConvertedFile(outputFile, inputMetadata, coverArtPath); err != nil {
-				fmt.Printf("[FFmpeg] Warning: Failed to embed metadata: %v\n", err)
+	if err := cmd.Wait(); err != nil {
+		os.Remove(outputFile)
+		if ctx.Err() != nil {
+			return result
+		}
+		result.Error = fmt.Sprintf("conversion failed: %s - %s", err.Error(), strings.TrimSpace(stderr.String()))
+		return result
+	}
+
+	if !preset.tagsWithFFmpeg() {
+		if err := MergeMetadataToFile(outputFile, inputMetadata, coverArtPath, CoverImageOptions{}); err != nil {
+			fmt.Printf("[FFmpeg] Warning: Failed to embed metadata: %v\n", err)
+		} else {
+			fmt.Printf("[FFmpeg] Metadata embedded successfully\n")
+		}
+
+		if lyrics != "" {
+			if err := EmbedLyricsOnlyUniversal(outputFile, lyrics); err != nil {
+				fmt.Printf("[FFmpeg] Warning: Failed to embed lyrics: %v\n", err)
 			} else {
-				fmt.Printf("[FFmpeg] Metadata embedded successfully\n")
+				fmt.Printf("[FFmpeg] Lyrics embedded successfully\n")
 			}
+		}
+	}
 
-			if lyrics != "" {
-				if err := EmbedLyricsOnlyUniversal(outputFile, lyrics); err != nil {
-					fmt.Printf("[FFmpeg] Warning: Failed to embed lyrics: %v\n", err)
-				} else {
-					fmt.Printf("[FFmpeg] Lyrics embedded successfully\n")
-				}
-			}
+	result.Success = true
+	fmt.Printf("[FFmpeg] Successfully converted: %s\n", outputFile)
 
-			if coverArtPath != "" {
-				os.Remove(coverArtPath)
-			}
+	return result
+}
 
-			result.Success = true
-			fmt.Printf("[FFmpeg] Successfully converted: %s\n", outputFile)
+func parseFFmpegProgress(r io.Reader, duration float64, report func(progress, speed float64)) {
+	scanner := bufio.NewScanner(r)
+	var outTime, speed float64
 
-			mu.Lock()
-			results[idx] = result
-			mu.Unlock()
-		}(i, inputFile)
-	}
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
 
-	wg.Wait()
-	return results, nil
+		switch key {
+		case "out_time_us", "out_time_ms":
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				outTime = float64(us) / 1e6
+			}
+		case "speed":
+			if x, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "x"), 64); err == nil {
+				speed = x
+			}
+		case "progress":
+			progress := 0.0
+			if value == "end" {
+				progress = 100
+			} else if duration > 0 {
+				progress = math.Min(99.9, outTime/duration*100)
+			}
+			report(progress, speed)
+		}
+	}
 }
 
 type AudioFileInfo struct {
@@ -33,18 +33,21 @@ type AudioMetadata struct {
 }
 
 type RenamePreview struct {
-	OldPath  string        `json:"old_path"`
-	OldName  string        `json:"old_name"`
-	NewName  string        `json:"new_name"`
-	NewPath  string        `json:"new_path"`
-	Error    string        `json:"error,omitempty"`
-	Metadata AudioMetadata `json:"metadata"`
+	OldPath   string        `json:"old_path"`
+	OldName   string        `json:"old_name"`
+	NewName   string        `json:"new_name"`
+	NewPath   string        `json:"new_path"`
+	Error     string        `json:"error,omitempty"`
+	Collision bool          `json:"collision,omitempty"`
+	Skipped   bool          `json:"skipped,omitempty"`
+	Metadata  AudioMetadata `json:"metadata"`
 }
 
 type RenameResult struct {
 	OldPath string `json:"old_path"`
 	NewPath string `json:"new_path"`
 	Success bool   `json:"success"`
+	Skipped bool   `json:"skipped,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
 
@@ -315,59 +318,7 @@ func readM4aMetadata(filePath string) (*AudioMetadata, error) {
 	return metadata, nil
 }
 
-func GenerateFilename(metadata *AudioMetadata, format string, ext string) string {
-	if metadata == nil {
-		return ""
-	}
-
-	result := format
-
-	year := metadata.Year
-	if len(year) >= 4 {
-		year = year[:4]
-	}
-
-	result = strings.ReplaceAll(result, "{title}", sanitizeFilenameForRename(metadata.Title))
-	result = strings.ReplaceAll(result, "{artist}", sanitizeFilenameForRename(metadata.Artist))
-	result = strings.ReplaceAll(result, "{album}", sanitizeFilenameForRename(metadata.Album))
-	result = strings.ReplaceAll(result, "{album_artist}", sanitizeFilenameForRename(metadata.AlbumArtist))
-	result = strings.ReplaceAll(result, "{year}", sanitizeFilenameForRename(year))
-
-	if metadata.TrackNumber > 0 {
-		result = strings.ReplaceAll(result, "{track}", fmt.Sprintf("%02d", metadata.TrackNumber))
-	} else {
-		result = strings.ReplaceAll(result, "{track}", "")
-	}
-
-	if metadata.DiscNumber > 0 {
-		result = strings.ReplaceAll(result, "{disc}", fmt.Sprintf("%d", metadata.DiscNumber))
-	} else {
-		result = strings.ReplaceAll(result, "{disc}", "")
-	}
-
-	result = strings.TrimSpace(result)
-	result = strings.Join(strings.Fields(result), " ")
-
-	result = strings.Trim(result, " -._")
-
-	if result == "" {
-		return ""
-	}
-
-	return result + ext
-}
-
-func sanitizeFilenameForRename(name string) string {
-
-	invalid := []string{"<", ">", ":", "\"", "/", "\\", "|", "?", "*"}
-	result := name
-	for _, char := range invalid {
-		result = strings.ReplaceAll(result, char, "")
-	}
-	return strings.TrimSpace(result)
-}
-
-func PreviewRename(files []string, format string) []RenamePreview {
+func PreviewRename(files []string, format string, strategy RenameCollisionStrategy) []RenamePreview {
 	var previews []RenamePreview
 
 	for _, filePath := range files {
@@ -385,8 +336,7 @@ func PreviewRename(files []string, format string) []RenamePreview {
 
 		preview.Metadata = *metadata
 
-		ext := filepath.Ext(filePath)
-		newName := GenerateFilename(metadata, format, ext)
+		newName := RenderTemplate(format, TemplateDataFromMetadata(metadata))
 
 		if newName == "" {
 			preview.Error = "Could not generate filename (missing metadata)"
@@ -394,12 +344,15 @@ func PreviewRename(files []string, format string) []RenamePreview {
 			continue
 		}
 
+		newName = filepath.FromSlash(newName) + filepath.Ext(filePath)
 		preview.NewName = newName
 		preview.NewPath = filepath.Join(filepath.Dir(filePath), newName)
 
 		previews = append(previews, preview)
 	}
 
+	resolveRenameCollisions(previews, strategy)
+
 	return previews
 }
 
@@ -414,54 +367,55 @@ func GetFileSizes(files []string) map[string]int64 {
 	return result
 }
 
-func RenameFiles(files []string, format string) []RenameResult {
+func RenameFiles(files []string, format string, strategy RenameCollisionStrategy) RenameBatchResult {
 	var results []RenameResult
+	var moves []RenameOperation
+	var moveResults []int
 
-	for _, filePath := range files {
+	for _, preview := range PreviewRename(files, format, strategy) {
 		result := RenameResult{
-			OldPath: filePath,
+			OldPath: preview.OldPath,
+			NewPath: preview.NewPath,
+			Skipped: preview.Skipped,
 		}
 
-		metadata, err := ReadAudioMetadata(filePath)
-		if err != nil {
-			result.Error = err.Error()
+		if preview.Error != "" {
+			result.Error = preview.Error
 			result.Success = false
 			results = append(results, result)
 			continue
 		}
 
-		ext := filepath.Ext(filePath)
-		newName := GenerateFilename(metadata, format, ext)
-
-		if newName == "" {
-			result.Error = "Could not generate filename (missing metadata)"
-			result.Success = false
+		if preview.NewPath == preview.OldPath {
+			result.Success = true
 			results = append(results, result)
 			continue
 		}
 
-		newPath := filepath.Join(filepath.Dir(filePath), newName)
-		result.NewPath = newPath
-
-		if newPath != filePath {
-			if _, err := os.Stat(newPath); err == nil {
-				result.Error = "File already exists"
-				result.Success = false
-				results = append(results, result)
-				continue
-			}
-		}
+		moves = append(moves, RenameOperation{OldPath: preview.OldPath, NewPath: preview.NewPath})
+		moveResults = append(moveResults, len(results))
+		results = append(results, result)
+	}
 
-		if err := os.Rename(filePath, newPath); err != nil {
+	var ops []RenameOperation
+	for i, err := range moveFilesInOrder(moves) {
+		result := &results[moveResults[i]]
+		if err != nil {
 			result.Error = err.Error()
 			result.Success = false
-			results = append(results, result)
 			continue
 		}
-
+		ops = append(ops, moves[i])
 		result.Success = true
-		results = append(results, result)
 	}
 
-	return results
+	batchID, err := RecordRenameBatch(ops)
+	if err != nil {
+		fmt.Printf("Failed to record rename batch: %v\n", err)
+	}
+	if err := UpdateHistoryPaths(ops); err != nil {
+		fmt.Printf("Failed to update history paths: %v\n", err)
+	}
+
+	return RenameBatchResult{BatchID: batchID, Results: results}
 }
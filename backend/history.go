@@ -20,9 +20,21 @@ type HistoryItem struct {
 	DurationStr string `json:"duration_str"`
 	CoverURL    string `json:"cover_url"`
 	Quality     string `json:"quality"`
+	Codec       string `json:"codec,omitempty"`
+	Lossy       bool   `json:"lossy,omitempty"`
 	Format      string `json:"format"`
 	Path        string `json:"path"`
 	Timestamp   int64  `json:"timestamp"`
+	Verdict     string `json:"verdict,omitempty"`
+	VerdictNote string `json:"verdict_note,omitempty"`
+	Suspicious  bool   `json:"suspicious,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	ISRC        string `json:"isrc,omitempty"`
+	FileSize    int64  `json:"file_size,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Missing     bool   `json:"missing,omitempty"`
 }
 
 var historyDB *bolt.DB
@@ -49,8 +61,10 @@ func InitHistoryDB(appName string) error {
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(historyBucket))
-		return err
+		if _, err := tx.CreateBucketIfNotExists([]byte(historyBucket)); err != nil {
+			return err
+		}
+		return ensureHistoryIndexes(tx)
 	})
 
 	if err != nil {
@@ -97,16 +111,27 @@ func AddHistoryItem(item HistoryItem, appName string) error {
 				toDelete = 1
 			}
 
-			count := 0
-			for k, _ := c.First(); k != nil && count < toDelete; k, _ = c.Next() {
+			var keysToDelete [][]byte
+			for k, v := c.First(); k != nil && len(keysToDelete) < toDelete; k, v = c.Next() {
+				var old HistoryItem
+				if err := json.Unmarshal(v, &old); err == nil {
+					if err := unindexHistoryItem(tx, old); err != nil {
+						return err
+					}
+				}
+				keysToDelete = append(keysToDelete, append([]byte(nil), k...))
+			}
+			for _, k := range keysToDelete {
 				if err := b.Delete(k); err != nil {
 					return err
 				}
-				count++
 			}
 		}
 
-		return b.Put([]byte(item.ID), buf)
+		if err := b.Put([]byte(item.ID), buf); err != nil {
+			return err
+		}
+		return indexHistoryItem(tx, item)
 	})
 }
 
@@ -147,6 +172,11 @@ func ClearHistory(appName string) error {
 		}
 	}
 	return historyDB.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(historyIndexBucket)) != nil {
+			if err := tx.DeleteBucket([]byte(historyIndexBucket)); err != nil {
+				return err
+			}
+		}
 		return tx.DeleteBucket([]byte(historyBucket))
 	})
 }
@@ -306,6 +336,14 @@ func DeleteHistoryItem(id string, appName string) error {
 			return nil
 		}
 
+		if v := b.Get([]byte(id)); v != nil {
+			var item HistoryItem
+			if err := json.Unmarshal(v, &item); err == nil {
+				if err := unindexHistoryItem(tx, item); err != nil {
+					return err
+				}
+			}
+		}
 		return b.Delete([]byte(id))
 	})
 }
@@ -0,0 +1,538 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	historyIndexBucket  = "DownloadHistoryIndex"
+	historyIndexVersion = "2"
+	historyIndexMetaKey = "_version"
+
+	HistoryStatusCompleted = "completed"
+	HistoryStatusFailed    = "failed"
+
+	defaultHistoryPageSize = 50
+)
+
+var historyQualityPattern = regexp.MustCompile(`(?i)^(\d+)-bit/([\d.]+)kHz`)
+
+type HistoryQuery struct {
+	Artist   string `json:"artist,omitempty"`
+	Album    string `json:"album,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Quality  string `json:"quality,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Missing  bool   `json:"missing,omitempty"`
+	From     int64  `json:"from,omitempty"`
+	To       int64  `json:"to,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Sort     string `json:"sort,omitempty"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+}
+
+type HistoryQueryResult struct {
+	Items  []HistoryItem `json:"items"`
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+}
+
+type HistoryMonthStat struct {
+	Month  string  `json:"month"`
+	Tracks int     `json:"tracks"`
+	Bytes  int64   `json:"bytes"`
+	GB     float64 `json:"gb"`
+}
+
+type HistoryProviderStat struct {
+	Provider string `json:"provider"`
+	Tracks   int    `json:"tracks"`
+	Failed   int    `json:"failed"`
+	Bytes    int64  `json:"bytes"`
+}
+
+type HistoryStats struct {
+	Total       int                   `json:"total"`
+	Completed   int                   `json:"completed"`
+	Failed      int                   `json:"failed"`
+	FailureRate float64               `json:"failure_rate"`
+	TotalBytes  int64                 `json:"total_bytes"`
+	HiRes       int                   `json:"hi_res"`
+	HiResShare  float64               `json:"hi_res_share"`
+	Months      []HistoryMonthStat    `json:"months"`
+	Providers   []HistoryProviderStat `json:"providers"`
+}
+
+func normalizeHistoryValue(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+func historyFormat(item HistoryItem) string {
+	switch format := strings.ToUpper(strings.TrimSpace(item.Format)); format {
+	case "HI_RES_LOSSLESS", "LOSSLESS", "6", "7", "27":
+		return "FLAC"
+	default:
+		return format
+	}
+}
+
+func historyQualityClass(item HistoryItem) string {
+	if item.Lossy || strings.HasPrefix(strings.ToLower(item.Quality), "lossy") {
+		return "lossy"
+	}
+	match := historyQualityPattern.FindStringSubmatch(item.Quality)
+	if match == nil {
+		return "unknown"
+	}
+	bits, _ := strconv.Atoi(match[1])
+	khz, _ := strconv.ParseFloat(match[2], 64)
+	if bits >= 24 || khz > 48 {
+		return "hi_res"
+	}
+	return "cd"
+}
+
+func historyStatus(item HistoryItem) string {
+	if item.Status == HistoryStatusFailed {
+		return HistoryStatusFailed
+	}
+	return HistoryStatusCompleted
+}
+
+func historyProvider(item HistoryItem) string {
+	if item.Provider == "" {
+		return "unknown"
+	}
+	return strings.ToLower(item.Provider)
+}
+
+func historyPathKey(path string) string {
+	if path == "" {
+		return ""
+	}
+	return normalizeHistoryValue(filepath.Clean(path))
+}
+
+func historyPresence(item HistoryItem) string {
+	if item.Missing {
+		return "missing"
+	}
+	return "present"
+}
+
+func historyIndexValues(item HistoryItem) map[string][]string {
+	var artists []string
+	for _, artist := range strings.FieldsFunc(item.Artists, func(r rune) bool { return r == ',' || r == ';' }) {
+		if v := normalizeHistoryValue(artist); v != "" {
+			artists = append(artists, v)
+		}
+	}
+	return map[string][]string{
+		"artist":   artists,
+		"album":    {normalizeHistoryValue(item.Album)},
+		"format":   {normalizeHistoryValue(historyFormat(item))},
+		"quality":  {historyQualityClass(item)},
+		"provider": {historyProvider(item)},
+		"status":   {historyStatus(item)},
+		"path":     {historyPathKey(item.Path)},
+		"missing":  {historyPresence(item)},
+	}
+}
+
+func historyIndexKey(value, id string) []byte {
+	return []byte(value + "\x00" + id)
+}
+
+func indexHistoryItem(tx *bolt.Tx, item HistoryItem) error {
+	root, err := tx.CreateBucketIfNotExists([]byte(historyIndexBucket))
+	if err != nil {
+		return err
+	}
+	if root.Get([]byte(historyIndexMetaKey)) == nil {
+		if err := root.Put([]byte(historyIndexMetaKey), []byte(historyIndexVersion)); err != nil {
+			return err
+		}
+	}
+	for name, values := range historyIndexValues(item) {
+		idx, err := root.CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			if err := idx.Put(historyIndexKey(value, item.ID), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func unindexHistoryItem(tx *bolt.Tx, item HistoryItem) error {
+	root := tx.Bucket([]byte(historyIndexBucket))
+	if root == nil {
+		return nil
+	}
+	for name, values := range historyIndexValues(item) {
+		idx := root.Bucket([]byte(name))
+		if idx == nil {
+			continue
+		}
+		for _, value := range values {
+			if err := idx.Delete(historyIndexKey(value, item.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func putHistoryItem(tx *bolt.Tx, old, item HistoryItem) error {
+	b := tx.Bucket([]byte(historyBucket))
+	if b == nil {
+		return nil
+	}
+	buf, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if err := unindexHistoryItem(tx, old); err != nil {
+		return err
+	}
+	if err := b.Put([]byte(item.ID), buf); err != nil {
+		return err
+	}
+	return indexHistoryItem(tx, item)
+}
+
+func ensureHistoryIndexes(tx *bolt.Tx) error {
+	if root := tx.Bucket([]byte(historyIndexBucket)); root != nil {
+		if string(root.Get([]byte(historyIndexMetaKey))) == historyIndexVersion {
+			return nil
+		}
+		if err := tx.DeleteBucket([]byte(historyIndexBucket)); err != nil {
+			return err
+		}
+	}
+
+	root, err := tx.CreateBucket([]byte(historyIndexBucket))
+	if err != nil {
+		return err
+	}
+	if err := root.Put([]byte(historyIndexMetaKey), []byte(historyIndexVersion)); err != nil {
+		return err
+	}
+
+	b := tx.Bucket([]byte(historyBucket))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		var item HistoryItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			return nil
+		}
+		return indexHistoryItem(tx, item)
+	})
+}
+
+func lookupHistoryIndex(tx *bolt.Tx, name, value string) map[string]bool {
+	ids := make(map[string]bool)
+	root := tx.Bucket([]byte(historyIndexBucket))
+	if root == nil {
+		return ids
+	}
+	idx := root.Bucket([]byte(name))
+	if idx == nil {
+		return ids
+	}
+	prefix := []byte(value + "\x00")
+	c := idx.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		ids[string(k[len(prefix):])] = true
+	}
+	return ids
+}
+
+func (q HistoryQuery) indexFilters() map[string]string {
+	filters := make(map[string]string)
+	for name, value := range map[string]string{
+		"artist":   q.Artist,
+		"album":    q.Album,
+		"format":   q.Format,
+		"quality":  q.Quality,
+		"provider": q.Provider,
+		"status":   q.Status,
+	} {
+		if v := normalizeHistoryValue(value); v != "" {
+			filters[name] = v
+		}
+	}
+	if q.Missing {
+		filters["missing"] = "missing"
+	}
+	return filters
+}
+
+func (q HistoryQuery) matchesText(item HistoryItem) bool {
+	text := normalizeHistoryValue(q.Text)
+	if text == "" {
+		return true
+	}
+	for _, field := range []string{item.Title, item.Artists, item.Album, item.AlbumArtist, item.ISRC, item.Path} {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q HistoryQuery) matchesTime(item HistoryItem) bool {
+	if q.From > 0 && item.Timestamp < q.From {
+		return false
+	}
+	if q.To > 0 && item.Timestamp > q.To {
+		return false
+	}
+	return true
+}
+
+func (q HistoryQuery) isDateSort() bool {
+	return q.Sort == "" || q.Sort == "default" || q.Sort == "date_desc" || q.Sort == "date_asc"
+}
+
+func matchingHistoryIDs(tx *bolt.Tx, filters map[string]string) map[string]bool {
+	var ids map[string]bool
+	for name, value := range filters {
+		found := lookupHistoryIndex(tx, name, value)
+		if ids == nil {
+			ids = found
+			continue
+		}
+		for id := range ids {
+			if !found[id] {
+				delete(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func collectHistoryItems(tx *bolt.Tx, q HistoryQuery) []HistoryItem {
+	b := tx.Bucket([]byte(historyBucket))
+	if b == nil {
+		return nil
+	}
+
+	var items []HistoryItem
+	add := func(v []byte) {
+		var item HistoryItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			return
+		}
+		if q.matchesTime(item) && q.matchesText(item) {
+			items = append(items, item)
+		}
+	}
+
+	if filters := q.indexFilters(); len(filters) > 0 {
+		for id := range matchingHistoryIDs(tx, filters) {
+			if v := b.Get([]byte(id)); v != nil {
+				add(v)
+			}
+		}
+		return items
+	}
+
+	b.ForEach(func(k, v []byte) error {
+		add(v)
+		return nil
+	})
+	return items
+}
+
+func sortHistoryItems(items []HistoryItem, sortBy string) {
+	less := func(i, j int) bool { return items[i].ID > items[j].ID }
+	switch sortBy {
+	case "date_asc":
+		less = func(i, j int) bool { return items[i].ID < items[j].ID }
+	case "title_asc":
+		less = func(i, j int) bool { return strings.ToLower(items[i].Title) < strings.ToLower(items[j].Title) }
+	case "title_desc":
+		less = func(i, j int) bool { return strings.ToLower(items[i].Title) > strings.ToLower(items[j].Title) }
+	case "artist_asc":
+		less = func(i, j int) bool { return strings.ToLower(items[i].Artists) < strings.ToLower(items[j].Artists) }
+	case "artist_desc":
+		less = func(i, j int) bool { return strings.ToLower(items[i].Artists) > strings.ToLower(items[j].Artists) }
+	case "duration_asc":
+		less = func(i, j int) bool {
+			return parseHistoryDuration(items[i].DurationStr) < parseHistoryDuration(items[j].DurationStr)
+		}
+	case "duration_desc":
+		less = func(i, j int) bool {
+			return parseHistoryDuration(items[i].DurationStr) > parseHistoryDuration(items[j].DurationStr)
+		}
+	}
+	sort.SliceStable(items, less)
+}
+
+func parseHistoryDuration(value string) int {
+	total := 0
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return total
+}
+
+func scanHistoryPage(tx *bolt.Tx, q HistoryQuery) HistoryQueryResult {
+	result := HistoryQueryResult{Offset: q.Offset, Limit: q.Limit, Items: []HistoryItem{}}
+	b := tx.Bucket([]byte(historyBucket))
+	if b == nil {
+		return result
+	}
+
+	c := b.Cursor()
+	first, next := c.Last, c.Prev
+	if q.Sort == "date_asc" {
+		first, next = c.First, c.Next
+	}
+
+	for k, v := first(); k != nil; k, v = next() {
+		if result.Total >= q.Offset && len(result.Items) < q.Limit {
+			var item HistoryItem
+			if err := json.Unmarshal(v, &item); err == nil {
+				result.Items = append(result.Items, item)
+			}
+		}
+		result.Total++
+	}
+	return result
+}
+
+func QueryHistory(q HistoryQuery, appName string) (HistoryQueryResult, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return HistoryQueryResult{}, err
+		}
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultHistoryPageSize
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	var result HistoryQueryResult
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		if q.isDateSort() && len(q.indexFilters()) == 0 && q.From == 0 && q.To == 0 && normalizeHistoryValue(q.Text) == "" {
+			result = scanHistoryPage(tx, q)
+			return nil
+		}
+
+		items := collectHistoryItems(tx, q)
+		sortHistoryItems(items, q.Sort)
+
+		result = HistoryQueryResult{Total: len(items), Offset: q.Offset, Limit: q.Limit, Items: []HistoryItem{}}
+		if q.Offset < len(items) {
+			result.Items = items[q.Offset:min(q.Offset+q.Limit, len(items))]
+		}
+		return nil
+	})
+	return result, err
+}
+
+func GetHistoryStats(q HistoryQuery, appName string) (HistoryStats, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return HistoryStats{}, err
+		}
+	}
+
+	var items []HistoryItem
+	if err := historyDB.View(func(tx *bolt.Tx) error {
+		items = collectHistoryItems(tx, q)
+		return nil
+	}); err != nil {
+		return HistoryStats{}, err
+	}
+
+	stats := HistoryStats{Total: len(items), Months: []HistoryMonthStat{}, Providers: []HistoryProviderStat{}}
+	months := make(map[string]*HistoryMonthStat)
+	providers := make(map[string]*HistoryProviderStat)
+	knownQuality := 0
+
+	for _, item := range items {
+		name := historyProvider(item)
+		provider, ok := providers[name]
+		if !ok {
+			provider = &HistoryProviderStat{Provider: name}
+			providers[name] = provider
+		}
+
+		if historyStatus(item) == HistoryStatusFailed {
+			stats.Failed++
+			provider.Failed++
+			continue
+		}
+
+		stats.Completed++
+		stats.TotalBytes += item.FileSize
+		provider.Tracks++
+		provider.Bytes += item.FileSize
+
+		month := time.Unix(item.Timestamp, 0).Format("2006-01")
+		m, ok := months[month]
+		if !ok {
+			m = &HistoryMonthStat{Month: month}
+			months[month] = m
+		}
+		m.Tracks++
+		m.Bytes += item.FileSize
+
+		switch historyQualityClass(item) {
+		case "hi_res":
+			stats.HiRes++
+			knownQuality++
+		case "cd", "lossy":
+			knownQuality++
+		}
+	}
+
+	if stats.Total > 0 {
+		stats.FailureRate = float64(stats.Failed) / float64(stats.Total)
+	}
+	if knownQuality > 0 {
+		stats.HiResShare = float64(stats.HiRes) / float64(knownQuality)
+	}
+
+	for _, m := range months {
+		m.GB = float64(m.Bytes) / (1024 * 1024 * 1024)
+		stats.Months = append(stats.Months, *m)
+	}
+	sort.Slice(stats.Months, func(i, j int) bool { return stats.Months[i].Month < stats.Months[j].Month })
+
+	for _, p := range providers {
+		stats.Providers = append(stats.Providers, *p)
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool { return stats.Providers[i].Tracks > stats.Providers[j].Tracks })
+
+	return stats, nil
+}
@@ -0,0 +1,396 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+	bolt "go.etcd.io/bbolt"
+)
+
+var spotifyTrackIDPattern = regexp.MustCompile(`(?:open\.spotify\.com/(?:intl-[a-z]+/)?track/|spotify:track:)([A-Za-z0-9]{22})`)
+
+type HistoryRelocation struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Artists   string `json:"artists"`
+	OldPath   string `json:"old_path"`
+	NewPath   string `json:"new_path"`
+	MatchedBy string `json:"matched_by"`
+}
+
+type HistoryReconcileResult struct {
+	Checked   int                 `json:"checked"`
+	Present   int                 `json:"present"`
+	Scanned   int                 `json:"scanned"`
+	Relocated []HistoryRelocation `json:"relocated"`
+	Missing   []HistoryItem       `json:"missing"`
+}
+
+type libraryTrackIDs struct {
+	isrc      string
+	spotifyID string
+}
+
+func spotifyIDFromText(text string) string {
+	if match := spotifyTrackIDPattern.FindStringSubmatch(text); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func parseFlacMetadata(filePath string) (*flac.File, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return flac.ParseMetadata(file)
+}
+
+func readLibraryTrackIDs(filePath string) (libraryTrackIDs, error) {
+	var ids libraryTrackIDs
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		f, err := parseFlacMetadata(filePath)
+		if err != nil {
+			return ids, fmt.Errorf("failed to parse FLAC file: %w", err)
+		}
+		for _, block := range f.Meta {
+			if block.Type != flac.VorbisComment {
+				continue
+			}
+			cmt, err := flacvorbis.ParseFromMetaDataBlock(*block)
+			if err != nil {
+				continue
+			}
+			for _, comment := range cmt.Comments {
+				key, value, ok := strings.Cut(comment, "=")
+				if !ok {
+					continue
+				}
+				switch strings.ToUpper(key) {
+				case "ISRC":
+					ids.isrc = value
+				case "SPOTIFY_ID", "SPOTIFYID", "SPOTIFY_TRACK_ID":
+					if len(value) == 22 {
+						ids.spotifyID = value
+					}
+				default:
+					if id := spotifyIDFromText(value); id != "" && ids.spotifyID == "" {
+						ids.spotifyID = id
+					}
+				}
+			}
+		}
+	case ".mp3":
+		tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+		if err != nil {
+			return ids, fmt.Errorf("failed to open MP3 file: %w", err)
+		}
+		defer tag.Close()
+		ids.isrc = tag.GetTextFrame("TSRC").Text
+		for _, frame := range tag.GetFrames("TXXX") {
+			if udtf, ok := frame.(id3v2.UserDefinedTextFrame); ok {
+				if id := spotifyIDFromText(udtf.Value); id != "" {
+					ids.spotifyID = id
+					break
+				}
+			}
+		}
+		if ids.spotifyID == "" {
+			for _, frame := range tag.GetFrames(tag.CommonID("Comments")) {
+				if comment, ok := frame.(id3v2.CommentFrame); ok {
+					if id := spotifyIDFromText(comment.Text); id != "" {
+						ids.spotifyID = id
+						break
+					}
+				}
+			}
+		}
+	case ".m4a":
+		probed, err := probeAudioTags(filePath)
+		if err != nil {
+			return ids, err
+		}
+		for key, value := range probed {
+			if key == "isrc" {
+				ids.isrc = value
+			} else if id := spotifyIDFromText(value); id != "" && ids.spotifyID == "" {
+				ids.spotifyID = id
+			}
+		}
+	default:
+		return ids, fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
+	}
+
+	ids.isrc = strings.ToUpper(strings.TrimSpace(ids.isrc))
+	return ids, nil
+}
+
+func loadHistoryItems(tx *bolt.Tx) []HistoryItem {
+	var items []HistoryItem
+	b := tx.Bucket([]byte(historyBucket))
+	if b == nil {
+		return nil
+	}
+	b.ForEach(func(k, v []byte) error {
+		var item HistoryItem
+		if err := json.Unmarshal(v, &item); err == nil {
+			items = append(items, item)
+		}
+		return nil
+	})
+	return items
+}
+
+func ReconcileHistory(ctx context.Context, roots []string, appName string) (HistoryReconcileResult, error) {
+	result := HistoryReconcileResult{Relocated: []HistoryRelocation{}, Missing: []HistoryItem{}}
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return result, err
+		}
+	}
+
+	var items []HistoryItem
+	if err := historyDB.View(func(tx *bolt.Tx) error {
+		items = loadHistoryItems(tx)
+		return nil
+	}); err != nil {
+		return result, err
+	}
+
+	updates := make(map[string]HistoryItem)
+	original := make(map[string]HistoryItem)
+	presentBySpotifyID := make(map[string]string)
+	presentByISRC := make(map[string]string)
+	var missing []HistoryItem
+
+	for _, item := range items {
+		if historyStatus(item) == HistoryStatusFailed || item.Path == "" {
+			continue
+		}
+		result.Checked++
+		original[item.ID] = item
+
+		if info, err := os.Stat(item.Path); err == nil && !info.IsDir() {
+			result.Present++
+			if item.SpotifyID != "" {
+				presentBySpotifyID[item.SpotifyID] = item.Path
+			}
+			if item.ISRC != "" {
+				presentByISRC[strings.ToUpper(item.ISRC)] = item.Path
+			}
+			if item.Missing {
+				item.Missing = false
+				updates[item.ID] = item
+			}
+			continue
+		}
+		missing = append(missing, item)
+	}
+
+	relocate := func(item HistoryItem, newPath, matchedBy string) {
+		result.Relocated = append(result.Relocated, HistoryRelocation{
+			ID:        item.ID,
+			Title:     item.Title,
+			Artists:   item.Artists,
+			OldPath:   item.Path,
+			NewPath:   newPath,
+			MatchedBy: matchedBy,
+		})
+		item.Path = newPath
+		item.Missing = false
+		if info, err := os.Stat(newPath); err == nil {
+			item.FileSize = info.Size()
+		}
+		updates[item.ID] = item
+	}
+
+	wantSpotifyID := make(map[string][]HistoryItem)
+	wantISRC := make(map[string][]HistoryItem)
+	for _, item := range missing {
+		if path, ok := presentBySpotifyID[item.SpotifyID]; ok && item.SpotifyID != "" {
+			relocate(item, path, "spotify_id")
+			continue
+		}
+		if path, ok := presentByISRC[strings.ToUpper(item.ISRC)]; ok && item.ISRC != "" {
+			relocate(item, path, "isrc")
+			continue
+		}
+		if item.SpotifyID != "" {
+			wantSpotifyID[item.SpotifyID] = append(wantSpotifyID[item.SpotifyID], item)
+		}
+		if item.ISRC != "" {
+			isrc := strings.ToUpper(item.ISRC)
+			wantISRC[isrc] = append(wantISRC[isrc], item)
+		}
+	}
+
+	relocated := make(map[string]bool)
+	claim := func(candidates []HistoryItem, path, matchedBy string) {
+		for _, item := range candidates {
+			if !relocated[item.ID] {
+				relocated[item.ID] = true
+				relocate(item, path, matchedBy)
+			}
+		}
+	}
+
+	if len(wantSpotifyID) > 0 || len(wantISRC) > 0 {
+		seenRoots := make(map[string]bool)
+		for _, root := range roots {
+			if root == "" {
+				continue
+			}
+			root = filepath.Clean(root)
+			if seenRoots[root] {
+				continue
+			}
+			seenRoots[root] = true
+			if info, err := os.Stat(root); err != nil || !info.IsDir() {
+				continue
+			}
+
+			files, err := ListAudioFiles(root)
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				if ctx.Err() != nil {
+					return result, ctx.Err()
+				}
+				ids, err := readLibraryTrackIDs(file.Path)
+				if err != nil {
+					continue
+				}
+				result.Scanned++
+				if ids.spotifyID != "" {
+					claim(wantSpotifyID[ids.spotifyID], file.Path, "spotify_id")
+				}
+				if ids.isrc != "" {
+					claim(wantISRC[ids.isrc], file.Path, "isrc")
+				}
+			}
+		}
+	}
+
+	for _, item := range missing {
+		if _, ok := updates[item.ID]; ok {
+			continue
+		}
+		if !item.Missing {
+			item.Missing = true
+			updates[item.ID] = item
+		}
+		result.Missing = append(result.Missing, item)
+	}
+
+	if len(updates) == 0 {
+		return result, nil
+	}
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		for id, item := range updates {
+			if err := putHistoryItem(tx, original[id], item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func UpdateHistoryPaths(ops []RenameOperation) error {
+	if historyDB == nil || len(ops) == 0 {
+		return nil
+	}
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		if b == nil {
+			return nil
+		}
+		for _, op := range ops {
+			for id := range lookupHistoryIndex(tx, "path", historyPathKey(op.OldPath)) {
+				v := b.Get([]byte(id))
+				if v == nil {
+					continue
+				}
+				var item HistoryItem
+				if err := json.Unmarshal(v, &item); err != nil || filepath.Clean(item.Path) != filepath.Clean(op.OldPath) {
+					continue
+				}
+				updated := item
+				updated.Path = op.NewPath
+				updated.Missing = false
+				if err := putHistoryItem(tx, item, updated); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func MissingHistoryTracks(ctx context.Context, ids []string, appName string) ([]TrackMetadata, error) {
+	if historyDB == nil {
+		if err := InitHistoryDB(appName); err != nil {
+			return nil, err
+		}
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var items []HistoryItem
+	if err := historyDB.View(func(tx *bolt.Tx) error {
+		for id := range lookupHistoryIndex(tx, "missing", "missing") {
+			if len(wanted) > 0 && !wanted[id] {
+				continue
+			}
+			var item HistoryItem
+			if v := tx.Bucket([]byte(historyBucket)).Get([]byte(id)); v != nil && json.Unmarshal(v, &item) == nil {
+				items = append(items, item)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	client := NewSpotifyMetadataClient()
+	seen := make(map[string]bool)
+	tracks := make([]TrackMetadata, 0, len(items))
+	for _, item := range items {
+		if item.SpotifyID == "" || seen[item.SpotifyID] {
+			continue
+		}
+		seen[item.SpotifyID] = true
+
+		if data, err := client.GetFilteredData(ctx, "https://open.spotify.com/track/"+item.SpotifyID, false, 0); err == nil {
+			if resp, ok := data.(TrackResponse); ok {
+				tracks = append(tracks, resp.Track)
+				continue
+			}
+		}
+		tracks = append(tracks, TrackMetadata{
+			SpotifyID:   item.SpotifyID,
+			Name:        item.Title,
+			Artists:     item.Artists,
+			AlbumName:   item.Album,
+			AlbumArtist: item.AlbumArtist,
+			Images:      item.CoverURL,
+			ExternalURL: "https://open.spotify.com/track/" + item.SpotifyID,
+		})
+	}
+	return tracks, nil
+}
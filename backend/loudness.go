@@ -0,0 +1,385 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	replayGainReference   = -18.0
+	loudnessAbsoluteGate  = -70.0
+	integratedRelGate     = -10.0
+	loudnessRangeRelGate  = -20.0
+	truePeakInterpTaps    = 49
+	loudnessBlockSubunits = 4
+	shortTermSubunits     = 30
+	shortTermHopSubunits  = 10
+)
+
+type LoudnessResult struct {
+	IntegratedLoudness float64 `json:"integrated_loudness"`
+	LoudnessRange      float64 `json:"loudness_range"`
+	TruePeak           float64 `json:"true_peak"`
+	TruePeakLinear     float64 `json:"true_peak_linear"`
+	SamplePeak         float64 `json:"sample_peak"`
+	ReplayGain         float64 `json:"replay_gain"`
+
+	blockEnergies []float64
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+func kWeightingFilters(sampleRate float64) (biquad, biquad) {
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+
+	k := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + k/q + k*k
+	highpass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highpass
+}
+
+type truePeakInterpolator struct {
+	factor  int
+	phases  [][]float64
+	history [][]float64
+	pos     int
+}
+
+func newTruePeakInterpolator(sampleRate, channels int) *truePeakInterpolator {
+	factor := 4
+	switch {
+	case sampleRate >= 176400:
+		factor = 1
+	case sampleRate >= 88200:
+		factor = 2
+	}
+	if factor == 1 {
+		return nil
+	}
+
+	taps := truePeakInterpTaps
+	phaseLen := (taps + factor - 1) / factor
+	phases := make([][]float64, factor)
+	for p := range phases {
+		phases[p] = make([]float64, phaseLen)
+	}
+
+	for j := 0; j < taps; j++ {
+		m := float64(j) - float64(taps-1)/2
+		c := 1.0
+		if math.Abs(m) > 1e-6 {
+			x := m * math.Pi / float64(factor)
+			c = math.Sin(x) / x
+		}
+		c *= 0.5 * (1 - math.Cos(2*math.Pi*float64(j)/float64(taps-1)))
+		phases[j%factor][j/factor] = c
+	}
+
+	history := make([][]float64, channels)
+	for ch := range history {
+		history[ch] = make([]float64, phaseLen)
+	}
+
+	return &truePeakInterpolator{factor: factor, phases: phases, history: history}
+}
+
+func (t *truePeakInterpolator) push(ch int, x float64) float64 {
+	h := t.history[ch]
+	n := len(h)
+	h[t.pos] = x
+
+	var peak float64
+	for _, coeffs := range t.phases {
+		var acc float64
+		idx := t.pos
+		for _, c := range coeffs {
+			acc += c * h[idx]
+			idx--
+			if idx < 0 {
+				idx = n - 1
+			}
+		}
+		if a := math.Abs(acc); a > peak {
+			peak = a
+		}
+	}
+	return peak
+}
+
+func (t *truePeakInterpolator) advance() {
+	t.pos++
+	if t.pos == len(t.history[0]) {
+		t.pos = 0
+	}
+}
+
+type loudnessMeter struct {
+	sampleRate    int
+	channels      int
+	weights       []float64
+	shelf         []biquad
+	highpass      []biquad
+	interp        *truePeakInterpolator
+	subunitLen    int
+	subunitFill   int
+	subunitSum    []float64
+	subunits      []float64
+	blockEnergies []float64
+	shortTerm     []float64
+	samplePeak    float64
+	truePeak      float64
+}
+
+func newLoudnessMeter(sampleRate, channels int) *loudnessMeter {
+	m := &loudnessMeter{
+		sampleRate: sampleRate,
+		channels:   channels,
+		weights:    make([]float64, channels),
+		shelf:      make([]biquad, channels),
+		highpass:   make([]biquad, channels),
+		interp:     newTruePeakInterpolator(sampleRate, channels),
+		subunitLen: sampleRate / 10,
+		subunitSum: make([]float64, channels),
+	}
+
+	for ch := 0; ch < channels; ch++ {
+		m.weights[ch] = 1
+		m.shelf[ch], m.highpass[ch] = kWeightingFilters(float64(sampleRate))
+	}
+	switch channels {
+	case 5:
+		m.weights[3], m.weights[4] = 1.41, 1.41
+	case 6:
+		m.weights[3] = 0
+		m.weights[4], m.weights[5] = 1.41, 1.41
+	}
+
+	return m
+}
+
+func (m *loudnessMeter) addFrames(samples [][]float64) {
+	if len(samples) == 0 {
+		return
+	}
+
+	n := len(samples[0])
+	for i := 0; i < n; i++ {
+		for ch := 0; ch < m.channels && ch < len(samples); ch++ {
+			x := samples[ch][i]
+
+			if a := math.Abs(x); a > m.samplePeak {
+				m.samplePeak = a
+			}
+			if m.interp != nil {
+				if p := m.interp.push(ch, x); p > m.truePeak {
+					m.truePeak = p
+				}
+			}
+
+			y := m.highpass[ch].process(m.shelf[ch].process(x))
+			m.subunitSum[ch] += y * y
+		}
+		if m.interp != nil {
+			m.interp.advance()
+		}
+
+		m.subunitFill++
+		if m.subunitFill == m.subunitLen {
+			m.finishSubunit()
+		}
+	}
+}
+
+func (m *loudnessMeter) finishSubunit() {
+	var energy float64
+	for ch := 0; ch < m.channels; ch++ {
+		energy += m.weights[ch] * m.subunitSum[ch] / float64(m.subunitLen)
+		m.subunitSum[ch] = 0
+	}
+	m.subunitFill = 0
+	m.subunits = append(m.subunits, energy)
+
+	count := len(m.subunits)
+	if count >= loudnessBlockSubunits {
+		m.blockEnergies = append(m.blockEnergies, meanEnergy(m.subunits[count-loudnessBlockSubunits:]))
+	}
+	if count >= shortTermSubunits && (count-shortTermSubunits)%shortTermHopSubunits == 0 {
+		m.shortTerm = append(m.shortTerm, meanEnergy(m.subunits[count-shortTermSubunits:]))
+	}
+}
+
+func meanEnergy(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func energyToLoudness(energy float64) float64 {
+	if energy <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(energy)
+}
+
+func gatedLoudness(energies []float64, relativeGate float64) (float64, []float64) {
+	var absGated []float64
+	for _, e := range energies {
+		if energyToLoudness(e) > loudnessAbsoluteGate {
+			absGated = append(absGated, e)
+		}
+	}
+	if len(absGated) == 0 {
+		return math.Inf(-1), nil
+	}
+
+	threshold := energyToLoudness(meanEnergy(absGated)) + relativeGate
+	var gated []float64
+	for _, e := range absGated {
+		if energyToLoudness(e) > threshold {
+			gated = append(gated, e)
+		}
+	}
+	if len(gated) == 0 {
+		return math.Inf(-1), nil
+	}
+
+	return energyToLoudness(meanEnergy(gated)), gated
+}
+
+func loudnessRange(shortTerm []float64) float64 {
+	_, gated := gatedLoudness(shortTerm, loudnessRangeRelGate)
+	if len(gated) == 0 {
+		return 0
+	}
+
+	values := make([]float64, len(gated))
+	for i, e := range gated {
+		values[i] = energyToLoudness(e)
+	}
+	sort.Float64s(values)
+
+	lo := int(float64(len(values)-1)*0.10 + 0.5)
+	hi := int(float64(len(values)-1)*0.95 + 0.5)
+	return values[hi] - values[lo]
+}
+
+func (m *loudnessMeter) result() *LoudnessResult {
+	integrated, _ := gatedLoudness(m.blockEnergies, integratedRelGate)
+
+	truePeak := math.Max(m.truePeak, m.samplePeak)
+	result := &LoudnessResult{
+		IntegratedLoudness: integrated,
+		LoudnessRange:      loudnessRange(m.shortTerm),
+		TruePeakLinear:     truePeak,
+		TruePeak:           20 * math.Log10(truePeak),
+		SamplePeak:         m.samplePeak,
+		blockEnergies:      m.blockEnergies,
+	}
+	if !math.IsInf(integrated, -1) {
+		result.ReplayGain = replayGainReference - integrated
+	}
+	if math.IsInf(result.TruePeak, -1) {
+		result.TruePeak = -144
+	}
+	if math.IsInf(result.IntegratedLoudness, -1) {
+		result.IntegratedLoudness = loudnessAbsoluteGate
+	}
+
+	return result
+}
+
+func AlbumLoudness(tracks []*LoudnessResult) (*LoudnessResult, error) {
+	var energies []float64
+	var peak, samplePeak float64
+	for _, t := range tracks {
+		if t == nil {
+			continue
+		}
+		energies = append(energies, t.blockEnergies...)
+		peak = math.Max(peak, t.TruePeakLinear)
+		samplePeak = math.Max(samplePeak, t.SamplePeak)
+	}
+	if len(energies) == 0 {
+		return nil, fmt.Errorf("no loudness data for album")
+	}
+
+	integrated, _ := gatedLoudness(energies, integratedRelGate)
+	if math.IsInf(integrated, -1) {
+		return nil, fmt.Errorf("album is silent")
+	}
+
+	return &LoudnessResult{
+		IntegratedLoudness: integrated,
+		TruePeakLinear:     peak,
+		TruePeak:           20 * math.Log10(math.Max(peak, 1e-10)),
+		SamplePeak:         samplePeak,
+		ReplayGain:         replayGainReference - integrated,
+	}, nil
+}
+
+func MeasureLoudness(filePath string) (*LoudnessResult, error) {
+	dec, err := OpenPCMDecoder(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	format := dec.Format()
+	meter := newLoudnessMeter(format.SampleRate, format.Channels)
+	maxVal := format.maxValue()
+	buf := make([][]float64, format.Channels)
+
+	for {
+		samples, err := dec.ReadFrames()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode audio: %w", err)
+		}
+
+		for ch := range buf {
+			buf[ch] = normalizeSamples(buf[ch], samples[ch], maxVal)
+		}
+		meter.addFrames(buf)
+	}
+
+	return meter.result(), nil
+}
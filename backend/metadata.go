@@ -1,11 +1,13 @@
 package backend
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	pathfilepath "path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -32,22 +34,24 @@ type Metadata struct {
 	Lyrics      string
 	Description string
 	ISRC        string
+	Genre       string
+	Comment     string
 }
 
-func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
-	f, err := flac.ParseFile(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to parse FLAC file: %w", err)
-	}
+type EmbedOptions struct {
+	Merge     bool
+	Overwrite []string
+	Extra     map[string]string
+	Cover     CoverImageOptions
+}
 
-	var cmtIdx = -1
-	for idx, block := range f.Meta {
-		if block.Type == flac.VorbisComment {
-			cmtIdx = idx
-			break
-		}
-	}
+const OverwriteAll = "*"
+
+func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
+	return EmbedMetadataWithOptions(filepath, metadata, coverPath, EmbedOptions{})
+}
 
+func vorbisCommentsFromMetadata(metadata Metadata, extra map[string]string) *flacvorbis.MetaDataBlockVorbisComment {
 	cmt := flacvorbis.New()
 
 	if metadata.Title != "" {
@@ -86,15 +90,109 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 	if metadata.Description != "" {
 		_ = cmt.Add("DESCRIPTION", metadata.Description)
 	}
+	if metadata.URL != "" {
+		_ = cmt.Add("URL", metadata.URL)
+	}
 
 	if metadata.ISRC != "" {
 		_ = cmt.Add("ISRC", metadata.ISRC)
 	}
 
+	if metadata.Genre != "" {
+		_ = cmt.Add(flacvorbis.FIELD_GENRE, metadata.Genre)
+	}
+
+	if metadata.Comment != "" {
+		_ = cmt.Add("COMMENT", metadata.Comment)
+	}
+
 	if metadata.Lyrics != "" {
 		_ = cmt.Add("LYRICS", metadata.Lyrics)
 	}
 
+	extraKeys := make([]string, 0, len(extra))
+	for key := range extra {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		if extra[key] != "" {
+			_ = cmt.Add(key, extra[key])
+		}
+	}
+
+	return cmt
+}
+
+func mergeVorbisComments(existing, updates *flacvorbis.MetaDataBlockVorbisComment, overwrite []string) *flacvorbis.MetaDataBlockVorbisComment {
+	updateKeys := make(map[string]bool)
+	for _, comment := range updates.Comments {
+		key, _, _ := strings.Cut(comment, "=")
+		updateKeys[strings.ToUpper(key)] = true
+	}
+
+	overwriteAll := false
+	replaced := make(map[string]bool)
+	for _, key := range overwrite {
+		if key == OverwriteAll {
+			overwriteAll = true
+			continue
+		}
+		replaced[strings.ToUpper(key)] = true
+	}
+
+	merged := flacvorbis.New()
+	merged.Vendor = existing.Vendor
+	kept := make(map[string]bool)
+	for _, comment := range existing.Comments {
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok {
+			continue
+		}
+		upper := strings.ToUpper(key)
+		if replaced[upper] || (overwriteAll && updateKeys[upper]) {
+			continue
+		}
+		_ = merged.Add(key, value)
+		kept[upper] = true
+	}
+
+	for _, comment := range updates.Comments {
+		key, value, _ := strings.Cut(comment, "=")
+		if !kept[strings.ToUpper(key)] {
+			_ = merged.Add(key, value)
+		}
+	}
+
+	return merged
+}
+
+func EmbedMetadataWithOptions(filepath string, metadata Metadata, coverPath string, opts EmbedOptions) error {
+	f, err := flac.ParseFile(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+
+	var cmtIdx = -1
+	var existingCmt *flacvorbis.MetaDataBlockVorbisComment
+	for idx, block := range f.Meta {
+		if block.Type == flac.VorbisComment {
+			cmtIdx = idx
+			if opts.Merge {
+				existingCmt, err = flacvorbis.ParseFromMetaDataBlock(*block)
+				if err != nil {
+					existingCmt = nil
+				}
+			}
+			break
+		}
+	}
+
+	cmt := vorbisCommentsFromMetadata(metadata, opts.Extra)
+	if existingCmt != nil {
+		cmt = mergeVorbisComments(existingCmt, cmt, opts.Overwrite)
+	}
+
 	cmtBlock := cmt.Marshal()
 	if cmtIdx < 0 {
 		f.Meta = append(f.Meta, &cmtBlock)
@@ -103,7 +201,7 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 	}
 
 	if coverPath != "" && fileExists(coverPath) {
-		if err := embedCoverArt(f, coverPath); err != nil {
+		if err := embedCoverArt(f, coverPath, opts.Cover); err != nil {
 			fmt.Printf("Warning: Failed to embed cover art: %v\n", err)
 		}
 	}
@@ -115,20 +213,20 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 	return nil
 }
 
-func embedCoverArt(f *flac.File, coverPath string) error {
-	imgData, err := os.ReadFile(coverPath)
+func embedCoverArt(f *flac.File, coverPath string, opts CoverImageOptions) error {
+	cover, err := LoadCoverImage(coverPath, opts)
 	if err != nil {
-		return fmt.Errorf("failed to read cover image: %w", err)
+		return err
 	}
 
-	picture, err := flacpicture.NewFromImageData(
-		flacpicture.PictureTypeFrontCover,
-		"Cover",
-		imgData,
-		"image/jpeg",
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create picture block: %w", err)
+	picture := &flacpicture.MetadataBlockPicture{
+		PictureType: flacpicture.PictureTypeFrontCover,
+		MIME:        cover.MIME,
+		Description: "Cover",
+		Width:       uint32(cover.Width),
+		Height:      uint32(cover.Height),
+		ColorDepth:  uint32(cover.Depth),
+		ImageData:   cover.Data,
 	}
 
 	pictureBlock := picture.Marshal()
@@ -164,52 +262,10 @@ func EmbedLyricsOnly(filepath string, lyrics string) error {
 	if lyrics == "" {
 		return nil
 	}
-	f, err := flac.ParseFile(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to parse FLAC file: %w", err)
-	}
-
-	var cmtIdx = -1
-	var existingCmt *flacvorbis.MetaDataBlockVorbisComment
-	for idx, block := range f.Meta {
-		if block.Type == flac.VorbisComment {
-			cmtIdx = idx
-			existingCmt, err = flacvorbis.ParseFromMetaDataBlock(*block)
-			if err != nil {
-				existingCmt = nil
-			}
-			break
-		}
-	}
-
-	cmt := flacvorbis.New()
-
-	if existingCmt != nil {
-		for _, comment := range existingCmt.Comments {
-			parts := strings.SplitN(comment, "=", 2)
-			if len(parts) == 2 {
-				fieldName := strings.ToUpper(parts[0])
-				if fieldName != "LYRICS" && fieldName != "UNSYNCEDLYRICS" && fieldName != "SYNCEDLYRICS" {
-					_ = cmt.Add(parts[0], parts[1])
-				}
-			}
-		}
-	}
-
-	_ = cmt.Add("LYRICS", lyrics)
-
-	cmtBlock := cmt.Marshal()
-	if cmtIdx < 0 {
-		f.Meta = append(f.Meta, &cmtBlock)
-	} else {
-		f.Meta[cmtIdx] = &cmtBlock
-	}
-
-	if err := f.Save(filepath); err != nil {
-		return fmt.Errorf("failed to save FLAC file: %w", err)
-	}
-
-	return nil
+	return EmbedMetadataWithOptions(filepath, Metadata{Lyrics: lyrics}, "", EmbedOptions{
+		Merge:     true,
+		Overwrite: []string{"LYRICS", "UNSYNCEDLYRICS", "SYNCEDLYRICS"},
+	})
 }
 
 func ExtractCoverArt(filePath string) (string, error) {
@@ -394,17 +450,17 @@ func embedCoverToMp3(filePath string, coverPath string) error {
 
 	tag.DeleteFrames(tag.CommonID("Attached picture"))
 
-	artwork, err := os.ReadFile(coverPath)
+	cover, err := LoadCoverImage(coverPath, CoverImageOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to read cover art: %w", err)
 	}
 
 	pic := id3v2.PictureFrame{
 		Encoding:    id3v2.EncodingUTF8,
-		MimeType:    "image/jpeg",
+		MimeType:    cover.MIME,
 		PictureType: id3v2.PTFrontCover,
 		Description: "Front cover",
-		Picture:     artwork,
+		Picture:     cover.Data,
 	}
 	tag.AddAttachedPicture(pic)
 
@@ -682,16 +738,14 @@ func parseLRCTimestamp(timestamp string) int64 {
 	return -1
 }
 
-func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
-	var metadata Metadata
-
+func probeAudioTags(filePath string) (map[string]string, error) {
 	ffprobePath, err := GetFFprobePath()
 	if err != nil {
-		return metadata, err
+		return nil, err
 	}
 
 	if err := ValidateExecutable(ffprobePath); err != nil {
-		return metadata, fmt.Errorf("invalid ffprobe executable: %w", err)
+		return nil, fmt.Errorf("invalid ffprobe executable: %w", err)
 	}
 
 	cmd := exec.Command(ffprobePath,
@@ -706,7 +760,7 @@ func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
 
 	output, err := cmd.Output()
 	if err != nil {
-		return metadata, err
+		return nil, err
 	}
 
 	var result struct {
@@ -719,7 +773,7 @@ func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
 	}
 
 	if err := json.Unmarshal(output, &result); err != nil {
-		return metadata, err
+		return nil, err
 	}
 
 	allTags := make(map[string]string)
@@ -734,6 +788,17 @@ func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
 		allTags[strings.ToLower(key)] = value
 	}
 
+	return allTags, nil
+}
+
+func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
+	var metadata Metadata
+
+	allTags, err := probeAudioTags(filePath)
+	if err != nil {
+		return metadata, err
+	}
+
 	for key, value := range allTags {
 		switch key {
 		case "title":
@@ -780,33 +845,55 @@ func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
 			metadata.Publisher = value
 		case "url":
 			metadata.URL = value
-		case "description", "comment":
-			if metadata.Description == "" {
-				metadata.Description = value
-			}
+		case "description":
+			metadata.Description = value
+		case "comment":
+			metadata.Comment = value
+		case "genre":
+			metadata.Genre = value
+		case "isrc", "tsrc":
+			metadata.ISRC = value
 		}
 	}
 
 	return metadata, nil
 }
 
-func EmbedMetadataToConvertedFile(filePath string, metadata Metadata, coverPath string) error {
+func EmbedMetadataToConvertedFile(filePath string, metadata Metadata, coverPath string, cover CoverImageOptions) error {
+	return embedMetadataToFile(filePath, metadata, coverPath, EmbedOptions{Cover: cover})
+}
+
+func MergeMetadataToFile(filePath string, metadata Metadata, coverPath string, cover CoverImageOptions) error {
+	return embedMetadataToFile(filePath, metadata, coverPath, EmbedOptions{Merge: true, Overwrite: []string{OverwriteAll}, Cover: cover})
+}
+
+func embedMetadataToFile(filePath string, metadata Metadata, coverPath string, opts EmbedOptions) error {
 	ext := strings.ToLower(pathfilepath.Ext(filePath))
 
 	switch ext {
 	case ".flac":
 
-		return EmbedMetadata(filePath, metadata, coverPath)
+		return EmbedMetadataWithOptions(filePath, metadata, coverPath, opts)
 	case ".mp3":
-		return embedMetadataToMP3(filePath, metadata, coverPath)
+		if !opts.Merge {
+			return embedMetadataToMP3(filePath, metadata, coverPath, opts.Cover)
+		}
+		userFrames, err := readMp3UserFrames(filePath)
+		if err != nil {
+			return err
+		}
+		if err := embedMetadataToMP3(filePath, metadata, coverPath, opts.Cover); err != nil {
+			return err
+		}
+		return finishMp3Tags(filePath, userFrames, nil)
 	case ".m4a":
-		return embedMetadataToM4A(filePath, metadata, coverPath)
+		return embedMetadataToM4A(filePath, metadata, coverPath, opts.Cover)
 	default:
 		return fmt.Errorf("unsupported file format: %s", ext)
 	}
 }
 
-func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) error {
+func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string, coverOpts CoverImageOptions) error {
 	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
 	if err != nil {
 		return fmt.Errorf("failed to open MP3 file: %w", err)
@@ -825,11 +912,11 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 		tag.SetAlbum(metadata.Album)
 	}
 	if metadata.Date != "" {
-		year := metadata.Date
-		if len(year) >= 4 {
-			year = year[:4]
+		date := metadata.Date
+		if tag.Version() < 4 && len(date) >= 4 {
+			date = date[:4]
 		}
-		tag.SetYear(year)
+		tag.SetYear(date)
 	}
 
 	if metadata.AlbumArtist != "" {
@@ -870,18 +957,32 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 		tag.AddTextFrame("TSRC", id3v2.EncodingUTF8, metadata.ISRC)
 	}
 
+	if metadata.Genre != "" {
+		tag.SetGenre(metadata.Genre)
+	}
+
+	if metadata.Comment != "" {
+		tag.DeleteFrames(tag.CommonID("Comments"))
+		tag.AddCommentFrame(id3v2.CommentFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			Language:    "eng",
+			Description: "",
+			Text:        metadata.Comment,
+		})
+	}
+
 	if coverPath != "" && fileExists(coverPath) {
 
 		tag.DeleteFrames(tag.CommonID("Attached picture"))
 
-		artwork, err := os.ReadFile(coverPath)
+		cover, err := LoadCoverImage(coverPath, coverOpts)
 		if err == nil {
 			pic := id3v2.PictureFrame{
 				Encoding:    id3v2.EncodingUTF8,
-				MimeType:    "image/jpeg",
+				MimeType:    cover.MIME,
 				PictureType: id3v2.PTFrontCover,
 				Description: "Cover",
-				Picture:     artwork,
+				Picture:     cover.Data,
 			}
 			tag.AddAttachedPicture(pic)
 		} else {
@@ -896,7 +997,11 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 	return nil
 }
 
-func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) error {
+func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string, coverOpts CoverImageOptions) error {
+	return writeM4AMetadata(filePath, metadata, coverPath, coverOpts, nil)
+}
+
+func writeM4AMetadata(filePath string, metadata Metadata, coverPath string, coverOpts CoverImageOptions, clearKeys []string) error {
 	ffmpegPath, err := GetFFmpegPath()
 	if err != nil {
 		return fmt.Errorf("ffmpeg not found: %w", err)
@@ -912,6 +1017,15 @@ func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) er
 	}
 
 	if coverPath != "" && fileExists(coverPath) {
+		if processedPath, err := writeProcessedCover(coverPath, coverOpts); err == nil {
+			if processedPath != coverPath {
+				defer os.Remove(processedPath)
+				coverPath = processedPath
+			}
+		} else {
+			fmt.Printf("[EmbedMetadataToM4A] Warning: Failed to process cover art: %v\n", err)
+		}
+
 		args = append(args, "-i", coverPath)
 		args = append(args, "-map", "0:a", "-map", "1", "-c:a", "copy", "-c:v", "copy", "-disposition:v:0", "attached_pic")
 	} else {
@@ -956,6 +1070,15 @@ func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) er
 	if metadata.ISRC != "" {
 		args = append(args, "-metadata", "isrc="+metadata.ISRC)
 	}
+	if metadata.Genre != "" {
+		args = append(args, "-metadata", "genre="+metadata.Genre)
+	}
+	if metadata.Comment != "" {
+		args = append(args, "-metadata", "comment="+metadata.Comment)
+	}
+	for _, key := range clearKeys {
+		args = append(args, "-metadata", key+"=")
+	}
 
 	tmpOutputFile := strings.TrimSuffix(filePath, pathfilepath.Ext(filePath)) + ".tmp" + pathfilepath.Ext(filePath)
 	defer func() {
@@ -980,3 +1103,37 @@ func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) er
 
 	return nil
 }
+
+func writeProcessedCover(coverPath string, opts CoverImageOptions) (string, error) {
+	original, err := os.ReadFile(coverPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cover image: %w", err)
+	}
+
+	cover, err := ProcessCoverImage(original, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(cover.Data, original) {
+		return coverPath, nil
+	}
+
+	ext := ".jpg"
+	if cover.MIME == "image/png" {
+		ext = ".png"
+	}
+
+	tmpFile, err := os.CreateTemp("", "cover-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.Write(cover.Data); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write cover art: %w", err)
+	}
+
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,579 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const mirrorBucket = "MirrorState"
+
+var (
+	mirrorDB     *bolt.DB
+	mirrorDBLock sync.Mutex
+)
+
+var (
+	mirrorAudioExtensions    = map[string]bool{".flac": true, ".mp3": true, ".m4a": true, ".wav": true, ".aiff": true, ".ogg": true, ".opus": true}
+	mirrorSidecarExtensions  = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".lrc": true, ".txt": true}
+	mirrorPlaylistExtensions = map[string]bool{".m3u": true, ".m3u8": true}
+)
+
+type MirrorRequest struct {
+	SourceRoot string `json:"source_root"`
+	DestRoot   string `json:"dest_root"`
+	Preset     string `json:"preset"`
+	Workers    int    `json:"workers,omitempty"`
+}
+
+type MirrorResult struct {
+	Converted int                  `json:"converted"`
+	Copied    int                  `json:"copied"`
+	Unchanged int                  `json:"unchanged"`
+	Sidecars  int                  `json:"sidecars"`
+	Playlists int                  `json:"playlists"`
+	Deleted   int                  `json:"deleted"`
+	Failed    []ConvertAudioResult `json:"failed,omitempty"`
+	Errors    []string             `json:"errors,omitempty"`
+	Cancelled bool                 `json:"cancelled"`
+}
+
+type mirrorEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	Hash    string `json:"hash"`
+	Preset  string `json:"preset"`
+	Output  string `json:"output"`
+}
+
+type mirrorJob struct {
+	source string
+	dest   string
+	key    []byte
+	sig    fileSignature
+	copy   bool
+}
+
+func InitMirrorDB() error {
+	mirrorDBLock.Lock()
+	defer mirrorDBLock.Unlock()
+
+	if mirrorDB != nil {
+		return nil
+	}
+
+	appDir, err := GetFFmpegDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		os.MkdirAll(appDir, 0755)
+	}
+	dbPath := filepath.Join(appDir, "mirror.db")
+
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(mirrorBucket))
+		return err
+	})
+
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	mirrorDB = db
+	return nil
+}
+
+func CloseMirrorDB() {
+	mirrorDBLock.Lock()
+	defer mirrorDBLock.Unlock()
+
+	if mirrorDB != nil {
+		mirrorDB.Close()
+		mirrorDB = nil
+	}
+}
+
+func mirrorKeyPrefix(destRoot string) []byte {
+	return []byte(destRoot + "\x00")
+}
+
+func mirrorKey(destRoot, rel string) []byte {
+	return append(mirrorKeyPrefix(destRoot), filepath.ToSlash(rel)...)
+}
+
+func loadMirrorEntries(destRoot string) map[string]mirrorEntry {
+	entries := make(map[string]mirrorEntry)
+	prefix := mirrorKeyPrefix(destRoot)
+
+	mirrorDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(mirrorBucket))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var entry mirrorEntry
+			if json.Unmarshal(v, &entry) == nil {
+				entries[string(k)] = entry
+			}
+		}
+		return nil
+	})
+
+	return entries
+}
+
+func storeMirrorEntry(key []byte, entry mirrorEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return mirrorDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(mirrorBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put(key, buf)
+	})
+}
+
+func deleteMirrorEntries(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return mirrorDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(mirrorBucket))
+		if b == nil {
+			return nil
+		}
+		for _, k := range keys {
+			if err := b.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func MirrorLibrary(ctx context.Context, req MirrorRequest) (*MirrorResult, error) {
+	if req.SourceRoot == "" || req.DestRoot == "" {
+		return nil, fmt.Errorf("source and destination folders are required")
+	}
+
+	sourceRoot, err := filepath.Abs(req.SourceRoot)
+	if err != nil {
+		return nil, err
+	}
+	destRoot, err := filepath.Abs(req.DestRoot)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(sourceRoot); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("source folder does not exist: %s", sourceRoot)
+	}
+	if isWithinDir(destRoot, sourceRoot) || isWithinDir(sourceRoot, destRoot) {
+		return nil, fmt.Errorf("source and destination folders must not contain each other")
+	}
+
+	preset, ok := FindConvertPreset(req.Preset)
+	if !ok {
+		return nil, fmt.Errorf("unknown preset: %s", req.Preset)
+	}
+
+	ffmpegPath, err := getConvertFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := InitMirrorDB(); err != nil {
+		return nil, fmt.Errorf("failed to open mirror database: %w", err)
+	}
+
+	var audioFiles, sidecars, playlists, scanErrors []string
+	err = filepath.WalkDir(sourceRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			scanErrors = append(scanErrors, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+		if d.IsDir() {
+			if path != sourceRoot && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		switch {
+		case mirrorAudioExtensions[ext]:
+			audioFiles = append(audioFiles, path)
+		case mirrorSidecarExtensions[ext]:
+			sidecars = append(sidecars, path)
+		case mirrorPlaylistExtensions[ext]:
+			playlists = append(playlists, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan source folder: %w", err)
+	}
+
+	result := &MirrorResult{Errors: scanErrors}
+	entries := loadMirrorEntries(destRoot)
+	seenKeys := make(map[string]bool)
+	expected := make(map[string]bool)
+	outputs := make(map[string]string)
+	var jobs []mirrorJob
+
+	for _, source := range audioFiles {
+		rel, _ := filepath.Rel(sourceRoot, source)
+		ext := filepath.Ext(rel)
+		copyAsIs := strings.EqualFold(ext, preset.Extension()) && preset.Mode != ConvertModeLossless
+		dest := filepath.Join(destRoot, strings.TrimSuffix(rel, ext)+preset.Extension())
+		if copyAsIs {
+			dest = filepath.Join(destRoot, rel)
+		}
+
+		key := mirrorKey(destRoot, rel)
+		seenKeys[string(key)] = true
+		expected[dest] = true
+		outputs[filepath.Clean(source)] = dest
+
+		sig, err := computeFileSignature(source)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+
+		if entry, ok := entries[string(key)]; ok && entry.Preset == preset.ID && entry.Size == sig.size &&
+			entry.ModTime == sig.modTime && entry.Hash == sig.hash && fileExists(dest) {
+			result.Unchanged++
+			continue
+		}
+
+		jobs = append(jobs, mirrorJob{source: source, dest: dest, key: key, sig: sig, copy: copyAsIs})
+	}
+
+	runMirrorJobs(ctx, ffmpegPath, preset, jobs, req.Workers, result)
+
+	if ctx.Err() != nil {
+		result.Cancelled = true
+		return result, ctx.Err()
+	}
+
+	for _, source := range sidecars {
+		rel, _ := filepath.Rel(sourceRoot, source)
+		dest := filepath.Join(destRoot, rel)
+		key := mirrorKey(destRoot, rel)
+		seenKeys[string(key)] = true
+		expected[dest] = true
+
+		copied, err := syncSidecar(source, dest)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+		if copied {
+			result.Sidecars++
+		}
+		if entries[string(key)].Output != dest {
+			storeMirrorEntry(key, mirrorEntry{Output: dest})
+		}
+	}
+
+	for _, source := range playlists {
+		rel, _ := filepath.Rel(sourceRoot, source)
+		dest := filepath.Join(destRoot, strings.TrimSuffix(rel, filepath.Ext(rel))+".m3u8")
+		key := mirrorKey(destRoot, rel)
+		seenKeys[string(key)] = true
+		expected[dest] = true
+
+		if err := rewriteMirrorPlaylist(source, dest, outputs); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+		result.Playlists++
+		if entries[string(key)].Output != dest {
+			storeMirrorEntry(key, mirrorEntry{Output: dest})
+		}
+	}
+
+	if len(scanErrors) > 0 {
+		result.Errors = append(result.Errors, "source folder could not be fully scanned, skipped removing orphaned files")
+		return result, nil
+	}
+
+	var staleKeys []string
+	result.Deleted, staleKeys = removeMirrorOrphans(destRoot, entries, seenKeys, expected)
+	if err := deleteMirrorEntries(staleKeys); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to prune mirror database: %v", err))
+	}
+
+	return result, nil
+}
+
+func runMirrorJobs(ctx context.Context, ffmpegPath string, preset ConvertPreset, jobs []mirrorJob, workers int, result *MirrorResult) {
+	if len(jobs) == 0 {
+		return
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	batchID := time.Now().UnixNano()
+	itemIDs := make([]string, len(jobs))
+	for i, job := range jobs {
+		itemIDs[i] = fmt.Sprintf("mirror-%d-%d", batchID, i)
+		AddToConvertQueue(itemIDs[i], job.source)
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				job := jobs[i]
+				converted := false
+				var res ConvertAudioResult
+
+				if job.copy {
+					StartConvertItem(itemIDs[i], job.dest, 0)
+					res = ConvertAudioResult{InputFile: job.source, OutputFile: job.dest}
+					if err := copyFilePreservingTime(job.source, job.dest); err != nil {
+						res.Error = err.Error()
+						FailConvertItem(itemIDs[i], res.Error)
+					} else {
+						res.Success = true
+						CompleteConvertItem(itemIDs[i], job.dest)
+					}
+				} else {
+					res = convertAudioFile(ctx, ffmpegPath, itemIDs[i], job.source, job.dest, preset)
+					if res.Skipped && strings.EqualFold(filepath.Ext(job.source), preset.Extension()) {
+						res.OutputFile = job.dest
+						if err := copyFilePreservingTime(job.source, job.dest); err != nil {
+							res.Success = false
+							res.Error = err.Error()
+						}
+					} else {
+						if res.Skipped {
+							reencode := preset
+							reencode.OnlyIfAbove, reencode.SampleRate, reencode.BitDepth = false, 0, 0
+							res = convertAudioFile(ctx, ffmpegPath, itemIDs[i], job.source, job.dest, reencode)
+						}
+						converted = res.Success
+					}
+				}
+
+				if res.Success {
+					entry := mirrorEntry{
+						Size:    job.sig.size,
+						ModTime: job.sig.modTime,
+						Hash:    job.sig.hash,
+						Preset:  preset.ID,
+						Output:  job.dest,
+					}
+					if err := storeMirrorEntry(job.key, entry); err != nil {
+						fmt.Printf("[Mirror] Warning: failed to record %s: %v\n", job.source, err)
+					}
+				}
+
+				mu.Lock()
+				switch {
+				case converted:
+					result.Converted++
+				case res.Success:
+					result.Copied++
+				case ctx.Err() == nil:
+					result.Failed = append(result.Failed, res)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range jobs {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case queue <- i:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		for _, id := range itemIDs {
+			updateQueueItem(id, func(item *DownloadItem) {
+				if item.Status == StatusQueued {
+					item.Status = StatusSkipped
+					item.EndTime = time.Now().Unix()
+					item.ErrorMessage = "Cancelled"
+				}
+			})
+		}
+	}
+}
+
+func copyFilePreservingTime(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+func syncSidecar(src, dst string) (bool, error) {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, err
+	}
+	if dstInfo, err := os.Stat(dst); err == nil &&
+		dstInfo.Size() == srcInfo.Size() && !dstInfo.ModTime().Before(srcInfo.ModTime()) {
+		return false, nil
+	}
+	return true, copyFilePreservingTime(src, dst)
+}
+
+func rewriteMirrorPlaylist(src, dst string, outputs map[string]string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	srcDir := filepath.Dir(src)
+	dstDir := filepath.Dir(dst)
+
+	var out strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		entry := strings.TrimSpace(line)
+
+		if entry == "" || strings.HasPrefix(entry, "#") || strings.Contains(entry, "://") {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		native := filepath.FromSlash(strings.ReplaceAll(entry, "\\", "/"))
+		absolute := filepath.IsAbs(native)
+		target := native
+		if !absolute {
+			target = filepath.Join(srcDir, native)
+		}
+
+		mirrored, ok := outputs[filepath.Clean(target)]
+		if !ok {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		if absolute {
+			out.WriteString(mirrored + "\n")
+			continue
+		}
+		rel, err := filepath.Rel(dstDir, mirrored)
+		if err != nil {
+			out.WriteString(mirrored + "\n")
+			continue
+		}
+		out.WriteString(filepath.ToSlash(rel) + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, []byte(out.String()), 0644)
+}
+
+func removeMirrorOrphans(destRoot string, entries map[string]mirrorEntry, seenKeys, expected map[string]bool) (int, []string) {
+	deleted := 0
+	var staleKeys []string
+	for key, entry := range entries {
+		stale := !seenKeys[key]
+		output := filepath.Clean(entry.Output)
+		if entry.Output == "" || expected[output] || !isWithinDir(output, destRoot) {
+			if stale {
+				staleKeys = append(staleKeys, key)
+			}
+			continue
+		}
+		if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("[Mirror] Warning: failed to remove %s: %v\n", output, err)
+			continue
+		} else if err == nil {
+			fmt.Printf("[Mirror] Removed orphan: %s\n", output)
+			deleted++
+		}
+		if stale {
+			staleKeys = append(staleKeys, key)
+		}
+		removeEmptyDirs(filepath.Dir(output), destRoot)
+	}
+	return deleted, staleKeys
+}
@@ -0,0 +1,376 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const itunesFreeformMean = "com.apple.iTunes"
+
+var mp4ContainerBoxes = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+	"udta": true,
+	"meta": true,
+	"ilst": true,
+	"edts": true,
+	"dinf": true,
+}
+
+type mp4Box struct {
+	typ      string
+	prefix   []byte
+	data     []byte
+	children []*mp4Box
+}
+
+type mp4TopBox struct {
+	typ    string
+	offset int64
+	size   int64
+}
+
+func readMP4TopBoxes(f *os.File) ([]mp4TopBox, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileSize := info.Size()
+
+	var boxes []mp4TopBox
+	var offset int64
+	header := make([]byte, 16)
+	for offset < fileSize {
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return nil, fmt.Errorf("failed to read box header: %w", err)
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		typ := string(header[4:8])
+		switch size {
+		case 0:
+			size = fileSize - offset
+		case 1:
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, fmt.Errorf("failed to read box size: %w", err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if size < 8 || offset+size > fileSize {
+			return nil, fmt.Errorf("invalid %q box size", typ)
+		}
+
+		boxes = append(boxes, mp4TopBox{typ: typ, offset: offset, size: size})
+		offset += size
+	}
+
+	return boxes, nil
+}
+
+func parseMP4Boxes(data []byte) ([]*mp4Box, error) {
+	var boxes []*mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated box header")
+		}
+
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		typ := string(data[4:8])
+		headerLen := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, fmt.Errorf("truncated box header")
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerLen = 16
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid %q box size", typ)
+		}
+
+		box, err := newMP4Box(typ, data[headerLen:size])
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, box)
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+func newMP4Box(typ string, payload []byte) (*mp4Box, error) {
+	box := &mp4Box{typ: typ}
+	if !mp4ContainerBoxes[typ] {
+		box.data = payload
+		return box, nil
+	}
+
+	if typ == "meta" && !(len(payload) >= 8 && string(payload[4:8]) == "hdlr") {
+		if len(payload) < 4 {
+			return nil, fmt.Errorf("truncated meta box")
+		}
+		box.prefix = payload[:4]
+		payload = payload[4:]
+	}
+
+	children, err := parseMP4Boxes(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", typ, err)
+	}
+	box.children = children
+	return box, nil
+}
+
+func (b *mp4Box) child(typ string) *mp4Box {
+	for _, c := range b.children {
+		if c.typ == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+func (b *mp4Box) ensureChild(typ string, create func() *mp4Box) *mp4Box {
+	if c := b.child(typ); c != nil {
+		return c
+	}
+	c := create()
+	b.children = append(b.children, c)
+	return c
+}
+
+func (b *mp4Box) size() uint64 {
+	size := uint64(8 + len(b.prefix) + len(b.data))
+	for _, c := range b.children {
+		size += c.size()
+	}
+	if size > math.MaxUint32 {
+		size += 8
+	}
+	return size
+}
+
+func (b *mp4Box) marshal(buf *bytes.Buffer) {
+	size := b.size()
+	if size > math.MaxUint32 {
+		_ = binary.Write(buf, binary.BigEndian, uint32(1))
+		buf.WriteString(b.typ)
+		_ = binary.Write(buf, binary.BigEndian, size)
+	} else {
+		_ = binary.Write(buf, binary.BigEndian, uint32(size))
+		buf.WriteString(b.typ)
+	}
+	buf.Write(b.prefix)
+	buf.Write(b.data)
+	for _, c := range b.children {
+		c.marshal(buf)
+	}
+}
+
+func (b *mp4Box) walk(fn func(*mp4Box)) {
+	fn(b)
+	for _, c := range b.children {
+		c.walk(fn)
+	}
+}
+
+func newMP4MetaBox() *mp4Box {
+	hdlr := make([]byte, 25)
+	copy(hdlr[8:12], "mdir")
+	copy(hdlr[12:16], "appl")
+	return &mp4Box{
+		typ:    "meta",
+		prefix: make([]byte, 4),
+		children: []*mp4Box{
+			{typ: "hdlr", data: hdlr},
+			{typ: "ilst"},
+		},
+	}
+}
+
+func freeformName(box *mp4Box) string {
+	if box.typ != "----" {
+		return ""
+	}
+	children, err := parseMP4Boxes(box.data)
+	if err != nil {
+		return ""
+	}
+	for _, c := range children {
+		if c.typ == "name" && len(c.data) >= 4 {
+			return string(c.data[4:])
+		}
+	}
+	return ""
+}
+
+func newFreeformBox(name, value string) *mp4Box {
+	var payload bytes.Buffer
+
+	mean := &mp4Box{typ: "mean", data: append(make([]byte, 4), itunesFreeformMean...)}
+	nameBox := &mp4Box{typ: "name", data: append(make([]byte, 4), name...)}
+	dataBox := &mp4Box{typ: "data", data: append([]byte{0, 0, 0, 1, 0, 0, 0, 0}, value...)}
+
+	mean.marshal(&payload)
+	nameBox.marshal(&payload)
+	dataBox.marshal(&payload)
+
+	return &mp4Box{typ: "----", data: payload.Bytes()}
+}
+
+func adjustChunkOffsets(moov *mp4Box, after int64, delta int64) error {
+	var err error
+	moov.walk(func(b *mp4Box) {
+		if err != nil || (b.typ != "stco" && b.typ != "co64") || len(b.data) < 8 {
+			return
+		}
+
+		count := int(binary.BigEndian.Uint32(b.data[4:8]))
+		entrySize := 4
+		if b.typ == "co64" {
+			entrySize = 8
+		}
+		if len(b.data) < 8+count*entrySize {
+			err = fmt.Errorf("truncated %s box", b.typ)
+			return
+		}
+
+		for i := 0; i < count; i++ {
+			pos := 8 + i*entrySize
+			if b.typ == "co64" {
+				offset := int64(binary.BigEndian.Uint64(b.data[pos:]))
+				if offset >= after {
+					binary.BigEndian.PutUint64(b.data[pos:], uint64(offset+delta))
+				}
+				continue
+			}
+
+			offset := int64(binary.BigEndian.Uint32(b.data[pos:]))
+			if offset >= after {
+				adjusted := offset + delta
+				if adjusted < 0 || adjusted > math.MaxUint32 {
+					err = fmt.Errorf("chunk offset out of range")
+					return
+				}
+				binary.BigEndian.PutUint32(b.data[pos:], uint32(adjusted))
+			}
+		}
+	})
+	return err
+}
+
+func WriteMP4FreeformTags(filePath string, tags map[string]string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	topBoxes, err := readMP4TopBoxes(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse MP4 file: %w", err)
+	}
+
+	moovIdx := -1
+	for i, box := range topBoxes {
+		if box.typ == "moov" {
+			moovIdx = i
+			break
+		}
+	}
+	if moovIdx < 0 {
+		return fmt.Errorf("moov box not found")
+	}
+
+	moovTop := topBoxes[moovIdx]
+	moovData := make([]byte, moovTop.size)
+	if _, err := f.ReadAt(moovData, moovTop.offset); err != nil {
+		return fmt.Errorf("failed to read moov box: %w", err)
+	}
+
+	parsed, err := parseMP4Boxes(moovData)
+	if err != nil || len(parsed) != 1 {
+		return fmt.Errorf("failed to parse moov box: %w", err)
+	}
+	moov := parsed[0]
+
+	udta := moov.ensureChild("udta", func() *mp4Box { return &mp4Box{typ: "udta"} })
+	meta := udta.ensureChild("meta", newMP4MetaBox)
+	ilst := meta.ensureChild("ilst", func() *mp4Box { return &mp4Box{typ: "ilst"} })
+
+	kept := ilst.children[:0]
+	for _, item := range ilst.children {
+		name := freeformName(item)
+		replaced := false
+		for key := range tags {
+			if name != "" && strings.EqualFold(name, key) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			kept = append(kept, item)
+		}
+	}
+	ilst.children = kept
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ilst.children = append(ilst.children, newFreeformBox(key, tags[key]))
+	}
+
+	delta := int64(moov.size()) - moovTop.size
+	if err := adjustChunkOffsets(moov, moovTop.offset+moovTop.size, delta); err != nil {
+		return err
+	}
+
+	var moovBuf bytes.Buffer
+	moov.marshal(&moovBuf)
+
+	tmpPath := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".tmp" + filepath.Ext(filePath)
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	for i, box := range topBoxes {
+		if i == moovIdx {
+			_, err = out.Write(moovBuf.Bytes())
+		} else {
+			_, err = io.Copy(out, io.NewSectionReader(f, box.offset, box.size))
+		}
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("failed to write MP4 file: %w", err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write MP4 file: %w", err)
+	}
+	f.Close()
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace original file: %w", err)
+	}
+
+	return nil
+}
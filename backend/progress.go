@@ -15,10 +15,19 @@ const (
 	StatusCompleted   DownloadStatus = "completed"
 	StatusFailed      DownloadStatus = "failed"
 	StatusSkipped     DownloadStatus = "skipped"
+	StatusConverting  DownloadStatus = "converting"
+)
+
+type QueueItemKind string
+
+const (
+	QueueKindDownload QueueItemKind = "download"
+	QueueKindConvert  QueueItemKind = "convert"
 )
 
 type DownloadItem struct {
 	ID           string         `json:"id"`
+	Kind         QueueItemKind  `json:"kind"`
 	TrackName    string         `json:"track_name"`
 	ArtistName   string         `json:"artist_name"`
 	AlbumName    string         `json:"album_name"`
@@ -31,6 +40,8 @@ type DownloadItem struct {
 	EndTime      int64          `json:"end_time"`
 	ErrorMessage string         `json:"error_message"`
 	FilePath     string         `json:"file_path"`
+	InputFile    string         `json:"input_file,omitempty"`
+	Duration     float64        `json:"duration,omitempty"`
 }
 
 var (
@@ -190,6 +201,7 @@ func AddToQueue(id, trackName, artistName, albumName, spotifyID string) {
 
 	item := DownloadItem{
 		ID:         id,
+		Kind:       QueueKindDownload,
 		TrackName:  trackName,
 		ArtistName: artistName,
 		AlbumName:  albumName,
@@ -211,35 +223,66 @@ func AddToQueue(id, trackName, artistName, albumName, spotifyID string) {
 	sessionStartLock.Unlock()
 }
 
-func StartDownloadItem(id string) {
+func updateQueueItem(id string, update func(item *DownloadItem)) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
 	for i := range downloadQueue {
 		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusDownloading
-			downloadQueue[i].StartTime = time.Now().Unix()
-			downloadQueue[i].Progress = 0
+			update(&downloadQueue[i])
 			break
 		}
 	}
+}
 
-	currentItemLock.Lock()
-	currentItemID = id
-	currentItemLock.Unlock()
+func queueItemsOfKind(kind QueueItemKind) []DownloadItem {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	items := make([]DownloadItem, 0, len(downloadQueue))
+	for _, item := range downloadQueue {
+		if item.Kind == kind {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
-func UpdateItemProgress(id string, progress, speed float64) {
+func isActiveQueueStatus(status DownloadStatus) bool {
+	return status == StatusQueued || status == StatusDownloading || status == StatusConverting
+}
+
+func clearFinishedQueueItems(kind QueueItemKind) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Progress = progress
-			downloadQueue[i].Speed = speed
-			break
+	newQueue := make([]DownloadItem, 0)
+	for _, item := range downloadQueue {
+		if item.Kind != kind || isActiveQueueStatus(item.Status) {
+			newQueue = append(newQueue, item)
 		}
 	}
+	downloadQueue = newQueue
+}
+
+func StartDownloadItem(id string) {
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusDownloading
+		item.StartTime = time.Now().Unix()
+		item.Progress = 0
+		item.ErrorMessage = ""
+	})
+
+	currentItemLock.Lock()
+	currentItemID = id
+	currentItemLock.Unlock()
+}
+
+func UpdateItemProgress(id string, progress, speed float64) {
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Progress = progress
+		item.Speed = speed
+	})
 }
 
 func GetCurrentItemID() string {
@@ -249,59 +292,40 @@ func GetCurrentItemID() string {
 }
 
 func CompleteDownloadItem(id, filePath string, finalSize float64) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusCompleted
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			downloadQueue[i].Progress = finalSize
-			downloadQueue[i].TotalSize = finalSize
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusCompleted
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		item.Progress = finalSize
+		item.TotalSize = finalSize
 
-			totalDownloadedLock.Lock()
-			totalDownloaded += finalSize
-			totalDownloadedLock.Unlock()
-			break
-		}
-	}
+		totalDownloadedLock.Lock()
+		totalDownloaded += finalSize
+		totalDownloadedLock.Unlock()
+	})
 }
 
 func FailDownloadItem(id, errorMsg string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusFailed
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].ErrorMessage = errorMsg
-			break
-		}
-	}
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusFailed
+		item.EndTime = time.Now().Unix()
+		item.ErrorMessage = errorMsg
+	})
 }
 
 func SkipDownloadItem(id, filePath string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusSkipped
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			break
-		}
-	}
+	updateQueueItem(id, func(item *DownloadItem) {
+		item.Status = StatusSkipped
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+	})
 }
 
 func GetDownloadQueue() DownloadQueueInfo {
 
 	ResetSessionIfComplete()
 
-	downloadQueueLock.RLock()
-	defer downloadQueueLock.RUnlock()
+	queue := queueItemsOfKind(QueueKindDownload)
 
 	downloadingLock.RLock()
 	downloading := isDownloading
@@ -320,7 +344,7 @@ func GetDownloadQueue() DownloadQueueInfo {
 	sessionStartLock.RUnlock()
 
 	var queued, completed, failed, skipped int
-	for _, item := range downloadQueue {
+	for _, item := range queue {
 		switch item.Status {
 		case StatusQueued:
 			queued++
@@ -333,12 +357,9 @@ func GetDownloadQueue() DownloadQueueInfo {
 		}
 	}
 
-	queueCopy := make([]DownloadItem, len(downloadQueue))
-	copy(queueCopy, downloadQueue)
-
 	return DownloadQueueInfo{
 		IsDownloading:    downloading,
-		Queue:            queueCopy,
+		Queue:            queue,
 		CurrentSpeed:     speed,
 		TotalDownloaded:  total,
 		SessionStartTime: sessionStart,
@@ -350,21 +371,18 @@ func GetDownloadQueue() DownloadQueueInfo {
 }
 
 func ClearDownloadQueue() {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+	clearFinishedQueueItems(QueueKindDownload)
+}
 
+func ClearAllDownloads() {
+	downloadQueueLock.Lock()
 	newQueue := make([]DownloadItem, 0)
 	for _, item := range downloadQueue {
-		if item.Status == StatusQueued || item.Status == StatusDownloading {
+		if item.Kind != QueueKindDownload {
 			newQueue = append(newQueue, item)
 		}
 	}
 	downloadQueue = newQueue
-}
-
-func ClearAllDownloads() {
-	downloadQueueLock.Lock()
-	downloadQueue = []DownloadItem{}
 	downloadQueueLock.Unlock()
 
 	totalDownloadedLock.Lock()
@@ -388,7 +406,7 @@ func CancelAllQueuedItems() {
 	defer downloadQueueLock.Unlock()
 
 	for i := range downloadQueue {
-		if downloadQueue[i].Status == StatusQueued {
+		if downloadQueue[i].Kind == QueueKindDownload && downloadQueue[i].Status == StatusQueued {
 			downloadQueue[i].Status = StatusSkipped
 			downloadQueue[i].EndTime = time.Now().Unix()
 			downloadQueue[i].ErrorMessage = "Cancelled"
@@ -400,7 +418,7 @@ func ResetSessionIfComplete() {
 	downloadQueueLock.RLock()
 	hasActiveOrQueued := false
 	for _, item := range downloadQueue {
-		if item.Status == StatusQueued || item.Status == StatusDownloading {
+		if item.Kind == QueueKindDownload && isActiveQueueStatus(item.Status) {
 			hasActiveOrQueued = true
 			break
 		}
@@ -0,0 +1,341 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	QualityStatusGenuine    = "genuine"
+	QualityStatusSuspicious = "suspicious"
+	QualityStatusLikelyFake = "likely_fake"
+
+	qualityCheckSpectralCutoff = "spectral_cutoff"
+	qualityCheckBitDepth       = "bit_depth"
+	qualityCheckLowpass        = "lowpass"
+
+	suspiciousConfidence = 0.5
+	likelyFakeConfidence = 0.8
+)
+
+type QualityCheck struct {
+	Name       string  `json:"name"`
+	Detected   bool    `json:"detected"`
+	Confidence float64 `json:"confidence"`
+	Value      float64 `json:"value"`
+	Detail     string  `json:"detail"`
+}
+
+type QualityVerdict struct {
+	Status            string         `json:"status"`
+	Suspicious        bool           `json:"suspicious"`
+	Confidence        float64        `json:"confidence"`
+	SpectralCutoff    float64        `json:"spectral_cutoff"`
+	EffectiveBitDepth int            `json:"effective_bit_depth"`
+	Lowpass           string         `json:"lowpass,omitempty"`
+	Summary           string         `json:"summary"`
+	Checks            []QualityCheck `json:"checks"`
+}
+
+type sampleStats struct {
+	bitsPerSample int
+	nonZero       int64
+	trailingZeros [33]int64
+}
+
+func (s *sampleStats) add(sample int32) {
+	if sample == 0 {
+		return
+	}
+	tz := 0
+	for v := uint32(sample); v&1 == 0 && tz < 32; v >>= 1 {
+		tz++
+	}
+	s.trailingZeros[tz]++
+	s.nonZero++
+}
+
+type spectralCutoff struct {
+	frequency float64
+	dropDB    float64
+	fullBand  bool
+	valid     bool
+}
+
+func EvaluateQuality(result *AnalysisResult, stats *sampleStats) *QualityVerdict {
+	verdict := &QualityVerdict{
+		Status:            QualityStatusGenuine,
+		EffectiveBitDepth: int(result.BitsPerSample),
+	}
+
+	nyquist := float64(result.SampleRate) / 2
+	if result.Spectrum != nil {
+		nyquist = result.Spectrum.MaxFreq
+	}
+
+	cutoff := detectSpectralCutoff(result.Spectrum)
+	verdict.SpectralCutoff = nyquist
+	if cutoff.valid {
+		verdict.SpectralCutoff = cutoff.frequency
+	}
+
+	verdict.Checks = append(verdict.Checks,
+		spectralCutoffCheck(cutoff, nyquist, int(result.SampleRate)),
+		lowpassCheck(cutoff),
+		bitDepthCheck(stats, int(result.BitsPerSample)),
+	)
+
+	var reasons []string
+	for _, check := range verdict.Checks {
+		switch check.Name {
+		case qualityCheckBitDepth:
+			verdict.EffectiveBitDepth = int(check.Value)
+		case qualityCheckLowpass:
+			if check.Detected {
+				verdict.Lowpass = lowpassLabel(check.Value)
+			}
+		}
+
+		if !check.Detected {
+			continue
+		}
+		if check.Confidence > verdict.Confidence {
+			verdict.Confidence = check.Confidence
+		}
+		if check.Confidence >= suspiciousConfidence {
+			reasons = append(reasons, check.Detail)
+		}
+	}
+
+	switch {
+	case verdict.Confidence >= likelyFakeConfidence:
+		verdict.Status = QualityStatusLikelyFake
+		verdict.Suspicious = true
+	case verdict.Confidence >= suspiciousConfidence:
+		verdict.Status = QualityStatusSuspicious
+		verdict.Suspicious = true
+	}
+
+	if len(reasons) > 0 {
+		verdict.Summary = strings.Join(reasons, "; ")
+	} else {
+		verdict.Summary = "No signs of upsampling or lossy transcoding"
+	}
+
+	return verdict
+}
+
+func averageSpectrum(spectrum *SpectrumData) []float64 {
+	if spectrum == nil || len(spectrum.TimeSlices) == 0 || spectrum.FreqBins == 0 {
+		return nil
+	}
+
+	loudest := math.Inf(-1)
+	peaks := make([]float64, len(spectrum.TimeSlices))
+	for i, slice := range spectrum.TimeSlices {
+		peaks[i] = math.Inf(-1)
+		for _, m := range slice.Magnitudes {
+			if m > peaks[i] {
+				peaks[i] = m
+			}
+		}
+		if peaks[i] > loudest {
+			loudest = peaks[i]
+		}
+	}
+
+	avg := make([]float64, spectrum.FreqBins)
+	used := 0
+	for i, slice := range spectrum.TimeSlices {
+		if peaks[i] < loudest-60 || len(slice.Magnitudes) < spectrum.FreqBins {
+			continue
+		}
+		for j := range avg {
+			avg[j] += slice.Magnitudes[j]
+		}
+		used++
+	}
+	if used == 0 {
+		return nil
+	}
+
+	for j := range avg {
+		avg[j] /= float64(used)
+	}
+	return avg
+}
+
+func smoothSpectrum(values []float64, width int) []float64 {
+	half := width / 2
+	smoothed := make([]float64, len(values))
+	for i := range values {
+		smoothed[i] = meanRange(values, i-half, i+half+1)
+	}
+	return smoothed
+}
+
+func meanRange(values []float64, from, to int) float64 {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(values) {
+		to = len(values)
+	}
+	if to <= from {
+		return 0
+	}
+	var sum float64
+	for _, v := range values[from:to] {
+		sum += v
+	}
+	return sum / float64(to-from)
+}
+
+func detectSpectralCutoff(spectrum *SpectrumData) spectralCutoff {
+	avg := averageSpectrum(spectrum)
+	if avg == nil {
+		return spectralCutoff{}
+	}
+
+	binHz := spectrum.MaxFreq / float64(len(avg))
+	bins := func(hz float64) int {
+		return int(math.Round(hz / binHz))
+	}
+
+	smoothed := smoothSpectrum(avg, bins(200))
+
+	sorted := append([]float64(nil), smoothed...)
+	sort.Float64s(sorted)
+	floor := sorted[len(sorted)/20]
+	ref := meanRange(smoothed, bins(1000), bins(5000))
+	if ref-floor < 20 {
+		return spectralCutoff{}
+	}
+
+	threshold := floor + math.Max(10, (ref-floor)*0.5)
+	cutoffBin := 0
+	for i := len(smoothed) - 1; i >= 0; i-- {
+		if smoothed[i] > threshold {
+			cutoffBin = i
+			break
+		}
+	}
+
+	span := bins(500)
+	drop := meanRange(smoothed, cutoffBin-span, cutoffBin) - meanRange(smoothed, cutoffBin+1, cutoffBin+1+span)
+	frequency := float64(cutoffBin+1) * binHz
+
+	return spectralCutoff{
+		frequency: frequency,
+		dropDB:    drop,
+		fullBand:  frequency >= spectrum.MaxFreq*0.95,
+		valid:     true,
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func spectralCutoffCheck(cutoff spectralCutoff, nyquist float64, sampleRate int) QualityCheck {
+	check := QualityCheck{Name: qualityCheckSpectralCutoff, Value: nyquist}
+	if !cutoff.valid {
+		check.Detail = "Not enough signal to detect a spectral cutoff"
+		return check
+	}
+
+	check.Value = cutoff.frequency
+	if cutoff.fullBand {
+		check.Confidence = 0
+		check.Detail = fmt.Sprintf("Content extends to %.1f kHz", cutoff.frequency/1000)
+		return check
+	}
+
+	steepness := clamp01(cutoff.dropDB / 30)
+	missing := clamp01((nyquist - cutoff.frequency) / (nyquist * 0.2))
+
+	if sampleRate > 48000 && cutoff.frequency <= 24500 {
+		check.Detected = true
+		check.Confidence = clamp01(0.5 + 0.5*steepness)
+		check.Detail = fmt.Sprintf("Spectrum ends at %.1f kHz in a %.1f kHz file, likely upsampled from 44.1/48 kHz", cutoff.frequency/1000, float64(sampleRate)/1000)
+		return check
+	}
+
+	check.Detected = true
+	check.Confidence = steepness * missing
+	check.Detail = fmt.Sprintf("Spectral cutoff at %.1f kHz", cutoff.frequency/1000)
+	return check
+}
+
+func lowpassCheck(cutoff spectralCutoff) QualityCheck {
+	check := QualityCheck{Name: qualityCheckLowpass}
+	if !cutoff.valid || cutoff.fullBand {
+		return check
+	}
+
+	check.Value = cutoff.frequency
+	steepness := clamp01(cutoff.dropDB / 30)
+	if steepness < 0.5 {
+		return check
+	}
+
+	switch {
+	case cutoff.frequency >= 15000 && cutoff.frequency <= 17000:
+		check.Detected = true
+		check.Confidence = clamp01(0.6 + 0.4*steepness)
+		check.Detail = fmt.Sprintf("16 kHz brick-wall lowpass at %.1f kHz (typical of low-bitrate MP3)", cutoff.frequency/1000)
+	case cutoff.frequency >= 18500 && cutoff.frequency <= 19600 && steepness >= 0.8:
+		check.Detected = true
+		check.Confidence = clamp01(0.4 + 0.35*steepness)
+		check.Detail = fmt.Sprintf("19 kHz brick-wall lowpass at %.1f kHz (typical of MP3/AAC encoders)", cutoff.frequency/1000)
+	}
+
+	return check
+}
+
+func lowpassLabel(frequency float64) string {
+	if frequency <= 17000 {
+		return "16k"
+	}
+	return "19k"
+}
+
+func bitDepthCheck(stats *sampleStats, declared int) QualityCheck {
+	check := QualityCheck{Name: qualityCheckBitDepth, Value: float64(declared)}
+	if stats == nil || stats.nonZero == 0 || declared <= 8 {
+		check.Detail = "Not enough samples to check bit depth"
+		return check
+	}
+
+	zeroBits := 0
+	fraction := 1.0
+	for k := 1; k <= declared-8; k++ {
+		var count int64
+		for tz := k; tz < len(stats.trailingZeros); tz++ {
+			count += stats.trailingZeros[tz]
+		}
+		f := float64(count) / float64(stats.nonZero)
+		if f < 0.999 {
+			break
+		}
+		zeroBits = k
+		fraction = f
+	}
+
+	effective := declared - zeroBits
+	check.Value = float64(effective)
+	if zeroBits == 0 {
+		check.Detail = fmt.Sprintf("All %d bits carry information", declared)
+		return check
+	}
+
+	check.Detected = true
+	check.Confidence = clamp01((fraction - 0.999) / 0.001)
+	if zeroBits >= 4 {
+		check.Confidence = math.Max(check.Confidence, 0.9)
+	}
+	check.Detail = fmt.Sprintf("Effective bit depth is %d-bit in a %d-bit file", effective, declared)
+	return check
+}
@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type RenameCollisionStrategy string
+
+const (
+	CollisionAppendDiscTrack RenameCollisionStrategy = "disc_track"
+	CollisionAppendNumber    RenameCollisionStrategy = "number"
+	CollisionSkip            RenameCollisionStrategy = "skip"
+)
+
+func pathOccupied(target, source string) bool {
+	info, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	return !sameFile(info, source)
+}
+
+func isCaseOnlyRename(oldPath, newPath string) bool {
+	return oldPath != newPath && strings.EqualFold(oldPath, newPath)
+}
+
+func renameViaTemp(oldPath, newPath string) error {
+	tmpPath := fmt.Sprintf("%s.renaming-%d", oldPath, time.Now().UnixNano())
+	if err := os.Rename(oldPath, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, newPath); err != nil {
+		os.Rename(tmpPath, oldPath)
+		return err
+	}
+	return nil
+}
+
+func collisionSuffixPath(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+func renameSourcesMoving(previews []RenamePreview) map[string]bool {
+	sources := make(map[string]bool)
+	for _, p := range previews {
+		if p.Error == "" && p.NewPath != "" && p.NewPath != p.OldPath {
+			sources[strings.ToLower(p.OldPath)] = true
+		}
+	}
+	return sources
+}
+
+func resolveRenameCollisions(previews []RenamePreview, strategy RenameCollisionStrategy) {
+	original := append([]RenamePreview(nil), previews...)
+	vacated := renameSourcesMoving(previews)
+	for {
+		copy(previews, original)
+		resolveRenameCollisionsWith(previews, strategy, vacated)
+
+		moving := renameSourcesMoving(previews)
+		if len(moving) == len(vacated) {
+			return
+		}
+		vacated = moving
+	}
+}
+
+func resolveRenameCollisionsWith(previews []RenamePreview, strategy RenameCollisionStrategy, vacated map[string]bool) {
+	claimed := make(map[string]bool)
+	taken := func(path, source string) bool {
+		key := strings.ToLower(path)
+		return claimed[key] || (!vacated[key] && pathOccupied(path, source))
+	}
+
+	for i := range previews {
+		p := &previews[i]
+		if p.Error != "" || p.NewPath == "" {
+			continue
+		}
+
+		if taken(p.NewPath, p.OldPath) {
+			p.Collision = true
+			resolved := ""
+
+			switch strategy {
+			case CollisionSkip:
+			case CollisionAppendDiscTrack:
+				suffix := ""
+				if p.Metadata.DiscNumber > 0 && p.Metadata.TrackNumber > 0 {
+					suffix = fmt.Sprintf(" (%d-%02d)", p.Metadata.DiscNumber, p.Metadata.TrackNumber)
+				} else if p.Metadata.TrackNumber > 0 {
+					suffix = fmt.Sprintf(" (%02d)", p.Metadata.TrackNumber)
+				}
+				if candidate := collisionSuffixPath(p.NewPath, suffix); suffix != "" && !taken(candidate, p.OldPath) {
+					resolved = candidate
+					break
+				}
+				fallthrough
+			default:
+				for n := 2; ; n++ {
+					if candidate := collisionSuffixPath(p.NewPath, fmt.Sprintf(" (%d)", n)); !taken(candidate, p.OldPath) {
+						resolved = candidate
+						break
+					}
+				}
+			}
+
+			if resolved == "" {
+				p.Skipped = true
+				p.Error = "Name collides with another file"
+				continue
+			}
+			p.NewPath = resolved
+			if rel, err := filepath.Rel(filepath.Dir(p.OldPath), resolved); err == nil {
+				p.NewName = rel
+			}
+		}
+
+		claimed[strings.ToLower(p.NewPath)] = true
+	}
+}
+
+func moveFilesInOrder(ops []RenameOperation) []error {
+	errs := make([]error, len(ops))
+	sources := make([]string, len(ops))
+	pending := make(map[int]bool, len(ops))
+	for i, op := range ops {
+		sources[i] = op.OldPath
+		pending[i] = true
+	}
+
+	blocked := func(i int) bool {
+		target := strings.ToLower(ops[i].NewPath)
+		for j := range pending {
+			if j != i && strings.ToLower(sources[j]) == target {
+				return true
+			}
+		}
+		return false
+	}
+
+	finish := func(i int, err error) {
+		if err != nil && sources[i] != ops[i].OldPath {
+			os.Rename(sources[i], ops[i].OldPath)
+		}
+		errs[i] = err
+		delete(pending, i)
+	}
+
+	for len(pending) > 0 {
+		progressed := false
+		for i := range ops {
+			if !pending[i] || blocked(i) {
+				continue
+			}
+			progressed = true
+			if pathOccupied(ops[i].NewPath, sources[i]) {
+				finish(i, fmt.Errorf("file already exists"))
+				continue
+			}
+			finish(i, MoveFile(sources[i], ops[i].NewPath))
+		}
+		if progressed {
+			continue
+		}
+
+		for i := range ops {
+			if !pending[i] {
+				continue
+			}
+			tmpPath := fmt.Sprintf("%s.renaming-%d", sources[i], time.Now().UnixNano())
+			if err := os.Rename(sources[i], tmpPath); err != nil {
+				finish(i, err)
+			} else {
+				sources[i] = tmpPath
+			}
+			break
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,170 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveRenameCollisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		renames  [][2]string
+		strategy RenameCollisionStrategy
+		want     []string
+		skipped  []bool
+	}{
+		{
+			name:     "duplicate targets in batch",
+			existing: []string{"a.flac", "b.flac"},
+			renames:  [][2]string{{"a.flac", "x.flac"}, {"b.flac", "x.flac"}},
+			strategy: CollisionAppendNumber,
+			want:     []string{"x.flac", "x (2).flac"},
+		},
+		{
+			name:     "duplicate targets skipped",
+			existing: []string{"a.flac", "b.flac"},
+			renames:  [][2]string{{"a.flac", "x.flac"}, {"b.flac", "x.flac"}},
+			strategy: CollisionSkip,
+			want:     []string{"x.flac", "x.flac"},
+			skipped:  []bool{false, true},
+		},
+		{
+			name:     "duplicate targets case-insensitive",
+			existing: []string{"a.flac", "b.flac"},
+			renames:  [][2]string{{"a.flac", "X.flac"}, {"b.flac", "x.flac"}},
+			strategy: CollisionAppendNumber,
+			want:     []string{"X.flac", "x (2).flac"},
+		},
+		{
+			name:     "target exists outside batch",
+			existing: []string{"a.flac", "x.flac"},
+			renames:  [][2]string{{"a.flac", "x.flac"}},
+			strategy: CollisionAppendNumber,
+			want:     []string{"x (2).flac"},
+		},
+		{
+			name:     "shift into vacated name",
+			existing: []string{"a.flac", "b.flac"},
+			renames:  [][2]string{{"a.flac", "b.flac"}, {"b.flac", "c.flac"}},
+			strategy: CollisionAppendNumber,
+			want:     []string{"b.flac", "c.flac"},
+		},
+		{
+			name:     "swap",
+			existing: []string{"a.flac", "b.flac"},
+			renames:  [][2]string{{"a.flac", "b.flac"}, {"b.flac", "a.flac"}},
+			strategy: CollisionAppendNumber,
+			want:     []string{"b.flac", "a.flac"},
+		},
+		{
+			name:     "case-only rename",
+			existing: []string{"song.flac"},
+			renames:  [][2]string{{"song.flac", "Song.flac"}},
+			strategy: CollisionSkip,
+			want:     []string{"Song.flac"},
+		},
+		{
+			name:     "vacated source that is skipped stays occupied",
+			existing: []string{"a.flac", "b.flac", "c.flac"},
+			renames:  [][2]string{{"a.flac", "b.flac"}, {"b.flac", "c.flac"}},
+			strategy: CollisionSkip,
+			want:     []string{"b.flac", "c.flac"},
+			skipped:  []bool{true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			previews := make([]RenamePreview, len(tt.renames))
+			for i, r := range tt.renames {
+				previews[i] = RenamePreview{
+					OldPath: filepath.Join(dir, r[0]),
+					NewPath: filepath.Join(dir, r[1]),
+					NewName: r[1],
+				}
+			}
+
+			resolveRenameCollisions(previews, tt.strategy)
+
+			for i, p := range previews {
+				if got := filepath.Base(p.NewPath); got != tt.want[i] {
+					t.Errorf("preview %d: NewPath = %q, want %q", i, got, tt.want[i])
+				}
+				wantSkipped := tt.skipped != nil && tt.skipped[i]
+				if p.Skipped != wantSkipped {
+					t.Errorf("preview %d: Skipped = %v, want %v", i, p.Skipped, wantSkipped)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveFilesInOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		renames [][2]string
+		want    map[string]string
+	}{
+		{
+			name:    "shift",
+			renames: [][2]string{{"a.flac", "b.flac"}, {"b.flac", "c.flac"}},
+			want:    map[string]string{"b.flac": "a.flac", "c.flac": "b.flac"},
+		},
+		{
+			name:    "swap",
+			renames: [][2]string{{"a.flac", "b.flac"}, {"b.flac", "a.flac"}},
+			want:    map[string]string{"a.flac": "b.flac", "b.flac": "a.flac"},
+		},
+		{
+			name:    "rotation",
+			renames: [][2]string{{"a.flac", "b.flac"}, {"b.flac", "c.flac"}, {"c.flac", "a.flac"}},
+			want:    map[string]string{"a.flac": "c.flac", "b.flac": "a.flac", "c.flac": "b.flac"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ops := make([]RenameOperation, len(tt.renames))
+			for i, r := range tt.renames {
+				if err := os.WriteFile(filepath.Join(dir, r[0]), []byte(r[0]), 0644); err != nil {
+					t.Fatal(err)
+				}
+				ops[i] = RenameOperation{OldPath: filepath.Join(dir, r[0]), NewPath: filepath.Join(dir, r[1])}
+			}
+
+			for i, err := range moveFilesInOrder(ops) {
+				if err != nil {
+					t.Errorf("move %d: %v", i, err)
+				}
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Errorf("got %d files, want %d", len(entries), len(tt.want))
+			}
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					continue
+				}
+				if string(data) != content {
+					t.Errorf("%s contains %q, want %q", name, data, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	renameJournalBucket = "RenameJournal"
+	maxRenameBatches    = 200
+)
+
+var (
+	renameJournalDB     *bolt.DB
+	renameJournalDBLock sync.Mutex
+)
+
+type RenameOperation struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+}
+
+type RenameBatch struct {
+	ID         string            `json:"id"`
+	Timestamp  int64             `json:"timestamp"`
+	Operations []RenameOperation `json:"operations"`
+	Undone     bool              `json:"undone"`
+}
+
+type RenameBatchResult struct {
+	BatchID string         `json:"batch_id,omitempty"`
+	Results []RenameResult `json:"results"`
+}
+
+type RenameConflict struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	Reason  string `json:"reason"`
+}
+
+type UndoRenameResult struct {
+	BatchID   string           `json:"batch_id"`
+	Restored  int              `json:"restored"`
+	Conflicts []RenameConflict `json:"conflicts,omitempty"`
+	Errors    []string         `json:"errors,omitempty"`
+}
+
+func InitRenameJournalDB() error {
+	renameJournalDBLock.Lock()
+	defer renameJournalDBLock.Unlock()
+
+	if renameJournalDB != nil {
+		return nil
+	}
+
+	appDir, err := GetFFmpegDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(appDir); os.IsNotExist(err) {
+		os.MkdirAll(appDir, 0755)
+	}
+	dbPath := filepath.Join(appDir, "renames.db")
+
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(renameJournalBucket))
+		return err
+	})
+
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	renameJournalDB = db
+	return nil
+}
+
+func CloseRenameJournalDB() {
+	renameJournalDBLock.Lock()
+	defer renameJournalDBLock.Unlock()
+
+	if renameJournalDB != nil {
+		renameJournalDB.Close()
+		renameJournalDB = nil
+	}
+}
+
+func RecordRenameBatch(ops []RenameOperation) (string, error) {
+	if len(ops) == 0 {
+		return "", nil
+	}
+	if err := InitRenameJournalDB(); err != nil {
+		return "", err
+	}
+
+	var batchID string
+	err := renameJournalDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(renameJournalBucket))
+		seq, _ := b.NextSequence()
+
+		batch := RenameBatch{
+			ID:         fmt.Sprintf("%020d-%d", time.Now().UnixNano(), seq),
+			Timestamp:  time.Now().Unix(),
+			Operations: ops,
+		}
+		buf, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+
+		if excess := b.Stats().KeyN - maxRenameBatches + 1; excess > 0 {
+			c := b.Cursor()
+			for k, _ := c.First(); k != nil && excess > 0; k, _ = c.Next() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				excess--
+			}
+		}
+
+		batchID = batch.ID
+		return b.Put([]byte(batch.ID), buf)
+	})
+	return batchID, err
+}
+
+func ListRenameBatches(limit int) ([]RenameBatch, error) {
+	if err := InitRenameJournalDB(); err != nil {
+		return nil, err
+	}
+
+	var batches []RenameBatch
+	err := renameJournalDB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(renameJournalBucket)).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if limit > 0 && len(batches) >= limit {
+				break
+			}
+			var batch RenameBatch
+			if err := json.Unmarshal(v, &batch); err == nil {
+				batches = append(batches, batch)
+			}
+		}
+		return nil
+	})
+	return batches, err
+}
+
+func getRenameBatch(batchID string) (*RenameBatch, error) {
+	var batch *RenameBatch
+	err := renameJournalDB.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(renameJournalBucket)).Get([]byte(batchID))
+		if v == nil {
+			return fmt.Errorf("rename batch %s not found", batchID)
+		}
+		batch = &RenameBatch{}
+		return json.Unmarshal(v, batch)
+	})
+	return batch, err
+}
+
+func saveRenameBatch(batch *RenameBatch) error {
+	buf, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	return renameJournalDB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(renameJournalBucket)).Put([]byte(batch.ID), buf)
+	})
+}
+
+func renameConflicts(ops []RenameOperation) []RenameConflict {
+	vacated := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		vacated[strings.ToLower(op.NewPath)] = true
+	}
+
+	var conflicts []RenameConflict
+	for _, op := range ops {
+		if _, err := os.Stat(op.NewPath); err != nil {
+			conflicts = append(conflicts, RenameConflict{OldPath: op.OldPath, NewPath: op.NewPath, Reason: "Renamed file no longer exists"})
+			continue
+		}
+		if vacated[strings.ToLower(op.OldPath)] {
+			continue
+		}
+		if info, err := os.Stat(op.OldPath); err == nil && !sameFile(info, op.NewPath) {
+			conflicts = append(conflicts, RenameConflict{OldPath: op.OldPath, NewPath: op.NewPath, Reason: "Original path is now occupied"})
+		}
+	}
+	return conflicts
+}
+
+func sameFile(info os.FileInfo, path string) bool {
+	other, err := os.Stat(path)
+	return err == nil && os.SameFile(info, other)
+}
+
+func UndoRenameBatch(batchID string) (UndoRenameResult, error) {
+	result := UndoRenameResult{BatchID: batchID}
+	if err := InitRenameJournalDB(); err != nil {
+		return result, err
+	}
+
+	batch, err := getRenameBatch(batchID)
+	if err != nil {
+		return result, err
+	}
+	if batch.Undone {
+		return result, fmt.Errorf("rename batch has already been undone")
+	}
+
+	if result.Conflicts = renameConflicts(batch.Operations); len(result.Conflicts) > 0 {
+		return result, nil
+	}
+
+	reverse := make([]RenameOperation, len(batch.Operations))
+	for i, op := range batch.Operations {
+		reverse[len(reverse)-1-i] = RenameOperation{OldPath: op.NewPath, NewPath: op.OldPath}
+	}
+
+	var restored, remaining []RenameOperation
+	for i, err := range moveFilesInOrder(reverse) {
+		op := reverse[i]
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", op.OldPath, err))
+			remaining = append([]RenameOperation{{OldPath: op.NewPath, NewPath: op.OldPath}}, remaining...)
+			continue
+		}
+		restored = append(restored, op)
+		result.Restored++
+	}
+
+	if err := UpdateHistoryPaths(restored); err != nil {
+		fmt.Printf("Failed to update history paths: %v\n", err)
+	}
+
+	if len(remaining) > 0 {
+		batch.Operations = remaining
+	} else {
+		batch.Undone = true
+	}
+	if err := saveRenameBatch(batch); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func MoveFile(oldPath, newPath string) error {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if isCaseOnlyRename(oldPath, newPath) {
+		if err := renameViaTemp(oldPath, newPath); err != nil {
+			return err
+		}
+	} else if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	removeEmptyDirs(filepath.Dir(oldPath), commonDir(filepath.Dir(oldPath), filepath.Dir(newPath)))
+	return nil
+}
+
+func commonDir(a, b string) string {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	for {
+		rel, err := filepath.Rel(a, b)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
+
+func removeEmptyDirs(dir, stop string) {
+	dir, stop = filepath.Clean(dir), filepath.Clean(stop)
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
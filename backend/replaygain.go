@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+)
+
+const (
+	replayGainTrackGain = "REPLAYGAIN_TRACK_GAIN"
+	replayGainTrackPeak = "REPLAYGAIN_TRACK_PEAK"
+	replayGainAlbumGain = "REPLAYGAIN_ALBUM_GAIN"
+	replayGainAlbumPeak = "REPLAYGAIN_ALBUM_PEAK"
+)
+
+type ReplayGainResult struct {
+	FilePath  string          `json:"file_path"`
+	TrackGain string          `json:"track_gain,omitempty"`
+	TrackPeak string          `json:"track_peak,omitempty"`
+	AlbumGain string          `json:"album_gain,omitempty"`
+	AlbumPeak string          `json:"album_peak,omitempty"`
+	Loudness  *LoudnessResult `json:"loudness,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+func formatReplayGain(gain float64) string {
+	return fmt.Sprintf("%.2f dB", gain)
+}
+
+func formatReplayGainPeak(peak float64) string {
+	return fmt.Sprintf("%.6f", peak)
+}
+
+func ApplyReplayGain(filePaths []string, album bool) []ReplayGainResult {
+	results := make([]ReplayGainResult, len(filePaths))
+	loudness := make([]*LoudnessResult, len(filePaths))
+
+	var dirs []string
+	dirFiles := make(map[string][]int)
+
+	for i, filePath := range filePaths {
+		results[i].FilePath = filePath
+
+		measured, err := MeasureLoudness(filePath)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		loudness[i] = measured
+		results[i].Loudness = measured
+		results[i].TrackGain = formatReplayGain(measured.ReplayGain)
+		results[i].TrackPeak = formatReplayGainPeak(measured.TruePeakLinear)
+
+		dir := filepath.Dir(filePath)
+		if _, ok := dirFiles[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		dirFiles[dir] = append(dirFiles[dir], i)
+	}
+
+	if album {
+		for _, dir := range dirs {
+			tracks := make([]*LoudnessResult, 0, len(dirFiles[dir]))
+			for _, i := range dirFiles[dir] {
+				tracks = append(tracks, loudness[i])
+			}
+
+			albumLoudness, err := AlbumLoudness(tracks)
+			if err != nil {
+				fmt.Printf("Warning: failed to compute album gain for %s: %v\n", dir, err)
+				continue
+			}
+			for _, i := range dirFiles[dir] {
+				results[i].AlbumGain = formatReplayGain(albumLoudness.ReplayGain)
+				results[i].AlbumPeak = formatReplayGainPeak(albumLoudness.TruePeakLinear)
+			}
+		}
+	}
+
+	for i := range results {
+		if results[i].Error != "" {
+			continue
+		}
+
+		tags := map[string]string{
+			replayGainTrackGain: results[i].TrackGain,
+			replayGainTrackPeak: results[i].TrackPeak,
+		}
+		if results[i].AlbumGain != "" {
+			tags[replayGainAlbumGain] = results[i].AlbumGain
+			tags[replayGainAlbumPeak] = results[i].AlbumPeak
+		}
+
+		if err := WriteReplayGainTags(results[i].FilePath, tags); err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+
+	return results
+}
+
+func WriteReplayGainTags(filePath string, tags map[string]string) error {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch ext {
+	case ".flac":
+		return writeReplayGainFLAC(filePath, tags)
+	case ".mp3":
+		return writeReplayGainMP3(filePath, tags)
+	case ".m4a", ".mp4", ".aac":
+		mp4Tags := make(map[string]string, len(tags))
+		for key, value := range tags {
+			mp4Tags[strings.ToLower(key)] = value
+		}
+		return WriteMP4FreeformTags(filePath, mp4Tags)
+	default:
+		return fmt.Errorf("unsupported file format for ReplayGain: %s", ext)
+	}
+}
+
+func writeReplayGainFLAC(filePath string, tags map[string]string) error {
+	overwrite := make([]string, 0, len(tags))
+	for key := range tags {
+		overwrite = append(overwrite, key)
+	}
+	return EmbedMetadataWithOptions(filePath, Metadata{}, "", EmbedOptions{
+		Merge:     true,
+		Overwrite: overwrite,
+		Extra:     tags,
+	})
+}
+
+func writeReplayGainMP3(filePath string, tags map[string]string) error {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	var kept []id3v2.UserDefinedTextFrame
+	for _, frame := range tag.GetFrames("TXXX") {
+		udtf, ok := frame.(id3v2.UserDefinedTextFrame)
+		if !ok {
+			continue
+		}
+		if _, replaced := tags[strings.ToUpper(udtf.Description)]; !replaced {
+			kept = append(kept, udtf)
+		}
+	}
+
+	tag.DeleteFrames("TXXX")
+	for _, frame := range kept {
+		tag.AddUserDefinedTextFrame(frame)
+	}
+
+	for _, key := range []string{replayGainTrackGain, replayGainTrackPeak, replayGainAlbumGain, replayGainAlbumPeak} {
+		if value, ok := tags[key]; ok {
+			tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+				Encoding:    id3v2.EncodingUTF8,
+				Description: key,
+				Value:       value,
+			})
+		}
+	}
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 tags: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,540 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	retagMatchThreshold = 0.75
+	retagMaxCandidates  = 5
+	retagSearchLimit    = 10
+	retagAlbumMinFiles  = 3
+)
+
+var (
+	retagBracketPattern = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+	retagSuffixPattern  = regexp.MustCompile(`(?i)\s+-\s+(remaster(ed)?|live|mono|stereo|single|radio edit|bonus|demo).*$`)
+	retagArtistSplit    = regexp.MustCompile(`(?i)\s*(,|;|&|/|\bfeat\.?|\bft\.?|\bfeaturing\b|\bx\b)\s*`)
+)
+
+type RetagCandidate struct {
+	SpotifyID     string  `json:"spotify_id"`
+	Title         string  `json:"title"`
+	Artists       string  `json:"artists"`
+	Album         string  `json:"album"`
+	AlbumArtist   string  `json:"album_artist,omitempty"`
+	ReleaseDate   string  `json:"release_date,omitempty"`
+	TrackNumber   int     `json:"track_number,omitempty"`
+	TotalTracks   int     `json:"total_tracks,omitempty"`
+	DiscNumber    int     `json:"disc_number,omitempty"`
+	TotalDiscs    int     `json:"total_discs,omitempty"`
+	DurationMS    int     `json:"duration_ms"`
+	CoverURL      string  `json:"cover_url,omitempty"`
+	Copyright     string  `json:"copyright,omitempty"`
+	Publisher     string  `json:"publisher,omitempty"`
+	Score         float64 `json:"score"`
+	TitleScore    float64 `json:"title_score"`
+	ArtistScore   float64 `json:"artist_score"`
+	DurationScore float64 `json:"duration_score"`
+}
+
+type RetagProposal struct {
+	File       string              `json:"file"`
+	Current    map[TagField]string `json:"current"`
+	DurationMS int                 `json:"duration_ms"`
+	Source     string              `json:"source"`
+	Candidates []RetagCandidate    `json:"candidates"`
+	Matched    bool                `json:"matched"`
+	Error      string              `json:"error,omitempty"`
+}
+
+type RetagApplyItem struct {
+	File      string         `json:"file"`
+	Candidate RetagCandidate `json:"candidate"`
+	Cover     bool           `json:"cover"`
+}
+
+type RetagApplyResult struct {
+	File    string `json:"file"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type retagFile struct {
+	path       string
+	tags       map[TagField]string
+	title      string
+	artist     string
+	durationMS int
+}
+
+func normalizeMatchText(s string) string {
+	s = retagBracketPattern.ReplaceAllString(s, "")
+	s = retagSuffixPattern.ReplaceAllString(s, "")
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space && b.Len() > 0 {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func levenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+func tokenOverlap(a, b string) float64 {
+	ta, tb := strings.Fields(a), strings.Fields(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(tb))
+	for _, t := range tb {
+		set[t] = true
+	}
+	shared := 0
+	for _, t := range ta {
+		if set[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(max(len(ta), len(tb)))
+}
+
+func textSimilarity(a, b string) float64 {
+	a, b = normalizeMatchText(a), normalizeMatchText(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	return math.Max(levenshteinRatio(a, b), tokenOverlap(a, b))
+}
+
+func artistSimilarity(a, b string) float64 {
+	best := 0.0
+	for _, x := range retagArtistSplit.Split(a, -1) {
+		for _, y := range retagArtistSplit.Split(b, -1) {
+			best = math.Max(best, textSimilarity(x, y))
+		}
+	}
+	return best
+}
+
+func durationSimilarity(aMS, bMS int) (float64, bool) {
+	if aMS <= 0 || bMS <= 0 {
+		return 0, false
+	}
+	diff := math.Abs(float64(aMS-bMS)) / 1000
+	switch {
+	case diff <= 2:
+		return 1, true
+	case diff >= 15:
+		return 0, true
+	default:
+		return 1 - (diff-2)/13, true
+	}
+}
+
+func scoreRetagCandidate(file retagFile, c *RetagCandidate) {
+	c.TitleScore = textSimilarity(file.title, c.Title)
+	c.ArtistScore = 0.5
+	if file.artist != "" {
+		c.ArtistScore = artistSimilarity(file.artist, c.Artists)
+	}
+
+	weightTotal := 0.8
+	score := 0.5*c.TitleScore + 0.3*c.ArtistScore
+	if d, ok := durationSimilarity(file.durationMS, c.DurationMS); ok {
+		c.DurationScore = d
+		score += 0.2 * d
+		weightTotal += 0.2
+	}
+	c.Score = math.Round(score/weightTotal*1000) / 1000
+}
+
+func loadRetagFile(filePath string) (retagFile, error) {
+	tags, err := ReadTags(filePath)
+	if err != nil {
+		return retagFile{}, err
+	}
+	file := retagFile{path: filePath, tags: tags, title: tags[TagTitle], artist: tags[TagArtist]}
+	if file.title == "" {
+		file.title = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+	if seconds, err := GetAudioDuration(filePath); err == nil {
+		file.durationMS = int(seconds * 1000)
+	}
+	return file, nil
+}
+
+func candidateFromTrack(t TrackMetadata) RetagCandidate {
+	return RetagCandidate{
+		SpotifyID:   t.SpotifyID,
+		Title:       t.Name,
+		Artists:     t.Artists,
+		Album:       t.AlbumName,
+		AlbumArtist: t.AlbumArtist,
+		ReleaseDate: t.ReleaseDate,
+		TrackNumber: t.TrackNumber,
+		TotalTracks: t.TotalTracks,
+		DiscNumber:  t.DiscNumber,
+		TotalDiscs:  t.TotalDiscs,
+		DurationMS:  t.DurationMS,
+		CoverURL:    t.Images,
+		Copyright:   t.Copyright,
+		Publisher:   t.Publisher,
+	}
+}
+
+func candidateFromAlbumTrack(t AlbumTrackMetadata) RetagCandidate {
+	return RetagCandidate{
+		SpotifyID:   t.SpotifyID,
+		Title:       t.Name,
+		Artists:     t.Artists,
+		Album:       t.AlbumName,
+		AlbumArtist: t.AlbumArtist,
+		ReleaseDate: t.ReleaseDate,
+		TrackNumber: t.TrackNumber,
+		TotalTracks: t.TotalTracks,
+		DiscNumber:  t.DiscNumber,
+		TotalDiscs:  t.TotalDiscs,
+		DurationMS:  t.DurationMS,
+		CoverURL:    t.Images,
+	}
+}
+
+func fetchRetagTrack(ctx context.Context, client *SpotifyMetadataClient, spotifyID string) (*RetagCandidate, error) {
+	data, err := client.GetFilteredData(ctx, "https://open.spotify.com/track/"+spotifyID, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := data.(TrackResponse)
+	if !ok {
+		return nil, fmt.Errorf("unexpected track response")
+	}
+	candidate := candidateFromTrack(resp.Track)
+	return &candidate, nil
+}
+
+func rankCandidates(file retagFile, candidates []RetagCandidate) []RetagCandidate {
+	for i := range candidates {
+		scoreRetagCandidate(file, &candidates[i])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > retagMaxCandidates {
+		candidates = candidates[:retagMaxCandidates]
+	}
+	return candidates
+}
+
+func matchTrackBySearch(ctx context.Context, client *SpotifyMetadataClient, file retagFile) RetagProposal {
+	proposal := RetagProposal{File: file.path, Current: file.tags, DurationMS: file.durationMS, Source: "track"}
+
+	query := strings.TrimSpace(file.title + " " + file.artist)
+	results, err := client.SearchByType(ctx, query, "track", retagSearchLimit, 0)
+	if err != nil {
+		proposal.Error = err.Error()
+		return proposal
+	}
+
+	candidates := make([]RetagCandidate, 0, len(results))
+	for _, r := range results {
+		candidates = append(candidates, RetagCandidate{
+			SpotifyID:  r.ID,
+			Title:      r.Name,
+			Artists:    r.Artists,
+			Album:      r.AlbumName,
+			DurationMS: r.Duration,
+			CoverURL:   r.Images,
+		})
+	}
+	proposal.Candidates = rankCandidates(file, candidates)
+	if len(proposal.Candidates) == 0 {
+		proposal.Error = "No Spotify results"
+		return proposal
+	}
+
+	if full, err := fetchRetagTrack(ctx, client, proposal.Candidates[0].SpotifyID); err == nil {
+		full.Score, full.TitleScore, full.ArtistScore, full.DurationScore = proposal.Candidates[0].Score, proposal.Candidates[0].TitleScore, proposal.Candidates[0].ArtistScore, proposal.Candidates[0].DurationScore
+		proposal.Candidates[0] = *full
+	}
+	proposal.Matched = proposal.Candidates[0].Score >= retagMatchThreshold
+	return proposal
+}
+
+func assignAlbumTracks(files []retagFile, tracks []RetagCandidate) ([]RetagProposal, float64) {
+	type pairing struct {
+		file, track int
+		score       float64
+	}
+
+	scored := make([][]RetagCandidate, len(files))
+	var pairs []pairing
+	for i, f := range files {
+		scored[i] = make([]RetagCandidate, len(tracks))
+		copy(scored[i], tracks)
+		for j := range scored[i] {
+			scoreRetagCandidate(f, &scored[i][j])
+			pairs = append(pairs, pairing{file: i, track: j, score: scored[i][j].Score})
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return pairs[a].score > pairs[b].score
+	})
+
+	assigned := make([]int, len(files))
+	for i := range assigned {
+		assigned[i] = -1
+	}
+	trackUsed := make([]bool, len(tracks))
+	for _, p := range pairs {
+		if assigned[p.file] >= 0 || trackUsed[p.track] {
+			continue
+		}
+		assigned[p.file] = p.track
+		trackUsed[p.track] = true
+	}
+
+	proposals := make([]RetagProposal, 0, len(files))
+	total := 0.0
+	for i, f := range files {
+		proposal := RetagProposal{File: f.path, Current: f.tags, DurationMS: f.durationMS, Source: "album"}
+		var rest []RetagCandidate
+		for j, c := range scored[i] {
+			if j != assigned[i] {
+				rest = append(rest, c)
+			}
+		}
+		sort.SliceStable(rest, func(a, b int) bool {
+			return rest[a].Score > rest[b].Score
+		})
+
+		if assigned[i] >= 0 {
+			match := scored[i][assigned[i]]
+			proposal.Candidates = append([]RetagCandidate{match}, rest...)
+			proposal.Matched = match.Score >= retagMatchThreshold
+			total += match.Score
+		} else {
+			proposal.Candidates = rest
+		}
+		if len(proposal.Candidates) > retagMaxCandidates {
+			proposal.Candidates = proposal.Candidates[:retagMaxCandidates]
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, total
+}
+
+func matchFolderByAlbum(ctx context.Context, client *SpotifyMetadataClient, files []retagFile) []RetagProposal {
+	album, albumArtist := files[0].tags[TagAlbum], files[0].tags[TagAlbumArtist]
+	if albumArtist == "" {
+		albumArtist = files[0].artist
+	}
+	for _, f := range files[1:] {
+		if f.tags[TagAlbum] != album {
+			album = ""
+			break
+		}
+	}
+	if album == "" {
+		album = filepath.Base(filepath.Dir(files[0].path))
+	}
+
+	results, err := client.SearchByType(ctx, strings.TrimSpace(album+" "+albumArtist), "album", retagMaxCandidates, 0)
+	if err != nil || len(results) == 0 {
+		return nil
+	}
+
+	var best []RetagProposal
+	bestScore := 0.0
+	for _, result := range results {
+		if ctx.Err() != nil {
+			return nil
+		}
+		data, err := client.GetFilteredData(ctx, "https://open.spotify.com/album/"+result.ID, false, 0)
+		if err != nil {
+			continue
+		}
+		payload, ok := data.(*AlbumResponsePayload)
+		if !ok || len(payload.TrackList) == 0 {
+			continue
+		}
+
+		tracks := make([]RetagCandidate, 0, len(payload.TrackList))
+		for _, t := range payload.TrackList {
+			tracks = append(tracks, candidateFromAlbumTrack(t))
+		}
+		proposals, total := assignAlbumTracks(files, tracks)
+
+		if avg := total / float64(len(files)); avg > bestScore {
+			bestScore, best = avg, proposals
+		}
+	}
+
+	if bestScore < retagMatchThreshold {
+		return nil
+	}
+	return best
+}
+
+func MatchFilesForRetag(ctx context.Context, files []string) ([]RetagProposal, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files provided")
+	}
+
+	client := NewSpotifyMetadataClient()
+	byDir := make(map[string][]retagFile)
+	var dirs []string
+	var proposals []RetagProposal
+
+	for _, filePath := range files {
+		file, err := loadRetagFile(filePath)
+		if err != nil {
+			proposals = append(proposals, RetagProposal{File: filePath, Error: err.Error()})
+			continue
+		}
+		dir := filepath.Dir(filePath)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+
+	for _, dir := range dirs {
+		group := byDir[dir]
+		if len(group) >= retagAlbumMinFiles {
+			if matched := matchFolderByAlbum(ctx, client, group); matched != nil {
+				proposals = append(proposals, matched...)
+				continue
+			}
+		}
+		for _, file := range group {
+			if ctx.Err() != nil {
+				return proposals, ctx.Err()
+			}
+			proposals = append(proposals, matchTrackBySearch(ctx, client, file))
+			select {
+			case <-ctx.Done():
+				return proposals, ctx.Err()
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+
+	return proposals, nil
+}
+
+func (c RetagCandidate) metadata() Metadata {
+	return Metadata{
+		Title:       c.Title,
+		Artist:      c.Artists,
+		Album:       c.Album,
+		AlbumArtist: c.AlbumArtist,
+		Date:        c.ReleaseDate,
+		TrackNumber: c.TrackNumber,
+		TotalTracks: c.TotalTracks,
+		DiscNumber:  c.DiscNumber,
+		TotalDiscs:  c.TotalDiscs,
+		URL:         "https://open.spotify.com/track/" + c.SpotifyID,
+		Copyright:   c.Copyright,
+		Publisher:   c.Publisher,
+	}
+}
+
+func downloadRetagCover(coverClient *CoverClient, coverURL string) string {
+	tmp, err := os.CreateTemp("", "retag-cover-*.jpg")
+	if err != nil {
+		return ""
+	}
+	tmp.Close()
+	if err := coverClient.DownloadCoverToPath(coverURL, tmp.Name(), true); err != nil {
+		fmt.Printf("[Retag] Warning: Failed to download cover: %v\n", err)
+		os.Remove(tmp.Name())
+		return ""
+	}
+	return tmp.Name()
+}
+
+func ApplyRetag(ctx context.Context, items []RetagApplyItem, coverOpts CoverImageOptions) []RetagApplyResult {
+	client := NewSpotifyMetadataClient()
+	coverClient := NewCoverClient()
+	results := make([]RetagApplyResult, 0, len(items))
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		result := RetagApplyResult{File: item.File}
+		candidate := item.Candidate
+
+		if candidate.SpotifyID == "" {
+			result.Error = "No candidate selected"
+			results = append(results, result)
+			continue
+		}
+		if candidate.TrackNumber == 0 || candidate.AlbumArtist == "" || candidate.Copyright == "" || candidate.Publisher == "" {
+			if full, err := fetchRetagTrack(ctx, client, candidate.SpotifyID); err == nil {
+				candidate = *full
+			}
+		}
+
+		coverPath := ""
+		if item.Cover && candidate.CoverURL != "" {
+			coverPath = downloadRetagCover(coverClient, candidate.CoverURL)
+		}
+
+		err := MergeMetadataToFile(item.File, candidate.metadata(), coverPath, coverOpts)
+		if coverPath != "" {
+			os.Remove(coverPath)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Success = true
+		results = append(results, result)
+	}
+
+	return results
+}
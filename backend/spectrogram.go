@@ -0,0 +1,383 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	spectrogramWidth        = 1200
+	spectrogramHeight       = 700
+	spectrogramMarginLeft   = 80
+	spectrogramMarginRight  = 80
+	spectrogramMarginTop    = 90
+	spectrogramMarginBottom = 60
+	spectrogramRangeDB      = 90
+	spectrogramTextScale    = 2
+	spectrogramSuffix       = ".spectrogram.png"
+)
+
+var (
+	spectrogramBackground = color.RGBA{0, 0, 0, 255}
+	spectrogramText       = color.RGBA{255, 255, 255, 255}
+	spectrogramSubtext    = color.RGBA{170, 170, 170, 255}
+	spectrogramAxis       = color.RGBA{255, 255, 255, 255}
+	spectrogramGrid       = color.RGBA{60, 60, 60, 255}
+)
+
+type SpectrogramExportResult struct {
+	InputFile  string `json:"input_file"`
+	OutputFile string `json:"output_file"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+func spekColor(intensity float64) color.RGBA {
+	i := math.Max(0, math.Min(1, intensity))
+
+	band := func(lo, hi float64) float64 {
+		return (i - lo) / (hi - lo)
+	}
+	c := func(r, g, b float64) color.RGBA {
+		clamp := func(v float64) uint8 {
+			return uint8(math.Max(0, math.Min(255, math.Round(v))))
+		}
+		return color.RGBA{clamp(r), clamp(g), clamp(b), 255}
+	}
+
+	switch {
+	case i < 0.08:
+		t := band(0, 0.08)
+		return c(0, 0, t*80)
+	case i < 0.18:
+		t := band(0.08, 0.18)
+		return c(t*50, t*30, 80+t*175)
+	case i < 0.28:
+		t := band(0.18, 0.28)
+		return c(50+t*150, 30-t*30, 255-t*55)
+	case i < 0.40:
+		t := band(0.28, 0.40)
+		return c(200+t*55, 0, 200-t*200)
+	case i < 0.52:
+		t := band(0.40, 0.52)
+		return c(255, t*100, 0)
+	case i < 0.65:
+		t := band(0.52, 0.65)
+		return c(255, 100+t*80, 0)
+	case i < 0.78:
+		t := band(0.65, 0.78)
+		return c(255, 180+t*55, t*30)
+	case i < 0.90:
+		t := band(0.78, 0.90)
+		return c(255, 235+t*20, 30+t*100)
+	default:
+		t := band(0.90, 1)
+		return c(255, 255, 130+t*125)
+	}
+}
+
+func spectrumRange(spectrum *SpectrumData) (float64, float64) {
+	minDB, maxDB := math.Inf(1), math.Inf(-1)
+	for _, slice := range spectrum.TimeSlices {
+		for _, m := range slice.Magnitudes {
+			if m <= -200 || math.IsInf(m, 0) || math.IsNaN(m) {
+				continue
+			}
+			minDB = math.Min(minDB, m)
+			maxDB = math.Max(maxDB, m)
+		}
+	}
+	if math.IsInf(minDB, 0) {
+		return -120, 0
+	}
+	minDB = math.Max(minDB, maxDB-spectrogramRangeDB)
+	if maxDB <= minDB {
+		maxDB = minDB + 1
+	}
+	return minDB, maxDB
+}
+
+func frequencyStep(maxFreq float64) float64 {
+	switch {
+	case maxFreq <= 24000:
+		return 2000
+	case maxFreq <= 48000:
+		return 5000
+	case maxFreq <= 96000:
+		return 10000
+	default:
+		return 20000
+	}
+}
+
+func timeStep(duration float64) float64 {
+	switch {
+	case duration <= 60:
+		return 15
+	case duration <= 300:
+		return 30
+	default:
+		return 60
+	}
+}
+
+func formatDuration(seconds float64) string {
+	total := int(math.Round(seconds))
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+func spectrogramHeader(info *AnalysisResult) (string, string) {
+	title := filepath.Base(info.FilePath)
+
+	var parts []string
+	if info.Codec != "" {
+		parts = append(parts, strings.ToUpper(info.Codec))
+	}
+	parts = append(parts, fmt.Sprintf("%.1f kHz", float64(info.SampleRate)/1000))
+	if info.BitDepth != "" {
+		parts = append(parts, info.BitDepth)
+	} else if info.Lossy {
+		parts = append(parts, "Lossy")
+	}
+	parts = append(parts, fmt.Sprintf("%d ch", info.Channels), formatDuration(info.Duration))
+	if info.DR != nil {
+		parts = append(parts, fmt.Sprintf("DR%d", info.DR.Value))
+	}
+	if info.Loudness != nil {
+		parts = append(parts, fmt.Sprintf("%.1f LUFS", info.Loudness.IntegratedLoudness))
+	}
+	if v := info.Verdict; v != nil {
+		verdict := strings.ReplaceAll(v.Status, "_", " ")
+		if v.Lowpass != "" {
+			verdict += " (" + v.Lowpass + ")"
+		}
+		parts = append(parts, verdict)
+	}
+
+	return title, strings.Join(parts, " | ")
+}
+
+func RenderSpectrogram(spectrum *SpectrumData, info *AnalysisResult) (*image.RGBA, error) {
+	if spectrum == nil || len(spectrum.TimeSlices) == 0 || spectrum.FreqBins == 0 {
+		return nil, fmt.Errorf("no spectrum data")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, spectrogramWidth, spectrogramHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{spectrogramBackground}, image.Point{}, draw.Src)
+
+	plot := image.Rect(
+		spectrogramMarginLeft,
+		spectrogramMarginTop,
+		spectrogramWidth-spectrogramMarginRight,
+		spectrogramHeight-spectrogramMarginBottom,
+	)
+	plotWidth, plotHeight := plot.Dx(), plot.Dy()
+
+	minDB, maxDB := spectrumRange(spectrum)
+	slices := spectrum.TimeSlices
+	bins := spectrum.FreqBins
+
+	for x := 0; x < plotWidth; x++ {
+		slice := slices[x*len(slices)/plotWidth]
+		for y := 0; y < plotHeight; y++ {
+			lo := (plotHeight - 1 - y) * bins / plotHeight
+			hi := (plotHeight - y) * bins / plotHeight
+			if hi <= lo {
+				hi = lo + 1
+			}
+			level := math.Inf(-1)
+			for k := lo; k < hi && k < len(slice.Magnitudes); k++ {
+				level = math.Max(level, slice.Magnitudes[k])
+			}
+			if math.IsInf(level, -1) {
+				continue
+			}
+			img.SetRGBA(plot.Min.X+x, plot.Min.Y+y, spekColor((level-minDB)/(maxDB-minDB)))
+		}
+	}
+
+	drawSpectrogramAxes(img, plot, spectrum)
+	drawColorBar(img, plot)
+
+	if info != nil {
+		title, details := spectrogramHeader(info)
+		maxWidth := spectrogramWidth - spectrogramMarginLeft
+		drawText(img, spectrogramMarginLeft/2, 16, fitText(title, maxWidth, spectrogramTextScale), spectrogramTextScale, spectrogramText)
+		drawText(img, spectrogramMarginLeft/2, 44, fitText(details, maxWidth, spectrogramTextScale), spectrogramTextScale, spectrogramSubtext)
+	}
+
+	return img, nil
+}
+
+func drawSpectrogramAxes(img *image.RGBA, plot image.Rectangle, spectrum *SpectrumData) {
+	const scale = spectrogramTextScale
+	charHeight := glyphHeight * scale
+
+	maxFreq := spectrum.MaxFreq
+	if maxFreq <= 0 {
+		maxFreq = float64(spectrum.SampleRate) / 2
+	}
+	step := frequencyStep(maxFreq)
+	for f := 0.0; f <= maxFreq; f += step {
+		y := plot.Max.Y - 1 - int(f/maxFreq*float64(plot.Dy()-1))
+		for x := plot.Min.X; x < plot.Max.X; x += 4 {
+			img.SetRGBA(x, y, spectrogramGrid)
+		}
+		for x := plot.Min.X - 6; x < plot.Min.X; x++ {
+			img.SetRGBA(x, y, spectrogramAxis)
+		}
+		label := fmt.Sprintf("%dk", int(f/1000))
+		drawText(img, plot.Min.X-10-textWidth(label, scale), y-charHeight/2, label, scale, spectrogramText)
+	}
+	drawText(img, plot.Min.X-10-textWidth("Hz", scale), plot.Min.Y-charHeight-8, "Hz", scale, spectrogramSubtext)
+
+	duration := spectrum.Duration
+	if duration > 0 {
+		step := timeStep(duration)
+		for t := 0.0; t <= duration; t += step {
+			x := plot.Min.X + int(t/duration*float64(plot.Dx()-1))
+			for y := plot.Max.Y; y < plot.Max.Y+6; y++ {
+				img.SetRGBA(x, y, spectrogramAxis)
+			}
+			label := formatDuration(t)
+			drawText(img, x-textWidth(label, scale)/2, plot.Max.Y+10, label, scale, spectrogramText)
+		}
+	}
+	axisTitle := "Time"
+	drawText(img, plot.Min.X+(plot.Dx()-textWidth(axisTitle, scale))/2, plot.Max.Y+10+charHeight+8, axisTitle, scale, spectrogramSubtext)
+
+	for x := plot.Min.X - 1; x <= plot.Max.X; x++ {
+		img.SetRGBA(x, plot.Min.Y-1, spectrogramAxis)
+		img.SetRGBA(x, plot.Max.Y, spectrogramAxis)
+	}
+	for y := plot.Min.Y - 1; y <= plot.Max.Y; y++ {
+		img.SetRGBA(plot.Min.X-1, y, spectrogramAxis)
+		img.SetRGBA(plot.Max.X, y, spectrogramAxis)
+	}
+}
+
+func drawColorBar(img *image.RGBA, plot image.Rectangle) {
+	const barWidth = 16
+	const scale = spectrogramTextScale
+
+	x0 := plot.Max.X + 20
+	for y := plot.Min.Y; y < plot.Max.Y; y++ {
+		c := spekColor(1 - float64(y-plot.Min.Y)/float64(plot.Dy()-1))
+		for x := x0; x < x0+barWidth; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	center := x0 + barWidth/2
+	drawText(img, center-textWidth("High", scale)/2, plot.Min.Y-glyphHeight*scale-8, "High", scale, spectrogramSubtext)
+	drawText(img, center-textWidth("Low", scale)/2, plot.Max.Y+10, "Low", scale, spectrogramSubtext)
+}
+
+func spectrogramOutputPath(filePath, outputDir string) string {
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)) + spectrogramSuffix
+	if outputDir == "" {
+		return filepath.Join(filepath.Dir(filePath), name)
+	}
+	return filepath.Join(outputDir, name)
+}
+
+func ExportSpectrogramPNG(filePath, outputPath string) (string, error) {
+	result, err := AnalyzeTrack(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	spectrum := result.Spectrum
+	if spectrum == nil {
+		spectrum, err = AnalyzeSpectrum(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to analyze spectrum: %w", err)
+		}
+	}
+
+	img, err := RenderSpectrogram(spectrum, result)
+	if err != nil {
+		return "", err
+	}
+
+	if outputPath == "" {
+		outputPath = spectrogramOutputPath(filePath, "")
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create image: %w", err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		os.Remove(outputPath)
+		return "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write image: %w", err)
+	}
+
+	return outputPath, nil
+}
+
+func ExportSpectrograms(ctx context.Context, filePaths []string, outputDir string) []SpectrogramExportResult {
+	results := make([]SpectrogramExportResult, len(filePaths))
+	for i, filePath := range filePaths {
+		results[i].InputFile = filePath
+	}
+
+	workers := runtime.NumCPU()
+	if workers > maxBatchAnalysisWorkers {
+		workers = maxBatchAnalysisWorkers
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := &results[i]
+				out, err := ExportSpectrogramPNG(res.InputFile, spectrogramOutputPath(res.InputFile, outputDir))
+				if err != nil {
+					res.Error = err.Error()
+					continue
+				}
+				res.OutputFile = out
+				res.Success = true
+			}
+		}()
+	}
+
+dispatch:
+	for i := range results {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := range results {
+		if !results[i].Success && results[i].Error == "" {
+			results[i].Error = "cancelled"
+		}
+	}
+	return results
+}
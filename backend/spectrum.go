@@ -2,18 +2,29 @@ package backend
 
 import (
 	"fmt"
+	"io"
 	"math"
-	"math/cmplx"
+	"math/bits"
+)
 
-	"github.com/mewkiz/flac"
+const (
+	defaultFFTSize       = 8192
+	defaultNumTimeSlices = 300
+	minSpectrumMagnitude = 1e-10
 )
 
 type SpectrumData struct {
+	TimeSlices []TimeSlice       `json:"time_slices"`
+	Channels   []ChannelSpectrum `json:"channels,omitempty"`
+	SampleRate int               `json:"sample_rate"`
+	FreqBins   int               `json:"freq_bins"`
+	Duration   float64           `json:"duration"`
+	MaxFreq    float64           `json:"max_freq"`
+}
+
+type ChannelSpectrum struct {
+	Channel    int         `json:"channel"`
 	TimeSlices []TimeSlice `json:"time_slices"`
-	SampleRate int         `json:"sample_rate"`
-	FreqBins   int         `json:"freq_bins"`
-	Duration   float64     `json:"duration"`
-	MaxFreq    float64     `json:"max_freq"`
 }
 
 type TimeSlice struct {
@@ -21,161 +32,295 @@ type TimeSlice struct {
 	Magnitudes []float64 `json:"magnitudes"`
 }
 
+type SpectrumOptions struct {
+	FFTSize       int  `json:"fft_size"`
+	NumTimeSlices int  `json:"num_time_slices"`
+	PerChannel    bool `json:"per_channel"`
+}
+
+func DefaultSpectrumOptions() SpectrumOptions {
+	return SpectrumOptions{
+		FFTSize:       defaultFFTSize,
+		NumTimeSlices: defaultNumTimeSlices,
+	}
+}
+
 func AnalyzeSpectrum(filepath string) (*SpectrumData, error) {
+	return AnalyzeSpectrumWithOptions(filepath, DefaultSpectrumOptions())
+}
 
-	stream, err := flac.ParseFile(filepath)
+func AnalyzeSpectrumWithOptions(filepath string, opts SpectrumOptions) (*SpectrumData, error) {
+	if opts.FFTSize <= 0 {
+		opts.FFTSize = defaultFFTSize
+	}
+	if opts.FFTSize&(opts.FFTSize-1) != 0 {
+		return nil, fmt.Errorf("FFT size must be a power of two: %d", opts.FFTSize)
+	}
+	if opts.NumTimeSlices <= 0 {
+		opts.NumTimeSlices = defaultNumTimeSlices
+	}
+
+	dec, err := OpenPCMDecoder(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC: %w", err)
+		return nil, err
 	}
-	defer stream.Close()
+	defer dec.Close()
 
-	info := stream.Info
-	sampleRate := int(info.SampleRate)
-	channels := int(info.NChannels)
+	format := dec.Format()
+	reader := newPCMWindowReader(dec)
+	return calculateSpectrum(reader, format.TotalSamples, format.SampleRate, format.Channels, opts)
+}
 
-	samples, err := readSamples(stream, channels)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read samples: %w", err)
+func windowPositions(totalSamples uint64, fftSize, numTimeSlices int) []uint64 {
+	if totalSamples < uint64(fftSize) {
+		return nil
 	}
 
-	if len(samples) == 0 {
-		return nil, fmt.Errorf("no audio samples found")
+	samplesPerSlice := totalSamples / uint64(numTimeSlices)
+	if samplesPerSlice < uint64(fftSize) {
+		samplesPerSlice = uint64(fftSize)
+		numTimeSlices = int(totalSamples / uint64(fftSize))
 	}
 
-	return calculateSpectrum(samples, sampleRate), nil
+	positions := make([]uint64, 0, numTimeSlices)
+	for i := 0; i < numTimeSlices; i++ {
+		start := uint64(i) * samplesPerSlice
+		if start+uint64(fftSize) > totalSamples {
+			break
+		}
+		positions = append(positions, start)
+	}
+	return positions
 }
 
-func readSamples(stream *flac.Stream, channels int) ([]float64, error) {
-	var allSamples []float64
-	maxSamples := 10 * 1024 * 1024
+func calculateSpectrum(reader *pcmWindowReader, totalSamples uint64, sampleRate, channels int, opts SpectrumOptions) (*SpectrumData, error) {
+	fftSize := opts.FFTSize
+	freqBins := fftSize / 2
 
-	for {
-		frame, err := stream.ParseNext()
-		if err != nil {
+	var positions []uint64
+	if totalSamples > 0 {
+		positions = windowPositions(totalSamples, fftSize, opts.NumTimeSlices)
+	} else {
+		for i := 0; i < opts.NumTimeSlices; i++ {
+			positions = append(positions, uint64(i*fftSize*4))
+		}
+	}
 
-			break
+	plan := newFFTPlan(fftSize)
+	window := make([][]float64, channels)
+	for ch := range window {
+		window[ch] = make([]float64, fftSize)
+	}
+	mono := make([]float64, fftSize)
+
+	result := &SpectrumData{
+		TimeSlices: make([]TimeSlice, 0, len(positions)),
+		SampleRate: sampleRate,
+		FreqBins:   freqBins,
+		Duration:   float64(totalSamples) / float64(sampleRate),
+		MaxFreq:    float64(sampleRate) / 2.0,
+	}
+	if opts.PerChannel {
+		result.Channels = make([]ChannelSpectrum, channels)
+		for ch := range result.Channels {
+			result.Channels[ch] = ChannelSpectrum{Channel: ch, TimeSlices: make([]TimeSlice, 0, len(positions))}
 		}
+	}
 
-		for i := 0; i < frame.Subframes[0].NSamples; i++ {
-			var sample float64
+	for _, start := range positions {
+		n, err := reader.ReadWindow(start, window)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read samples: %w", err)
+		}
+		if n < fftSize {
+			break
+		}
 
+		for i := 0; i < fftSize; i++ {
+			var sum float64
 			for ch := 0; ch < channels; ch++ {
-				sample += float64(frame.Subframes[ch].Samples[i])
+				sum += window[ch][i]
 			}
-			sample /= float64(channels)
+			mono[i] = sum / float64(channels)
+		}
 
-			allSamples = append(allSamples, sample)
+		t := float64(start) / float64(sampleRate)
+		result.TimeSlices = append(result.TimeSlices, TimeSlice{Time: t, Magnitudes: plan.MagnitudesDB(mono)})
 
-			if len(allSamples) >= maxSamples {
-				return allSamples, nil
+		if opts.PerChannel {
+			for ch := 0; ch < channels; ch++ {
+				result.Channels[ch].TimeSlices = append(result.Channels[ch].TimeSlices, TimeSlice{Time: t, Magnitudes: plan.MagnitudesDB(window[ch])})
 			}
 		}
 	}
 
-	return allSamples, nil
+	if totalSamples == 0 && len(result.TimeSlices) > 0 {
+		last := result.TimeSlices[len(result.TimeSlices)-1]
+		result.Duration = last.Time + float64(fftSize)/float64(sampleRate)
+	}
+
+	if len(result.TimeSlices) == 0 {
+		return nil, fmt.Errorf("no audio samples found")
+	}
+
+	return result, nil
 }
 
-func calculateSpectrum(samples []float64, sampleRate int) *SpectrumData {
-	fftSize := 8192
-	numTimeSlices := 300
+type pcmWindowReader struct {
+	dec        PCMDecoder
+	seeker     SeekablePCMDecoder
+	seekGap    uint64
+	samples    [][]int32
+	frameStart uint64
+	nextSample uint64
+}
 
-	duration := float64(len(samples)) / float64(sampleRate)
+func newPCMWindowReader(dec PCMDecoder) *pcmWindowReader {
+	seeker, _ := dec.(SeekablePCMDecoder)
+	return &pcmWindowReader{
+		dec:     dec,
+		seeker:  seeker,
+		seekGap: uint64(dec.Format().SampleRate) * 2,
+	}
+}
 
-	samplesPerSlice := len(samples) / numTimeSlices
-	if samplesPerSlice < fftSize {
-		samplesPerSlice = fftSize
-		numTimeSlices = len(samples) / fftSize
+func (r *pcmWindowReader) next() error {
+	samples, err := r.dec.ReadFrames()
+	if err != nil {
+		r.samples = nil
+		return err
 	}
+	r.samples = samples
+	r.frameStart = r.nextSample
+	if len(samples) > 0 {
+		r.nextSample += uint64(len(samples[0]))
+	}
+	return nil
+}
 
-	timeSlices := make([]TimeSlice, 0, numTimeSlices)
-	freqBins := fftSize / 2
-	maxFreq := float64(sampleRate) / 2.0
+func (r *pcmWindowReader) position(target uint64) error {
+	if r.samples != nil && target >= r.frameStart && target < r.nextSample {
+		return nil
+	}
 
-	for i := 0; i < numTimeSlices; i++ {
-		startIdx := i * samplesPerSlice
-		if startIdx+fftSize > len(samples) {
-			break
+	if r.seeker != nil && (target < r.nextSample || target >= r.nextSample+r.seekGap) {
+		start, err := r.seeker.Seek(target)
+		if err != nil {
+			return err
 		}
+		r.samples = nil
+		r.nextSample = start
+	} else if target < r.nextSample {
+		return fmt.Errorf("cannot seek backwards to sample %d", target)
+	}
 
-		window := samples[startIdx : startIdx+fftSize]
-
-		windowedSamples := applyHannWindow(window)
-
-		spectrum := fft(windowedSamples)
+	for {
+		if err := r.next(); err != nil {
+			return err
+		}
+		if target < r.nextSample {
+			return nil
+		}
+	}
+}
 
-		magnitudes := make([]float64, freqBins)
-		for j := 0; j < freqBins; j++ {
-			magnitude := cmplx.Abs(spectrum[j])
+func (r *pcmWindowReader) ReadWindow(start uint64, dst [][]float64) (int, error) {
+	size := len(dst[0])
+	filled := 0
 
-			if magnitude < 1e-10 {
-				magnitude = 1e-10
+	for filled < size {
+		target := start + uint64(filled)
+		if err := r.position(target); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return filled, nil
 			}
-			magnitudes[j] = 20 * math.Log10(magnitude)
+			return filled, err
 		}
 
-		timeSlice := TimeSlice{
-			Time:       float64(startIdx) / float64(sampleRate),
-			Magnitudes: magnitudes,
+		offset := int(target - r.frameStart)
+		n := int(r.nextSample-r.frameStart) - offset
+		if n > size-filled {
+			n = size - filled
 		}
-		timeSlices = append(timeSlices, timeSlice)
-	}
 
-	return &SpectrumData{
-		TimeSlices: timeSlices,
-		SampleRate: sampleRate,
-		FreqBins:   freqBins,
-		Duration:   duration,
-		MaxFreq:    maxFreq,
+		for ch := 0; ch < len(dst) && ch < len(r.samples); ch++ {
+			samples := r.samples[ch][offset : offset+n]
+			out := dst[ch][filled : filled+n]
+			for i, s := range samples {
+				out[i] = float64(s)
+			}
+		}
+		filled += n
 	}
+
+	return filled, nil
 }
 
-func applyHannWindow(samples []float64) []float64 {
-	n := len(samples)
-	windowed := make([]float64, n)
+type fftPlan struct {
+	size    int
+	window  []float64
+	rev     []int
+	twiddle []complex128
+	buf     []complex128
+}
 
-	for i := 0; i < n; i++ {
-		window := 0.5 * (1.0 - math.Cos(2.0*math.Pi*float64(i)/float64(n-1)))
-		windowed[i] = samples[i] * window
+func newFFTPlan(size int) *fftPlan {
+	p := &fftPlan{
+		size:    size,
+		window:  make([]float64, size),
+		rev:     make([]int, size),
+		twiddle: make([]complex128, size/2),
+		buf:     make([]complex128, size),
 	}
 
-	return windowed
-}
+	for i := 0; i < size; i++ {
+		p.window[i] = 0.5 * (1.0 - math.Cos(2.0*math.Pi*float64(i)/float64(size-1)))
+	}
 
-func fft(samples []float64) []complex128 {
-	n := len(samples)
+	logN := bits.TrailingZeros(uint(size))
+	for i := 0; i < size; i++ {
+		p.rev[i] = int(bits.Reverse(uint(i)) >> (bits.UintSize - logN))
+	}
 
-	x := make([]complex128, n)
-	for i := 0; i < n; i++ {
-		x[i] = complex(samples[i], 0)
+	for k := 0; k < size/2; k++ {
+		angle := -2 * math.Pi * float64(k) / float64(size)
+		p.twiddle[k] = complex(math.Cos(angle), math.Sin(angle))
 	}
 
-	return fftRecursive(x)
+	return p
 }
 
-func fftRecursive(x []complex128) []complex128 {
-	n := len(x)
-
-	if n <= 1 {
-		return x
+func (p *fftPlan) Transform(samples []float64) []complex128 {
+	n := p.size
+	for i := 0; i < n; i++ {
+		p.buf[p.rev[i]] = complex(samples[i]*p.window[i], 0)
 	}
 
-	even := make([]complex128, n/2)
-	odd := make([]complex128, n/2)
-
-	for i := 0; i < n/2; i++ {
-		even[i] = x[2*i]
-		odd[i] = x[2*i+1]
+	for length := 2; length <= n; length <<= 1 {
+		half := length >> 1
+		step := n / length
+		for start := 0; start < n; start += length {
+			for k := 0; k < half; k++ {
+				t := p.twiddle[k*step] * p.buf[start+k+half]
+				u := p.buf[start+k]
+				p.buf[start+k] = u + t
+				p.buf[start+k+half] = u - t
+			}
+		}
 	}
 
-	evenFFT := fftRecursive(even)
-	oddFFT := fftRecursive(odd)
+	return p.buf
+}
 
-	result := make([]complex128, n)
-	for k := 0; k < n/2; k++ {
-		t := cmplx.Exp(complex(0, -2*math.Pi*float64(k)/float64(n))) * oddFFT[k]
-		result[k] = evenFFT[k] + t
-		result[k+n/2] = evenFFT[k] - t
+func (p *fftPlan) MagnitudesDB(samples []float64) []float64 {
+	spectrum := p.Transform(samples)
+	magnitudes := make([]float64, p.size/2)
+	for j := range magnitudes {
+		magnitude := math.Hypot(real(spectrum[j]), imag(spectrum[j]))
+		if magnitude < minSpectrumMagnitude {
+			magnitude = minSpectrumMagnitude
+		}
+		magnitudes[j] = 20 * math.Log10(magnitude)
 	}
-
-	return result
+	return magnitudes
 }
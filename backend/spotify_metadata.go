@@ -687,15 +687,10 @@ func (c *SpotifyMetadataClient) fetchPlaylist(ctx context.Context, playlistID st
 	return &result, nil
 }
 
-func (c *SpotifyMetadataClient) fetchArtistDiscography(ctx context.Context, parsed spotifyURI) (*apiArtistResponse, error) {
-	client := NewSpotifyClient()
-	if err := client.Initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
-	}
-
-	overviewPayload := map[string]interface{}{
+func artistOverviewPayload(artistID string) map[string]interface{} {
+	return map[string]interface{}{
 		"variables": map[string]interface{}{
-			"uri":    fmt.Sprintf("spotify:artist:%s", parsed.ID),
+			"uri":    fmt.Sprintf("spotify:artist:%s", artistID),
 			"locale": "",
 		},
 		"operationName": "queryArtistOverview",
@@ -706,8 +701,61 @@ func (c *SpotifyMetadataClient) fetchArtistDiscography(ctx context.Context, pars
 			},
 		},
 	}
+}
+
+func (c *SpotifyMetadataClient) FetchArtistInfo(ctx context.Context, artistID string) (*ArtistInfoMetadata, error) {
+	if artistID == "" {
+		return nil, fmt.Errorf("artist ID is required")
+	}
+
+	client := NewSpotifyClient()
+	if err := client.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
+	}
+
+	data, err := client.Query(artistOverviewPayload(artistID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query artist overview: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	jsonData, err := json.Marshal(FilterArtist(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal filtered data: %w", err)
+	}
+
+	var raw apiArtistResponse
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal to apiArtistResponse: %w", err)
+	}
+
+	return &ArtistInfoMetadata{
+		Name:        raw.Name,
+		Followers:   raw.Stats.Followers,
+		Genres:      []string{},
+		Images:      raw.Avatar,
+		Header:      raw.Header,
+		Gallery:     raw.Gallery,
+		ExternalURL: fmt.Sprintf("https://open.spotify.com/artist/%s", raw.ID),
+		Biography:   raw.Profile.Biography,
+		Verified:    raw.Profile.Verified,
+		Listeners:   raw.Stats.Listeners,
+		Rank:        raw.Stats.Rank,
+	}, nil
+}
+
+func (c *SpotifyMetadataClient) fetchArtistDiscography(ctx context.Context, parsed spotifyURI) (*apiArtistResponse, error) {
+	client := NewSpotifyClient()
+	if err := client.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize spotify client: %w", err)
+	}
 
-	data, err := client.Query(overviewPayload)
+	data, err := client.Query(artistOverviewPayload(parsed.ID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query artist overview: %w", err)
 	}
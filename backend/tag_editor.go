@@ -0,0 +1,494 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+type TagField string
+
+const (
+	TagTitle       TagField = "title"
+	TagArtist      TagField = "artist"
+	TagAlbum       TagField = "album"
+	TagAlbumArtist TagField = "album_artist"
+	TagTrackNumber TagField = "track_number"
+	TagTotalTracks TagField = "total_tracks"
+	TagDiscNumber  TagField = "disc_number"
+	TagTotalDiscs  TagField = "total_discs"
+	TagDate        TagField = "date"
+	TagGenre       TagField = "genre"
+	TagISRC        TagField = "isrc"
+	TagComment     TagField = "comment"
+)
+
+var TagFields = []TagField{
+	TagTitle, TagArtist, TagAlbum, TagAlbumArtist,
+	TagTrackNumber, TagTotalTracks, TagDiscNumber, TagTotalDiscs,
+	TagDate, TagGenre, TagISRC, TagComment,
+}
+
+type TagEditAction string
+
+const (
+	TagKeep    TagEditAction = "keep"
+	TagSet     TagEditAction = "set"
+	TagClear   TagEditAction = "clear"
+	TagReplace TagEditAction = "replace"
+)
+
+type TagEdit struct {
+	Field       TagField      `json:"field"`
+	Action      TagEditAction `json:"action"`
+	Value       string        `json:"value,omitempty"`
+	Pattern     string        `json:"pattern,omitempty"`
+	Replacement string        `json:"replacement,omitempty"`
+}
+
+type TagEditRequest struct {
+	Files  []string  `json:"files"`
+	Edits  []TagEdit `json:"edits"`
+	DryRun bool      `json:"dry_run"`
+}
+
+type TagEditResult struct {
+	File    string              `json:"file"`
+	Before  map[TagField]string `json:"before"`
+	After   map[TagField]string `json:"after"`
+	Changed []TagField          `json:"changed,omitempty"`
+	Success bool                `json:"success"`
+	Error   string              `json:"error,omitempty"`
+}
+
+var tagNumberFields = map[TagField]bool{
+	TagTrackNumber: true,
+	TagTotalTracks: true,
+	TagDiscNumber:  true,
+	TagTotalDiscs:  true,
+}
+
+var vorbisTagKeys = map[string]TagField{
+	"TITLE":        TagTitle,
+	"ARTIST":       TagArtist,
+	"ALBUM":        TagAlbum,
+	"ALBUMARTIST":  TagAlbumArtist,
+	"ALBUM ARTIST": TagAlbumArtist,
+	"TRACKNUMBER":  TagTrackNumber,
+	"TOTALTRACKS":  TagTotalTracks,
+	"TRACKTOTAL":   TagTotalTracks,
+	"DISCNUMBER":   TagDiscNumber,
+	"TOTALDISCS":   TagTotalDiscs,
+	"DISCTOTAL":    TagTotalDiscs,
+	"DATE":         TagDate,
+	"YEAR":         TagDate,
+	"GENRE":        TagGenre,
+	"ISRC":         TagISRC,
+	"COMMENT":      TagComment,
+}
+
+var m4aClearKeys = map[TagField][]string{
+	TagTitle:       {"title"},
+	TagArtist:      {"artist"},
+	TagAlbum:       {"album"},
+	TagAlbumArtist: {"album_artist"},
+	TagTrackNumber: {"track"},
+	TagDiscNumber:  {"disc"},
+	TagDate:        {"date"},
+	TagGenre:       {"genre"},
+	TagISRC:        {"isrc"},
+	TagComment:     {"comment"},
+}
+
+func splitNumberPair(value string) (string, string) {
+	first, second, _ := strings.Cut(value, "/")
+	return strings.TrimSpace(first), strings.TrimSpace(second)
+}
+
+func setNumberPair(tags map[TagField]string, value string, number, total TagField) {
+	n, t := splitNumberPair(value)
+	if n != "" {
+		tags[number] = n
+	}
+	if t != "" && tags[total] == "" {
+		tags[total] = t
+	}
+}
+
+func ReadTags(filePath string) (map[TagField]string, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		return readFlacTags(filePath)
+	case ".mp3":
+		return readMp3Tags(filePath)
+	case ".m4a":
+		return readM4aTags(filePath)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
+	}
+}
+
+func readFlacTags(filePath string) (map[TagField]string, error) {
+	f, err := parseFlacMetadata(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+
+	tags := make(map[TagField]string)
+	for _, block := range f.Meta {
+		if block.Type != flac.VorbisComment {
+			continue
+		}
+		cmt, err := flacvorbis.ParseFromMetaDataBlock(*block)
+		if err != nil {
+			continue
+		}
+		for _, comment := range cmt.Comments {
+			key, value, ok := strings.Cut(comment, "=")
+			if !ok {
+				continue
+			}
+			field, managed := vorbisTagKeys[strings.ToUpper(key)]
+			if !managed {
+				continue
+			}
+			switch {
+			case field == TagTrackNumber:
+				setNumberPair(tags, value, TagTrackNumber, TagTotalTracks)
+			case field == TagDiscNumber:
+				setNumberPair(tags, value, TagDiscNumber, TagTotalDiscs)
+			case tags[field] == "":
+				tags[field] = value
+			case field == TagArtist || field == TagGenre:
+				tags[field] += "; " + value
+			}
+		}
+		break
+	}
+	return tags, nil
+}
+
+func readMp3Tags(filePath string) (map[TagField]string, error) {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	tags := map[TagField]string{
+		TagTitle:  tag.Title(),
+		TagArtist: tag.Artist(),
+		TagAlbum:  tag.Album(),
+		TagDate:   tag.Year(),
+		TagGenre:  tag.Genre(),
+	}
+	if frame := tag.GetTextFrame("TPE2"); frame.Text != "" {
+		tags[TagAlbumArtist] = frame.Text
+	}
+	if frame := tag.GetTextFrame("TSRC"); frame.Text != "" {
+		tags[TagISRC] = frame.Text
+	}
+	setNumberPair(tags, tag.GetTextFrame(tag.CommonID("Track number/Position in set")).Text, TagTrackNumber, TagTotalTracks)
+	setNumberPair(tags, tag.GetTextFrame(tag.CommonID("Part of a set")).Text, TagDiscNumber, TagTotalDiscs)
+	for _, frame := range tag.GetFrames(tag.CommonID("Comments")) {
+		if comment, ok := frame.(id3v2.CommentFrame); ok && comment.Text != "" {
+			tags[TagComment] = comment.Text
+			break
+		}
+	}
+
+	for field, value := range tags {
+		if value == "" {
+			delete(tags, field)
+		}
+	}
+	return tags, nil
+}
+
+func readM4aTags(filePath string) (map[TagField]string, error) {
+	probed, err := probeAudioTags(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[TagField]string)
+	for key, value := range probed {
+		switch key {
+		case "title":
+			tags[TagTitle] = value
+		case "artist":
+			tags[TagArtist] = value
+		case "album":
+			tags[TagAlbum] = value
+		case "album_artist", "albumartist":
+			tags[TagAlbumArtist] = value
+		case "date", "year":
+			if len(value) > len(tags[TagDate]) {
+				tags[TagDate] = value
+			}
+		case "track":
+			setNumberPair(tags, value, TagTrackNumber, TagTotalTracks)
+		case "disc":
+			setNumberPair(tags, value, TagDiscNumber, TagTotalDiscs)
+		case "genre":
+			tags[TagGenre] = value
+		case "isrc":
+			tags[TagISRC] = value
+		case "comment":
+			tags[TagComment] = value
+		}
+	}
+	return tags, nil
+}
+
+func compileTagEdits(edits []TagEdit) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, len(edits))
+	for i, edit := range edits {
+		known := false
+		for _, field := range TagFields {
+			if field == edit.Field {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown tag field: %s", edit.Field)
+		}
+		switch edit.Action {
+		case "", TagKeep, TagClear:
+		case TagSet:
+			if tagNumberFields[edit.Field] && edit.Value != "" {
+				if _, err := strconv.Atoi(edit.Value); err != nil {
+					return nil, fmt.Errorf("%s must be a number", edit.Field)
+				}
+			}
+		case TagReplace:
+			re, err := regexp.Compile(edit.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", edit.Field, err)
+			}
+			patterns[i] = re
+		default:
+			return nil, fmt.Errorf("unknown tag action: %s", edit.Action)
+		}
+	}
+	return patterns, nil
+}
+
+func applyTagEdits(before map[TagField]string, edits []TagEdit, patterns []*regexp.Regexp) (map[TagField]string, error) {
+	after := make(map[TagField]string, len(before))
+	for field, value := range before {
+		after[field] = value
+	}
+
+	for i, edit := range edits {
+		switch edit.Action {
+		case TagSet:
+			after[edit.Field] = edit.Value
+		case TagClear:
+			delete(after, edit.Field)
+		case TagReplace:
+			after[edit.Field] = patterns[i].ReplaceAllString(after[edit.Field], edit.Replacement)
+		}
+		if after[edit.Field] == "" {
+			delete(after, edit.Field)
+		} else if tagNumberFields[edit.Field] {
+			if _, err := strconv.Atoi(after[edit.Field]); err != nil {
+				return nil, fmt.Errorf("%s must be a number, got %q", edit.Field, after[edit.Field])
+			}
+		}
+	}
+	return after, nil
+}
+
+func metadataFromTags(tags map[TagField]string) Metadata {
+	number := func(field TagField) int {
+		n, _ := strconv.Atoi(tags[field])
+		return n
+	}
+	return Metadata{
+		Title:       tags[TagTitle],
+		Artist:      tags[TagArtist],
+		Album:       tags[TagAlbum],
+		AlbumArtist: tags[TagAlbumArtist],
+		Date:        tags[TagDate],
+		TrackNumber: number(TagTrackNumber),
+		TotalTracks: number(TagTotalTracks),
+		DiscNumber:  number(TagDiscNumber),
+		TotalDiscs:  number(TagTotalDiscs),
+		Genre:       tags[TagGenre],
+		ISRC:        tags[TagISRC],
+		Comment:     tags[TagComment],
+	}
+}
+
+func writeTags(filePath string, before, after map[TagField]string) error {
+	metadata := metadataFromTags(after)
+
+	var cleared []TagField
+	for field := range before {
+		if after[field] == "" {
+			cleared = append(cleared, field)
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		touched := make(map[TagField]bool)
+		for _, field := range TagFields {
+			if before[field] != after[field] {
+				touched[field] = true
+			}
+		}
+		for _, pair := range [][2]TagField{{TagTrackNumber, TagTotalTracks}, {TagDiscNumber, TagTotalDiscs}} {
+			if touched[pair[0]] || touched[pair[1]] {
+				touched[pair[0]], touched[pair[1]] = true, true
+			}
+		}
+
+		changed := make(map[TagField]string, len(touched))
+		for field := range touched {
+			if after[field] != "" {
+				changed[field] = after[field]
+			}
+		}
+		var overwrite []string
+		for key, field := range vorbisTagKeys {
+			if touched[field] {
+				overwrite = append(overwrite, key)
+			}
+		}
+		return EmbedMetadataWithOptions(filePath, metadataFromTags(changed), "", EmbedOptions{Merge: true, Overwrite: overwrite})
+	case ".mp3":
+		userFrames, err := readMp3UserFrames(filePath)
+		if err != nil {
+			return err
+		}
+		if err := embedMetadataToMP3(filePath, metadata, "", CoverImageOptions{}); err != nil {
+			return err
+		}
+		return finishMp3Tags(filePath, userFrames, cleared)
+	case ".m4a":
+		var clearKeys []string
+		for _, field := range cleared {
+			clearKeys = append(clearKeys, m4aClearKeys[field]...)
+		}
+		return writeM4AMetadata(filePath, metadata, "", CoverImageOptions{}, clearKeys)
+	default:
+		return fmt.Errorf("unsupported file format: %s", filepath.Ext(filePath))
+	}
+}
+
+func readMp3UserFrames(filePath string) ([]id3v2.UserDefinedTextFrame, error) {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	var frames []id3v2.UserDefinedTextFrame
+	for _, frame := range tag.GetFrames("TXXX") {
+		if udtf, ok := frame.(id3v2.UserDefinedTextFrame); ok {
+			frames = append(frames, udtf)
+		}
+	}
+	return frames, nil
+}
+
+func finishMp3Tags(filePath string, userFrames []id3v2.UserDefinedTextFrame, cleared []TagField) error {
+	if len(userFrames) == 0 && len(cleared) == 0 {
+		return nil
+	}
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	for _, frame := range userFrames {
+		tag.AddUserDefinedTextFrame(frame)
+	}
+
+	for _, field := range cleared {
+		switch field {
+		case TagTitle:
+			tag.DeleteFrames(tag.CommonID("Title/Songname/Content description"))
+		case TagArtist:
+			tag.DeleteFrames(tag.CommonID("Lead artist/Lead performer/Soloist/Performing group"))
+		case TagAlbum:
+			tag.DeleteFrames(tag.CommonID("Album/Movie/Show title"))
+		case TagAlbumArtist:
+			tag.DeleteFrames("TPE2")
+		case TagTrackNumber:
+			tag.DeleteFrames(tag.CommonID("Track number/Position in set"))
+		case TagDiscNumber:
+			tag.DeleteFrames(tag.CommonID("Part of a set"))
+		case TagDate:
+			tag.DeleteFrames("TYER")
+			tag.DeleteFrames("TDRC")
+		case TagGenre:
+			tag.DeleteFrames(tag.CommonID("Content type"))
+		case TagISRC:
+			tag.DeleteFrames("TSRC")
+		case TagComment:
+			tag.DeleteFrames(tag.CommonID("Comments"))
+		}
+	}
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 tags: %w", err)
+	}
+	return nil
+}
+
+func EditTags(req TagEditRequest) ([]TagEditResult, error) {
+	patterns, err := compileTagEdits(req.Edits)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TagEditResult, 0, len(req.Files))
+	for _, filePath := range req.Files {
+		result := TagEditResult{File: filePath}
+
+		before, err := ReadTags(filePath)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.Before = before
+
+		after, err := applyTagEdits(before, req.Edits, patterns)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.After = after
+
+		for _, field := range TagFields {
+			if before[field] != after[field] {
+				result.Changed = append(result.Changed, field)
+			}
+		}
+
+		if !req.DryRun && len(result.Changed) > 0 {
+			if err := writeTags(filePath, before, after); err != nil {
+				result.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+		}
+
+		result.Success = true
+		results = append(results, result)
+	}
+	return results, nil
+}
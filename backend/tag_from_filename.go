@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var filenamePatternFields = map[string]TagField{
+	"title":        TagTitle,
+	"artist":       TagArtist,
+	"album":        TagAlbum,
+	"album_artist": TagAlbumArtist,
+	"track":        TagTrackNumber,
+	"total_tracks": TagTotalTracks,
+	"disc":         TagDiscNumber,
+	"total_discs":  TagTotalDiscs,
+	"year":         TagDate,
+	"date":         TagDate,
+	"genre":        TagGenre,
+}
+
+var (
+	filenamePatternToken = regexp.MustCompile(`\{([a-z_*]+)\}`)
+	filenamePatternSpace = regexp.MustCompile(`\s+`)
+)
+
+type filenamePattern struct {
+	re       *regexp.Regexp
+	fields   []TagField
+	segments int
+}
+
+func compileFilenamePattern(pattern string) (*filenamePattern, error) {
+	pattern = strings.ReplaceAll(strings.TrimSpace(pattern), "\\", "/")
+	if pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+
+	compiled := &filenamePattern{segments: strings.Count(pattern, "/") + 1}
+	var b strings.Builder
+	b.WriteString("^")
+
+	literal := func(text string) {
+		b.WriteString(filenamePatternSpace.ReplaceAllLiteralString(regexp.QuoteMeta(text), `\s+`))
+	}
+
+	last := 0
+	for _, loc := range filenamePatternToken.FindAllStringSubmatchIndex(pattern, -1) {
+		literal(pattern[last:loc[0]])
+		last = loc[1]
+
+		name := pattern[loc[2]:loc[3]]
+		if name == "*" || name == "ignore" {
+			b.WriteString(`.*?`)
+			continue
+		}
+		field, ok := filenamePatternFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown pattern variable: {%s}", name)
+		}
+		if tagNumberFields[field] {
+			b.WriteString(`(\d+)`)
+		} else if name == "year" {
+			b.WriteString(`(\d{4})`)
+		} else {
+			b.WriteString(`(.+?)`)
+		}
+		compiled.fields = append(compiled.fields, field)
+	}
+	literal(pattern[last:])
+	b.WriteString("$")
+
+	if len(compiled.fields) == 0 {
+		return nil, fmt.Errorf("pattern has no variables")
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	compiled.re = re
+	return compiled, nil
+}
+
+func (p *filenamePattern) parse(filePath string) (map[TagField]string, bool) {
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	parts := []string{name}
+	dir := filepath.Dir(filePath)
+	for len(parts) < p.segments {
+		base := filepath.Base(dir)
+		if base == dir || base == "." || base == string(filepath.Separator) {
+			return nil, false
+		}
+		parts = append([]string{base}, parts...)
+		dir = filepath.Dir(dir)
+	}
+
+	match := p.re.FindStringSubmatch(strings.Join(parts, "/"))
+	if match == nil {
+		return nil, false
+	}
+
+	tags := make(map[TagField]string)
+	for i, field := range p.fields {
+		value := strings.TrimSpace(match[i+1])
+		if tagNumberFields[field] {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			value = strconv.Itoa(n)
+		}
+		if value != "" {
+			tags[field] = value
+		}
+	}
+	return tags, true
+}
+
+func TagsFromFilenames(files []string, pattern string, onlyEmpty bool, dryRun bool) ([]TagEditResult, error) {
+	compiled, err := compileFilenamePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TagEditResult, 0, len(files))
+	for _, filePath := range files {
+		parsed, ok := compiled.parse(filePath)
+		if !ok {
+			results = append(results, TagEditResult{File: filePath, Error: "Filename does not match pattern"})
+			continue
+		}
+
+		current, err := ReadTags(filePath)
+		if err != nil {
+			results = append(results, TagEditResult{File: filePath, Error: err.Error()})
+			continue
+		}
+
+		var edits []TagEdit
+		for _, field := range TagFields {
+			value, ok := parsed[field]
+			if !ok || (onlyEmpty && current[field] != "") {
+				continue
+			}
+			edits = append(edits, TagEdit{Field: field, Action: TagSet, Value: value})
+		}
+
+		edited, err := EditTags(TagEditRequest{Files: []string{filePath}, Edits: edits, DryRun: dryRun})
+		if err != nil {
+			results = append(results, TagEditResult{File: filePath, Error: err.Error()})
+			continue
+		}
+		results = append(results, edited...)
+	}
+	return results, nil
+}
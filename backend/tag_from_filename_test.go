@@ -0,0 +1,115 @@
+package backend
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFilenamePatternRoundTrip(t *testing.T) {
+	data := TemplateData{
+		Title:       "All The Stars",
+		Artist:      "Kendrick Lamar, SZA",
+		Album:       "Black Panther",
+		AlbumArtist: "Kendrick Lamar",
+		ReleaseDate: "2018-02-09",
+		Track:       3,
+		Disc:        2,
+		TotalTracks: 14,
+		TotalDiscs:  2,
+	}
+
+	tests := []struct {
+		name               string
+		format             string
+		includeTrackNumber bool
+		want               map[TagField]string
+	}{
+		{
+			name:   "title",
+			format: "{title}",
+			want:   map[TagField]string{TagTitle: "All The Stars"},
+		},
+		{
+			name:   "padded track and title",
+			format: "{track}. {title}",
+			want:   map[TagField]string{TagTrackNumber: "3", TagTitle: "All The Stars"},
+		},
+		{
+			name:   "artist and title",
+			format: "{artist} - {title}",
+			want:   map[TagField]string{TagArtist: "Kendrick Lamar, SZA", TagTitle: "All The Stars"},
+		},
+		{
+			name:   "disc and track",
+			format: "{disc}-{track} {title}",
+			want:   map[TagField]string{TagDiscNumber: "2", TagTrackNumber: "3", TagTitle: "All The Stars"},
+		},
+		{
+			name:   "track of total",
+			format: "{track} of {total_tracks} {title}",
+			want:   map[TagField]string{TagTrackNumber: "3", TagTotalTracks: "14", TagTitle: "All The Stars"},
+		},
+		{
+			name:   "folders",
+			format: "{album_artist}/{album}/{track}. {title}",
+			want: map[TagField]string{
+				TagAlbumArtist: "Kendrick Lamar",
+				TagAlbum:       "Black Panther",
+				TagTrackNumber: "3",
+				TagTitle:       "All The Stars",
+			},
+		},
+		{
+			name:   "year in folder",
+			format: "{year} - {album}/{track} {title}",
+			want:   map[TagField]string{TagDate: "2018", TagAlbum: "Black Panther", TagTrackNumber: "3", TagTitle: "All The Stars"},
+		},
+		{
+			name:               "legacy format with track number",
+			format:             "title-artist",
+			includeTrackNumber: true,
+			want:               map[TagField]string{TagTrackNumber: "3", TagTitle: "All The Stars", TagArtist: "Kendrick Lamar, SZA"},
+		},
+	}
+
+	root := filepath.Join(string(filepath.Separator), "music")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(root, BuildTrackFilename(tt.format, data, tt.includeTrackNumber, ".flac"))
+
+			pattern, err := compileFilenamePattern(ResolveFilenameFormat(tt.format, tt.includeTrackNumber))
+			if err != nil {
+				t.Fatalf("compileFilenamePattern: %v", err)
+			}
+
+			got, ok := pattern.parse(filePath)
+			if !ok {
+				t.Fatalf("pattern did not match %q", filePath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse(%q) = %v, want %v", filePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilenamePatternErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"empty", "  "},
+		{"no variables", "just text"},
+		{"only wildcards", "{*} - {ignore}"},
+		{"unknown variable", "{title} - {bogus}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileFilenamePattern(tt.pattern); err == nil {
+				t.Errorf("compileFilenamePattern(%q) succeeded, want error", tt.pattern)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package backend
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type TemplateData struct {
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Album       string `json:"album"`
+	AlbumArtist string `json:"album_artist"`
+	ReleaseDate string `json:"release_date"`
+	Playlist    string `json:"playlist"`
+	Creator     string `json:"creator"`
+	Track       int    `json:"track"`
+	Disc        int    `json:"disc"`
+	TotalTracks int    `json:"total_tracks"`
+	TotalDiscs  int    `json:"total_discs"`
+}
+
+type templateValue struct {
+	text    string
+	number  int
+	numeric bool
+}
+
+func (v templateValue) empty() bool {
+	if v.numeric {
+		return v.number <= 0
+	}
+	return v.text == ""
+}
+
+func (v templateValue) String() string {
+	if v.numeric {
+		if v.number <= 0 {
+			return ""
+		}
+		return strconv.Itoa(v.number)
+	}
+	return v.text
+}
+
+var templateVariables = map[string]func(d TemplateData) templateValue{
+	"title":        func(d TemplateData) templateValue { return templateValue{text: d.Title} },
+	"artist":       func(d TemplateData) templateValue { return templateValue{text: d.Artist} },
+	"album":        func(d TemplateData) templateValue { return templateValue{text: d.Album} },
+	"album_artist": func(d TemplateData) templateValue { return templateValue{text: d.AlbumArtist} },
+	"playlist":     func(d TemplateData) templateValue { return templateValue{text: d.Playlist} },
+	"creator":      func(d TemplateData) templateValue { return templateValue{text: d.Creator} },
+	"date":         func(d TemplateData) templateValue { return templateValue{text: d.ReleaseDate} },
+	"year":         func(d TemplateData) templateValue { return templateValue{text: releaseYear(d.ReleaseDate)} },
+	"track":        func(d TemplateData) templateValue { return templateValue{number: d.Track, numeric: true} },
+	"disc":         func(d TemplateData) templateValue { return templateValue{number: d.Disc, numeric: true} },
+	"total_tracks": func(d TemplateData) templateValue { return templateValue{number: d.TotalTracks, numeric: true} },
+	"total_discs":  func(d TemplateData) templateValue { return templateValue{number: d.TotalDiscs, numeric: true} },
+}
+
+var templateDefaultWidths = map[string]int{
+	"track": 2,
+}
+
+var templateFunctions = map[string]func(string) string{
+	"first":   GetFirstArtist,
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"caps":    capitalizeWords,
+	"initial": initialLetter,
+}
+
+const VariousArtistsFolder = "Various Artists"
+
+type FolderLayout struct {
+	Template          string
+	DiscFolders       bool
+	DiscFolderFormat  string
+	CompilationFolder bool
+	FirstArtistOnly   bool
+}
+
+var legacyFilenameFormats = map[string]string{
+	"title":        "{title}",
+	"title-artist": "{title} - {artist}",
+	"artist-title": "{artist} - {title}",
+}
+
+var (
+	templateVerbPattern  = regexp.MustCompile(`%(0?)(\d*)([ds%])`)
+	templateDashRunRegex = regexp.MustCompile(`(\s+-)(\s+-)+\s+`)
+)
+
+func releaseYear(date string) string {
+	if len(date) >= 4 {
+		return date[:4]
+	}
+	return ""
+}
+
+func capitalizeWords(s string) string {
+	runes := []rune(strings.ToLower(s))
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			if start {
+				runes[i] = unicode.ToUpper(r)
+			}
+			start = false
+		} else {
+			start = unicode.IsSpace(r) || r == '-' || r == '('
+		}
+	}
+	return string(runes)
+}
+
+func initialLetter(s string) string {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToUpper(r))
+		}
+		if unicode.IsDigit(r) {
+			return "#"
+		}
+	}
+	return ""
+}
+
+func TemplateTrackNumber(position, albumTrackNumber int, useAlbumTrackNumber bool) int {
+	if useAlbumTrackNumber && albumTrackNumber > 0 {
+		return albumTrackNumber
+	}
+	return position
+}
+
+func TemplateDataFromMetadata(metadata *AudioMetadata) TemplateData {
+	if metadata == nil {
+		return TemplateData{}
+	}
+	return TemplateData{
+		Title:       metadata.Title,
+		Artist:      metadata.Artist,
+		Album:       metadata.Album,
+		AlbumArtist: metadata.AlbumArtist,
+		ReleaseDate: metadata.Year,
+		Track:       metadata.TrackNumber,
+		Disc:        metadata.DiscNumber,
+	}
+}
+
+func ResolveFilenameFormat(format string, includeTrackNumber bool) string {
+	if strings.Contains(format, "{") {
+		return format
+	}
+	template, ok := legacyFilenameFormats[format]
+	if !ok {
+		template = legacyFilenameFormats["title-artist"]
+	}
+	if includeTrackNumber {
+		template = "{track}. " + template
+	}
+	return template
+}
+
+func RenderTemplate(template string, data TemplateData) string {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c == '\\' {
+			b.WriteByte('/')
+			continue
+		}
+		if c != '{' {
+			b.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(template[i+1:], '}')
+		if end < 0 {
+			b.WriteString(template[i:])
+			break
+		}
+		token := template[i+1 : i+1+end]
+		if rendered, ok := renderTemplateToken(token, data); ok {
+			b.WriteString(rendered)
+		} else {
+			b.WriteString("{" + token + "}")
+		}
+		i += end + 1
+	}
+
+	segments := strings.Split(b.String(), "/")
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if cleaned := cleanTemplateSegment(segment); cleaned != "" {
+			parts = append(parts, cleaned)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func renderTemplateToken(token string, data TemplateData) (string, bool) {
+	head, spec, _ := strings.Cut(token, ":")
+	pipeline := strings.Split(head, "|")
+
+	name := strings.TrimSpace(pipeline[0])
+	lookup, ok := templateVariables[name]
+	if !ok {
+		return "", false
+	}
+	value := lookup(data)
+
+	for _, step := range pipeline[1:] {
+		step = strings.TrimSpace(step)
+		if fn, ok := templateFunctions[step]; ok {
+			value = templateValue{text: fn(value.String())}
+			continue
+		}
+		fallback, ok := templateVariables[step]
+		if !ok {
+			return "", false
+		}
+		if value.empty() {
+			name = step
+			value = fallback(data)
+		}
+	}
+
+	if value.empty() {
+		return "", true
+	}
+
+	if spec == "" {
+		if width, ok := templateDefaultWidths[name]; ok && value.numeric {
+			return fmt.Sprintf("%0*d", width, value.number), true
+		}
+		return sanitizeTemplateValue(value.String()), true
+	}
+
+	if width, err := strconv.Atoi(spec); err == nil {
+		if value.numeric {
+			return fmt.Sprintf("%0*d", width, value.number), true
+		}
+		return sanitizeTemplateValue(value.String()), true
+	}
+
+	if !strings.Contains(spec, "%") {
+		return spec, true
+	}
+	return templateVerbPattern.ReplaceAllStringFunc(spec, func(verb string) string {
+		m := templateVerbPattern.FindStringSubmatch(verb)
+		if m[3] == "%" {
+			return "%"
+		}
+		if value.numeric && m[3] == "d" {
+			width, _ := strconv.Atoi(m[2])
+			if m[1] == "0" {
+				return fmt.Sprintf("%0*d", width, value.number)
+			}
+			return fmt.Sprintf("%*d", width, value.number)
+		}
+		return sanitizeTemplateValue(value.String())
+	}), true
+}
+
+func sanitizeTemplateValue(value string) string {
+	return strings.NewReplacer("/", " ", "\\", " ").Replace(value)
+}
+
+func cleanTemplateSegment(segment string) string {
+	cleaned := sanitizeName(segment)
+	cleaned = templateDashRunRegex.ReplaceAllString(cleaned, " - ")
+	return strings.Trim(cleaned, " -._")
+}
+
+func BuildTrackFilename(format string, data TemplateData, includeTrackNumber bool, ext string) string {
+	name := RenderTemplate(ResolveFilenameFormat(format, includeTrackNumber), data)
+	if name == "" {
+		name = SanitizeFilename(data.Title)
+	}
+	return filepath.FromSlash(name) + ext
+}
+
+func DownloadTemplateData(title, artist, album, albumArtist, releaseDate, playlistName, playlistOwner string, position, trackNumber, discNumber, totalTracks, totalDiscs int, useAlbumTrackNumber, useFirstArtistOnly bool) TemplateData {
+	if useFirstArtistOnly {
+		artist = GetFirstArtist(artist)
+		albumArtist = GetFirstArtist(albumArtist)
+	}
+	return TemplateData{
+		Title:       title,
+		Artist:      artist,
+		Album:       album,
+		AlbumArtist: albumArtist,
+		ReleaseDate: releaseDate,
+		Playlist:    playlistName,
+		Creator:     playlistOwner,
+		Track:       TemplateTrackNumber(position, trackNumber, useAlbumTrackNumber),
+		Disc:        discNumber,
+		TotalTracks: totalTracks,
+		TotalDiscs:  totalDiscs,
+	}
+}
+
+var SampleTemplateData = TemplateData{
+	Title:       "All The Stars",
+	Artist:      "Kendrick Lamar, SZA",
+	Album:       "Black Panther The Album",
+	AlbumArtist: "Kendrick Lamar",
+	ReleaseDate: "2018-02-09",
+	Playlist:    "Road Trip",
+	Creator:     "Spotify",
+	Track:       1,
+	Disc:        1,
+	TotalTracks: 14,
+	TotalDiscs:  1,
+}
+
+func IsCompilation(albumArtist string) bool {
+	switch strings.ToLower(strings.TrimSpace(albumArtist)) {
+	case "various artists", "various", "va":
+		return true
+	}
+	return false
+}
+
+func BuildFolderPath(layout FolderLayout, data TemplateData) string {
+	if layout.FirstArtistOnly {
+		data.Artist = GetFirstArtist(data.Artist)
+		data.AlbumArtist = GetFirstArtist(data.AlbumArtist)
+	}
+	if data.AlbumArtist == "" {
+		data.AlbumArtist = data.Artist
+	}
+	if layout.CompilationFolder && IsCompilation(data.AlbumArtist) {
+		data.Artist = VariousArtistsFolder
+		data.AlbumArtist = VariousArtistsFolder
+	}
+
+	folder := RenderTemplate(layout.Template, data)
+
+	if layout.DiscFolders && data.TotalDiscs > 1 && data.Disc > 0 {
+		format := layout.DiscFolderFormat
+		if format == "" {
+			format = "CD{disc}"
+		}
+		if disc := RenderTemplate(format, data); disc != "" {
+			folder = path.Join(folder, disc)
+		}
+	}
+
+	return folder
+}
@@ -0,0 +1,72 @@
+package backend
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	data := TemplateData{
+		Title:       "All The Stars",
+		Artist:      "Kendrick Lamar, SZA",
+		Album:       "Black Panther",
+		AlbumArtist: "Kendrick Lamar",
+		ReleaseDate: "2018-02-09",
+		Track:       3,
+		Disc:        2,
+		TotalTracks: 14,
+		TotalDiscs:  2,
+	}
+	single := data
+	single.Disc, single.TotalDiscs = 0, 0
+	noAlbumArtist := data
+	noAlbumArtist.AlbumArtist = ""
+
+	tests := []struct {
+		name     string
+		template string
+		data     TemplateData
+		want     string
+	}{
+		{"plain variables", "{artist} - {title}", data, "Kendrick Lamar, SZA - All The Stars"},
+		{"year from date", "{album} ({year})", data, "Black Panther (2018)"},
+		{"full date", "{date}", data, "2018-02-09"},
+		{"unknown variable kept", "{title} {nope}", data, "All The Stars {nope}"},
+		{"unclosed brace kept", "{title} {artist", data, "All The Stars {artist"},
+
+		{"track default padding", "{track}. {title}", data, "03. All The Stars"},
+		{"explicit width", "{track:3}", data, "003"},
+		{"printf zero padding", "{track:%03d}", data, "003"},
+		{"printf space padding", "{disc:%3d}", data, "2"},
+		{"printf with literal text", "{disc:CD%d}", data, "CD2"},
+		{"literal percent", "{track:%d%%}", data, "3%"},
+		{"width on text value", "{title:5}", data, "All The Stars"},
+
+		{"conditional present", "{disc:Disc }{track}", data, "Disc 03"},
+		{"conditional absent", "{disc:Disc }{track}", single, "03"},
+		{"conditional printf absent", "{title}{disc: (CD%d)}", single, "All The Stars"},
+		{"empty value drops dash", "{album_artist} - {disc} - {title}", single, "Kendrick Lamar - All The Stars"},
+
+		{"fallback unused", "{album_artist|artist}", data, "Kendrick Lamar"},
+		{"fallback used", "{album_artist|artist}", noAlbumArtist, "Kendrick Lamar, SZA"},
+		{"fallback then function", "{album_artist|artist|first}", noAlbumArtist, "Kendrick Lamar"},
+		{"fallback keeps numeric padding", "{disc|track}", single, "03"},
+		{"unknown fallback kept and sanitized", "{album_artist|bogus}", data, "{album_artist bogus}"},
+
+		{"upper", "{title|upper}", data, "ALL THE STARS"},
+		{"lower", "{title|lower}", data, "all the stars"},
+		{"caps", "{title|lower|caps}", data, "All The Stars"},
+		{"initial", "{artist|initial}/{artist}", data, "K/Kendrick Lamar, SZA"},
+
+		{"slash separator", "{album_artist}/{album}", data, "Kendrick Lamar/Black Panther"},
+		{"backslash separator", "{album_artist}\\{album}", data, "Kendrick Lamar/Black Panther"},
+		{"empty segment dropped", "{album_artist}/{disc}/{title}", single, "Kendrick Lamar/All The Stars"},
+		{"slash in value is not a separator", "{title}", TemplateData{Title: "AC/DC"}, "AC DC"},
+		{"backslash in value is not a separator", "{title}", TemplateData{Title: "AC\\DC"}, "AC DC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderTemplate(tt.template, tt.data); got != tt.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
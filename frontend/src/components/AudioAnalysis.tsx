@@ -76,7 +76,7 @@ export function AudioAnalysis({ result, analyzing, onAnalyze, showAnalyzeButton
           <div className="flex items-center gap-1">
             <FileAudio className="h-3 w-3 text-muted-foreground"/>
             <span className="text-muted-foreground">Bit Depth:</span>
-            <span className="font-semibold">{result.bit_depth}</span>
+            <span className="font-semibold">{result.lossy ? "Lossy" : result.bit_depth}</span>
           </div>
           <div className="flex items-center gap-1">
             <Waves className="h-3 w-3 text-muted-foreground"/>
@@ -105,7 +105,7 @@ export function AudioAnalysis({ result, analyzing, onAnalyze, showAnalyzeButton
           <div className="flex items-center gap-1">
             <TrendingUp className="h-3 w-3 text-muted-foreground"/>
             <span className="text-muted-foreground">Dynamic Range:</span>
-            <span className="font-semibold">{formatNumber(result.dynamic_range)} dB</span>
+            <span className="font-semibold">{result.dr ? `DR${result.dr.value}` : `${formatNumber(result.dynamic_range)} dB`}</span>
           </div>
           <div className="flex items-center gap-1">
             <span className="text-muted-foreground">Peak:</span>
@@ -1,18 +1,20 @@
 import { useState, useCallback, useEffect } from "react";
 import { Button } from "@/components/ui/button";
-import { Upload, ArrowLeft, Trash2 } from "lucide-react";
+import { Upload, ArrowLeft, Trash2, ImageDown } from "lucide-react";
 import { AudioAnalysis } from "@/components/AudioAnalysis";
 import { SpectrumVisualization } from "@/components/SpectrumVisualization";
 import { useAudioAnalysis } from "@/hooks/useAudioAnalysis";
 import { SelectFile } from "../../wailsjs/go/main/App";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { OnFileDrop, OnFileDropOff } from "../../wailsjs/runtime/runtime";
+const ExportSpectrogram = (filePath: string, outputPath: string): Promise<string> => (window as any)['go']['main']['App']['ExportSpectrogram'](filePath, outputPath);
 interface AudioAnalysisPageProps {
     onBack?: () => void;
 }
 export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
     const { analyzing, result, analyzeFile, clearResult, selectedFilePath, spectrumLoading } = useAudioAnalysis();
     const [isDragging, setIsDragging] = useState(false);
+    const [exporting, setExporting] = useState(false);
     const handleSelectFile = async () => {
         try {
             const filePath = await SelectFile();
@@ -31,9 +33,9 @@ export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
         if (paths.length === 0)
             return;
         const filePath = paths[0];
-        if (!filePath.toLowerCase().endsWith(".flac")) {
+        if (!/\.(flac|wav|mp3|m4a)$/i.test(filePath)) {
             toast.error("Invalid File Type", {
-                description: "Please drop a FLAC file for analysis",
+                description: "Please drop a FLAC, WAV, MP3 or M4A file for analysis",
             });
             return;
         }
@@ -47,6 +49,23 @@ export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
             OnFileDropOff();
         };
     }, [handleFileDrop]);
+    const handleExportSpectrogram = async () => {
+        if (!selectedFilePath)
+            return;
+        setExporting(true);
+        try {
+            const outputPath = await ExportSpectrogram(selectedFilePath, "");
+            toast.success("Spectrogram Exported", { description: outputPath });
+        }
+        catch (err) {
+            toast.error("Spectrogram Export Failed", {
+                description: err instanceof Error ? err.message : "Failed to export spectrogram",
+            });
+        }
+        finally {
+            setExporting(false);
+        }
+    };
     const handleAnalyzeAnother = () => {
         clearResult();
     };
@@ -59,10 +78,16 @@ export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
             </Button>)}
           <h1 className="text-2xl font-bold">Audio Quality Analyzer</h1>
         </div>
-        {result && (<Button onClick={handleAnalyzeAnother} variant="outline" size="sm">
-            <Trash2 className="h-4 w-4"/>
-            Clear
-          </Button>)}
+        {result && (<div className="flex items-center gap-2">
+            <Button onClick={handleExportSpectrogram} variant="outline" size="sm" disabled={exporting || spectrumLoading}>
+              <ImageDown className="h-4 w-4"/>
+              Export PNG
+            </Button>
+            <Button onClick={handleAnalyzeAnother} variant="outline" size="sm">
+              <Trash2 className="h-4 w-4"/>
+              Clear
+            </Button>
+          </div>)}
       </div>
 
       
@@ -83,12 +108,12 @@ export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
           </div>
           <p className="text-sm text-muted-foreground mb-4 text-center">
             {isDragging
-                ? "Drop your FLAC file here"
-                : "Drag and drop a FLAC file here, or click the button below to select"}
+                ? "Drop your audio file here"
+                : "Drag and drop a FLAC, WAV, MP3 or M4A file here, or click the button below to select"}
           </p>
           <Button onClick={handleSelectFile} size="lg">
             <Upload className="h-5 w-5"/>
-            Select FLAC File
+            Select Audio File
           </Button>
         </div>)}
 
@@ -2,8 +2,13 @@ import { useState, useCallback, useEffect } from "react";
 import { Button } from "@/components/ui/button";
 import { Label } from "@/components/ui/label";
 import { ToggleGroup, ToggleGroupItem, } from "@/components/ui/toggle-group";
-import { Upload, X, CheckCircle2, AlertCircle, Trash2, FileMusic, WandSparkles, } from "lucide-react";
+import { Upload, X, CheckCircle2, AlertCircle, Trash2, FileMusic, WandSparkles, FolderSync, } from "lucide-react";
 import { Spinner } from "@/components/ui/spinner";
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue, } from "@/components/ui/select";
+import { InputWithContext } from "@/components/ui/input-with-context";
+import { Switch } from "@/components/ui/switch";
+import { LibraryMirrorDialog } from "@/components/LibraryMirrorDialog";
+import { getSettings, updateSettings } from "@/lib/settings";
 import { ConvertAudio, SelectAudioFiles, } from "../../wailsjs/go/main/App";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { OnFileDrop, OnFileDropOff } from "../../wailsjs/runtime/runtime";
@@ -34,6 +39,26 @@ const M4A_CODEC_OPTIONS = [
     { value: "aac", label: "AAC" },
     { value: "alac", label: "ALAC" },
 ];
+interface ConvertPreset {
+    id: string;
+    name: string;
+    format: string;
+    mode: string;
+    sample_rate?: number;
+    bit_depth?: number;
+}
+interface ConvertItem {
+    id: string;
+    input_file: string;
+    status: string;
+    progress: number;
+    speed: number;
+}
+const GetConvertPresets = (): Promise<ConvertPreset[]> => (window as any)["go"]["main"]["App"]["GetConvertPresets"]();
+const GetConvertQueue = (): Promise<{
+    queue: ConvertItem[];
+}> => (window as any)["go"]["main"]["App"]["GetConvertQueue"]();
+const CancelConversion = (): Promise<void> => (window as any)["go"]["main"]["App"]["CancelConversion"]();
 const STORAGE_KEY = "spotiflac_audio_converter_state";
 export function AudioConverterPage() {
     const [files, setFiles] = useState<AudioFile[]>(() => {
@@ -93,7 +118,26 @@ export function AudioConverterPage() {
         }
         return "aac";
     });
+    const [preset, setPreset] = useState<string>(() => {
+        try {
+            const saved = sessionStorage.getItem(STORAGE_KEY);
+            if (saved) {
+                const parsed = JSON.parse(saved);
+                if (typeof parsed.preset === "string" && parsed.preset) {
+                    return parsed.preset;
+                }
+            }
+        }
+        catch (err) {
+        }
+        return "custom";
+    });
+    const [presets, setPresets] = useState<ConvertPreset[]>([]);
+    const [onlyIfAbove, setOnlyIfAbove] = useState(true);
+    const [showMirror, setShowMirror] = useState(false);
+    const [outputTemplate, setOutputTemplate] = useState(() => getSettings().convertOutputTemplate || "");
     const [converting, setConverting] = useState(false);
+    const [fileProgress, setFileProgress] = useState<Record<string, number>>({});
     const [isDragging, setIsDragging] = useState(false);
     const [isFullscreen, setIsFullscreen] = useState(false);
     const saveState = useCallback((stateToSave: {
@@ -101,6 +145,7 @@ export function AudioConverterPage() {
         outputFormat: "mp3" | "m4a";
         bitrate: string;
         m4aCodec: "aac" | "alac";
+        preset: string;
     }) => {
         try {
             sessionStorage.setItem(STORAGE_KEY, JSON.stringify(stateToSave));
@@ -110,20 +155,62 @@ export function AudioConverterPage() {
         }
     }, []);
     useEffect(() => {
-        saveState({ files, outputFormat, bitrate, m4aCodec });
-    }, [files, outputFormat, bitrate, m4aCodec, saveState]);
+        saveState({ files, outputFormat, bitrate, m4aCodec, preset });
+    }, [files, outputFormat, bitrate, m4aCodec, preset, saveState]);
+    useEffect(() => {
+        GetConvertPresets()
+            .then((list) => setPresets(list || []))
+            .catch((err) => console.error("Failed to load conversion presets:", err));
+    }, []);
+    useEffect(() => {
+        if (!converting)
+            return;
+        const interval = setInterval(async () => {
+            try {
+                const info = await GetConvertQueue();
+                const next: Record<string, number> = {};
+                for (const item of info.queue || []) {
+                    if (item.status === "converting")
+                        next[item.input_file] = item.progress;
+                }
+                setFileProgress(next);
+            }
+            catch (err) {
+                console.error("Failed to get conversion progress:", err);
+            }
+        }, 500);
+        return () => {
+            clearInterval(interval);
+            setFileProgress({});
+        };
+    }, [converting]);
+    const handleCancel = async () => {
+        try {
+            await CancelConversion();
+        }
+        catch (err) {
+            console.error("Failed to cancel conversion:", err);
+        }
+    };
+    const selectedPreset = presets.find((p) => p.id === preset);
+    const isDownsamplePreset = !!selectedPreset && (!!selectedPreset.sample_rate || !!selectedPreset.bit_depth);
+    const handleTemplateBlur = async () => {
+        if (outputTemplate !== (getSettings().convertOutputTemplate || "")) {
+            await updateSettings({ convertOutputTemplate: outputTemplate });
+        }
+    };
     useEffect(() => {
         if (files.length === 0)
             return;
         const allMP3 = files.every((f) => f.format === "mp3");
-        if (allMP3 && outputFormat !== "m4a") {
+        if (allMP3 && preset === "custom" && outputFormat !== "m4a") {
             setOutputFormat("m4a");
         }
         const hasFlac = files.some((f) => f.format === "flac");
         if (!hasFlac && m4aCodec === "alac") {
             setM4aCodec("aac");
         }
-    }, [files, outputFormat, m4aCodec]);
+    }, [files, outputFormat, m4aCodec, preset]);
     const isFormatDisabled = files.length > 0 && files.every((f) => f.format === "mp3");
     const hasFlacFiles = files.some((f) => f.format === "flac");
     useEffect(() => {
@@ -241,24 +328,28 @@ export function AudioConverterPage() {
                 output_format: outputFormat,
                 bitrate: bitrate,
                 codec: outputFormat === "m4a" ? m4aCodec : "",
+                preset: preset === "custom" ? "" : preset,
+                output_template: outputTemplate,
+                only_if_above: isDownsamplePreset && onlyIfAbove,
             });
             setFiles((prev) => prev.map((f) => {
                 const result = results.find((r) => r.input_file === f.path || r.input_file.toLowerCase() === f.path.toLowerCase());
                 if (result) {
                     return {
                         ...f,
-                        status: result.success ? "success" : "error",
-                        error: result.error,
+                        status: result.success ? "success" : result.error === "cancelled" ? "pending" : "error",
+                        error: result.error === "cancelled" ? undefined : result.error,
                         outputPath: result.output_file,
                     };
                 }
                 return f;
             }));
-            const successCount = results.filter((r) => r.success).length;
+            const skippedCount = results.filter((r) => r.skipped).length;
+            const successCount = results.filter((r) => r.success).length - skippedCount;
             const failCount = results.filter((r) => !r.success).length;
-            if (successCount > 0) {
+            if (successCount > 0 || skippedCount > 0) {
                 toast.success("Conversion Complete", {
-                    description: `Successfully converted ${successCount} file(s)${failCount > 0 ? `, ${failCount} failed` : ""}`,
+                    description: `Successfully converted ${successCount} file(s)${skippedCount > 0 ? `, ${skippedCount} already at target` : ""}${failCount > 0 ? `, ${failCount} failed` : ""}`,
                 });
             }
             else if (failCount > 0) {
@@ -295,7 +386,12 @@ export function AudioConverterPage() {
 
         <div className="flex items-center justify-between">
             <h1 className="text-2xl font-bold">Audio Converter</h1>
-            {files.length > 0 && (<div className="flex gap-2">
+            <div className="flex gap-2">
+                <Button variant="outline" size="sm" onClick={() => setShowMirror(true)} disabled={converting}>
+                    <FolderSync className="h-4 w-4"/>
+                    Mirror Library
+                </Button>
+                {files.length > 0 && (<>
                 <Button variant="outline" size="sm" onClick={handleSelectFiles}>
                     <Upload className="h-4 w-4"/>
                     Add More
@@ -304,9 +400,12 @@ export function AudioConverterPage() {
                     <Trash2 className="h-4 w-4"/>
                     Clear All
                 </Button>
-            </div>)}
+                </>)}
+            </div>
         </div>
 
+        <LibraryMirrorDialog open={showMirror} onOpenChange={setShowMirror} presets={presets}/>
+
 
         <div className={`flex flex-col items-center justify-center border-2 border-dashed rounded-lg transition-all ${isFullscreen ? "flex-1 min-h-[400px]" : "h-[400px]"} ${isDragging
             ? "border-primary bg-primary/10"
@@ -341,6 +440,29 @@ export function AudioConverterPage() {
                 <div className="space-y-2 pb-4 border-b shrink-0">
 
                     <div className="flex items-center gap-4">
+                        <div className="flex items-center gap-2">
+                            <Label className="whitespace-nowrap">Preset:</Label>
+                            <Select value={preset} onValueChange={setPreset}>
+                                <SelectTrigger className="w-[200px]">
+                                    <SelectValue placeholder="Select preset"/>
+                                </SelectTrigger>
+                                <SelectContent>
+                                    <SelectItem value="custom">Custom</SelectItem>
+                                    {presets.map((p) => (<SelectItem key={p.id} value={p.id}>{p.name}</SelectItem>))}
+                                </SelectContent>
+                            </Select>
+                        </div>
+                        {isDownsamplePreset && (<div className="flex items-center gap-2">
+                            <Switch id="only-if-above" checked={onlyIfAbove} onCheckedChange={setOnlyIfAbove}/>
+                            <Label htmlFor="only-if-above" className="whitespace-nowrap">Only if above target</Label>
+                        </div>)}
+                        <div className="flex items-center gap-2 flex-1">
+                            <Label className="whitespace-nowrap">Output:</Label>
+                            <InputWithContext value={outputTemplate} onChange={(e) => setOutputTemplate(e.target.value)} onBlur={handleTemplateBlur} placeholder="{artist}/{album}/{track}. {title} (empty = format subfolder)" className="flex-1"/>
+                        </div>
+                    </div>
+
+                    {preset === "custom" && (<div className="flex items-center gap-4">
                         <div className="flex items-center gap-2">
                             <Label className="whitespace-nowrap">Format:</Label>
                             <ToggleGroup type="single" variant="outline" value={outputFormat} onValueChange={(value) => {
@@ -379,7 +501,7 @@ export function AudioConverterPage() {
                                 </ToggleGroupItem>))}
                             </ToggleGroup>
                         </div>)}
-                    </div>
+                    </div>)}
                 </div>
 
 
@@ -399,6 +521,9 @@ export function AudioConverterPage() {
                                 {file.error}
                             </p>)}
                         </div>
+                        {file.status === "converting" && fileProgress[file.path] !== undefined && (<span className="text-xs text-primary tabular-nums">
+                            {Math.round(fileProgress[file.path])}%
+                        </span>)}
                         <span className="text-xs text-muted-foreground">
                             {formatFileSize(file.size)}
                         </span>
@@ -412,7 +537,7 @@ export function AudioConverterPage() {
                 </div>
 
 
-                <div className="flex justify-center pt-4 border-t shrink-0">
+                <div className="flex justify-center gap-2 pt-4 border-t shrink-0">
                     <Button onClick={handleConvert} disabled={converting || convertableCount === 0} size="lg">
                         {converting ? (<>
                             <Spinner className="h-4 w-4"/>
@@ -422,6 +547,10 @@ export function AudioConverterPage() {
                             Convert {convertableCount > 0 ? `${convertableCount} File(s)` : ""}
                         </>)}
                     </Button>
+                    {converting && (<Button onClick={handleCancel} variant="outline" size="lg">
+                        <X className="h-4 w-4"/>
+                        Cancel
+                    </Button>)}
                 </div>
             </div>)}
         </div>
@@ -4,7 +4,7 @@ import { Label } from "@/components/ui/label";
 import { InputWithContext } from "@/components/ui/input-with-context";
 import { Checkbox } from "@/components/ui/checkbox";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue, } from "@/components/ui/select";
-import { FolderOpen, RefreshCw, FileMusic, ChevronRight, ChevronDown, Pencil, Eye, Folder, Info, RotateCcw, FileText, Image, Copy, Check, } from "lucide-react";
+import { FolderOpen, RefreshCw, FileMusic, ChevronRight, ChevronDown, Pencil, Eye, Folder, Info, RotateCcw, FileText, Image, Copy, Check, Volume2, AudioWaveform, Tags, Wand2, } from "lucide-react";
 import { Tooltip, TooltipTrigger, TooltipContent } from "@/components/ui/tooltip";
 import { Spinner } from "@/components/ui/spinner";
 import { Badge } from "@/components/ui/badge";
@@ -13,13 +13,54 @@ import { backend } from "../../wailsjs/go/models";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { getSettings } from "@/lib/settings";
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
+import { TagFromFilenameDialog } from "@/components/TagFromFilenameDialog";
+import { RetagDialog } from "@/components/RetagDialog";
 const ListDirectoryFiles = (path: string): Promise<backend.FileInfo[]> => (window as any)['go']['main']['App']['ListDirectoryFiles'](path);
-const PreviewRenameFiles = (files: string[], format: string): Promise<backend.RenamePreview[]> => (window as any)['go']['main']['App']['PreviewRenameFiles'](files, format);
-const RenameFilesByMetadata = (files: string[], format: string): Promise<backend.RenameResult[]> => (window as any)['go']['main']['App']['RenameFilesByMetadata'](files, format);
+const PreviewRenameFiles = (files: string[], format: string, strategy: CollisionStrategy): Promise<RenamePreview[]> => (window as any)['go']['main']['App']['PreviewRenameFiles'](files, format, strategy);
+const RenameFilesByMetadata = (files: string[], format: string, strategy: CollisionStrategy): Promise<RenameBatchResult> => (window as any)['go']['main']['App']['RenameFilesByMetadata'](files, format, strategy);
+const UndoRename = (batchId: string): Promise<UndoRenameResult> => (window as any)['go']['main']['App']['UndoRename'](batchId);
 const ReadFileMetadata = (path: string): Promise<backend.AudioMetadata> => (window as any)['go']['main']['App']['ReadFileMetadata'](path);
 const ReadTextFile = (path: string): Promise<string> => (window as any)['go']['main']['App']['ReadTextFile'](path);
-const RenameFileTo = (oldPath: string, newName: string): Promise<void> => (window as any)['go']['main']['App']['RenameFileTo'](oldPath, newName);
+const RenameFileTo = (oldPath: string, newName: string): Promise<string> => (window as any)['go']['main']['App']['RenameFileTo'](oldPath, newName);
 const ReadImageAsBase64 = (path: string): Promise<string> => (window as any)['go']['main']['App']['ReadImageAsBase64'](path);
+const ApplyReplayGain = (files: string[], album: boolean): Promise<string> => (window as any)['go']['main']['App']['ApplyReplayGain'](files, album);
+const ExportSpectrograms = (files: string[], outputDir: string): Promise<SpectrogramExportResult[]> => (window as any)['go']['main']['App']['ExportSpectrograms'](files, outputDir);
+type CollisionStrategy = "disc_track" | "number" | "skip";
+const COLLISION_STRATEGIES: Record<CollisionStrategy, string> = {
+    "disc_track": "Append Disc-Track",
+    "number": "Append (2), (3)...",
+    "skip": "Skip Colliding Files",
+};
+interface RenamePreview extends backend.RenamePreview {
+    collision?: boolean;
+    skipped?: boolean;
+}
+interface RenameBatchResult {
+    batch_id?: string;
+    results: backend.RenameResult[];
+}
+interface UndoRenameResult {
+    batch_id: string;
+    restored: number;
+    conflicts?: {
+        old_path: string;
+        new_path: string;
+        reason: string;
+    }[];
+    errors?: string[];
+}
+interface SpectrogramExportResult {
+    input_file: string;
+    output_file: string;
+    success: boolean;
+    error?: string;
+}
+interface ReplayGainResult {
+    file_path: string;
+    track_gain?: string;
+    album_gain?: string;
+    error?: string;
+}
 interface FileNode {
     name: string;
     path: string;
@@ -101,9 +142,21 @@ export function FileManagerPage() {
         catch { }
         return DEFAULT_CUSTOM_FORMAT;
     });
+    const [collisionStrategy, setCollisionStrategy] = useState<CollisionStrategy>(() => {
+        try {
+            const saved = localStorage.getItem(STORAGE_KEY);
+            if (saved) {
+                const parsed = JSON.parse(saved);
+                if (parsed.collisionStrategy && COLLISION_STRATEGIES[parsed.collisionStrategy as CollisionStrategy])
+                    return parsed.collisionStrategy;
+            }
+        }
+        catch { }
+        return "disc_track";
+    });
     const renameFormat = formatPreset === "custom" ? (customFormat || FORMAT_PRESETS["custom"].template) : FORMAT_PRESETS[formatPreset].template;
     const [showPreview, setShowPreview] = useState(false);
-    const [previewData, setPreviewData] = useState<backend.RenamePreview[]>([]);
+    const [previewData, setPreviewData] = useState<RenamePreview[]>([]);
     const [renaming, setRenaming] = useState(false);
     const [previewOnly, setPreviewOnly] = useState(false);
     const [isFullscreen, setIsFullscreen] = useState(false);
@@ -124,12 +177,16 @@ export function FileManagerPage() {
     const [manualRenameFile, setManualRenameFile] = useState("");
     const [manualRenameName, setManualRenameName] = useState("");
     const [manualRenaming, setManualRenaming] = useState(false);
+    const [applyingReplayGain, setApplyingReplayGain] = useState(false);
+    const [exportingSpectrograms, setExportingSpectrograms] = useState(false);
+    const [showTagFromFilename, setShowTagFromFilename] = useState(false);
+    const [showRetag, setShowRetag] = useState(false);
     useEffect(() => {
         try {
-            localStorage.setItem(STORAGE_KEY, JSON.stringify({ formatPreset, customFormat }));
+            localStorage.setItem(STORAGE_KEY, JSON.stringify({ formatPreset, customFormat, collisionStrategy }));
         }
         catch { }
-    }, [formatPreset, customFormat]);
+    }, [formatPreset, customFormat, collisionStrategy]);
     useEffect(() => {
         const checkFullscreen = () => {
             const isMaximized = window.innerHeight >= window.screen.height * 0.9;
@@ -272,7 +329,7 @@ export function FileManagerPage() {
             return;
         }
         try {
-            const result = await PreviewRenameFiles(Array.from(selectedFiles), renameFormat);
+            const result = await PreviewRenameFiles(Array.from(selectedFiles), renameFormat, collisionStrategy);
             setPreviewData(result);
             setPreviewOnly(isPreviewOnly);
             setShowPreview(true);
@@ -380,13 +437,31 @@ export function FileManagerPage() {
         setManualRenameName(nameWithoutExt);
         setShowManualRename(true);
     };
+    const handleUndoRename = async (batchId: string) => {
+        try {
+            const result = await UndoRename(batchId);
+            if (result.conflicts && result.conflicts.length > 0) {
+                toast.error("Undo Blocked", { description: `${result.conflicts.length} conflict(s): ${result.conflicts[0].reason}` });
+                return;
+            }
+            const errorCount = result.errors?.length || 0;
+            if (errorCount > 0)
+                toast.warning("Undo Incomplete", { description: `${result.restored} file(s) restored, ${errorCount} failed` });
+            else
+                toast.success("Rename Undone", { description: `${result.restored} file(s) restored` });
+            loadFiles();
+        }
+        catch (err) {
+            toast.error("Undo Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+    };
     const handleConfirmManualRename = async () => {
         if (!manualRenameFile || !manualRenameName.trim())
             return;
         setManualRenaming(true);
         try {
-            await RenameFileTo(manualRenameFile, manualRenameName.trim());
-            toast.success("File renamed successfully");
+            const batchId = await RenameFileTo(manualRenameFile, manualRenameName.trim());
+            toast.success("File renamed successfully", batchId ? { action: { label: "Undo", onClick: () => handleUndoRename(batchId) } } : undefined);
             setShowManualRename(false);
             loadFiles();
         }
@@ -402,11 +477,14 @@ export function FileManagerPage() {
             return;
         setRenaming(true);
         try {
-            const result = await RenameFilesByMetadata(Array.from(selectedFiles), renameFormat);
+            const { batch_id, results: result } = await RenameFilesByMetadata(Array.from(selectedFiles), renameFormat, collisionStrategy);
             const successCount = result.filter((r: backend.RenameResult) => r.success).length;
             const failCount = result.filter((r: backend.RenameResult) => !r.success).length;
             if (successCount > 0)
-                toast.success("Rename Complete", { description: `${successCount} file(s) renamed${failCount > 0 ? `, ${failCount} failed` : ""}` });
+                toast.success("Rename Complete", {
+                    description: `${successCount} file(s) renamed${failCount > 0 ? `, ${failCount} failed` : ""}`,
+                    action: batch_id ? { label: "Undo", onClick: () => handleUndoRename(batch_id) } : undefined,
+                });
             else
                 toast.error("Rename Failed", { description: `All ${failCount} file(s) failed to rename` });
             setShowPreview(false);
@@ -420,6 +498,46 @@ export function FileManagerPage() {
             setRenaming(false);
         }
     };
+    const handleReplayGain = async () => {
+        if (selectedFiles.size === 0)
+            return;
+        setApplyingReplayGain(true);
+        try {
+            const result: ReplayGainResult[] = JSON.parse(await ApplyReplayGain(Array.from(selectedFiles), true));
+            const successCount = result.filter((r) => !r.error).length;
+            const failCount = result.length - successCount;
+            if (successCount > 0)
+                toast.success("ReplayGain Applied", { description: `${successCount} file(s) tagged${failCount > 0 ? `, ${failCount} failed` : ""}` });
+            else
+                toast.error("ReplayGain Failed", { description: result[0]?.error || `All ${failCount} file(s) failed` });
+        }
+        catch (err) {
+            toast.error("ReplayGain Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+        finally {
+            setApplyingReplayGain(false);
+        }
+    };
+    const handleExportSpectrograms = async () => {
+        if (selectedFiles.size === 0)
+            return;
+        setExportingSpectrograms(true);
+        try {
+            const result = await ExportSpectrograms(Array.from(selectedFiles), "");
+            const successCount = result.filter((r) => r.success).length;
+            const failCount = result.length - successCount;
+            if (successCount > 0)
+                toast.success("Spectrograms Exported", { description: `${successCount} image(s) saved next to the files${failCount > 0 ? `, ${failCount} failed` : ""}` });
+            else
+                toast.error("Spectrogram Export Failed", { description: result[0]?.error || `All ${failCount} file(s) failed` });
+        }
+        catch (err) {
+            toast.error("Spectrogram Export Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+        finally {
+            setExportingSpectrograms(false);
+        }
+    };
     const renderTrackTree = (nodes: FileNode[], depth = 0) => {
         return nodes.map((node) => (<div key={node.path}>
       <div className={`flex items-center gap-2 py-1.5 px-2 rounded hover:bg-muted/50 cursor-pointer ${selectedFiles.has(node.path) ? "bg-primary/10" : ""}`} style={{ paddingLeft: `${depth * 16 + 8}px` }} onClick={() => (node.is_dir ? toggleExpand(node.path) : toggleSelect(node.path))}>
@@ -561,6 +679,12 @@ export function FileManagerPage() {
           </SelectContent>
         </Select>
         {formatPreset === "custom" && (<InputWithContext value={customFormat} onChange={(e) => setCustomFormat(e.target.value)} placeholder="{artist} - {title}" className="flex-1"/>)}
+        <Select value={collisionStrategy} onValueChange={(value: CollisionStrategy) => setCollisionStrategy(value)}>
+          <SelectTrigger className="w-fit"><SelectValue /></SelectTrigger>
+          <SelectContent>
+            {Object.entries(COLLISION_STRATEGIES).map(([key, label]) => (<SelectItem key={key} value={key}>{label}</SelectItem>))}
+          </SelectContent>
+        </Select>
         <Tooltip>
           <TooltipTrigger asChild>
             <Button variant="ghost" size="icon" onClick={() => setShowResetConfirm(true)}>
@@ -585,6 +709,22 @@ export function FileManagerPage() {
           <span className="text-sm text-muted-foreground">{selectedFiles.size} of {allAudioFiles.length} file(s) selected</span>
         </div>
         <div className="flex items-center gap-2">
+          <Button variant="outline" size="sm" onClick={handleReplayGain} disabled={selectedFiles.size === 0 || loading || applyingReplayGain}>
+            {applyingReplayGain ? <Spinner className="h-4 w-4"/> : <Volume2 className="h-4 w-4"/>}
+            ReplayGain
+          </Button>
+          <Button variant="outline" size="sm" onClick={handleExportSpectrograms} disabled={selectedFiles.size === 0 || loading || exportingSpectrograms}>
+            {exportingSpectrograms ? <Spinner className="h-4 w-4"/> : <AudioWaveform className="h-4 w-4"/>}
+            Spectrograms
+          </Button>
+          <Button variant="outline" size="sm" onClick={() => setShowTagFromFilename(true)} disabled={selectedFiles.size === 0 || loading}>
+            <Tags className="h-4 w-4"/>
+            Tags from Name
+          </Button>
+          <Button variant="outline" size="sm" onClick={() => setShowRetag(true)} disabled={selectedFiles.size === 0 || loading}>
+            <Wand2 className="h-4 w-4"/>
+            Re-tag
+          </Button>
           <Button variant="outline" size="sm" onClick={() => handlePreview(true)} disabled={selectedFiles.size === 0 || loading}>
             <Eye className="h-4 w-4"/>
             Preview
@@ -606,6 +746,10 @@ export function FileManagerPage() {
     </div>
 
 
+    <TagFromFilenameDialog open={showTagFromFilename} onOpenChange={setShowTagFromFilename} files={Array.from(selectedFiles)} onApplied={loadFiles}/>
+    <RetagDialog open={showRetag} onOpenChange={setShowRetag} files={Array.from(selectedFiles)} onApplied={loadFiles}/>
+
+
     <Dialog open={showResetConfirm} onOpenChange={setShowResetConfirm}>
       <DialogContent className="max-w-md [&>button]:hidden">
         <DialogHeader>
@@ -631,6 +775,7 @@ export function FileManagerPage() {
             <div className="text-sm">
               <div className="text-muted-foreground break-all">{item.old_name}</div>
               {item.error ? <div className="text-destructive text-xs mt-1">{item.error}</div> : <div className="text-primary font-medium break-all mt-1">→ {item.new_name}</div>}
+              {item.collision && !item.skipped && <Badge variant="secondary" className="text-xs mt-1">Name collision resolved</Badge>}
             </div>
           </div>))}
         </div>
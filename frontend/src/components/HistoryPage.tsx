@@ -1,14 +1,17 @@
 import { useEffect, useState, useRef } from "react";
 import { Button } from "@/components/ui/button";
-import { Trash2, ExternalLink, Search, ArrowUpDown, History, Play, Pause, Database, CloudUpload, Music2, Disc3, ListMusic, UserRound } from "lucide-react";
+import { Trash2, ExternalLink, Search, ArrowUpDown, History, Play, Pause, Database, CloudUpload, Music2, Disc3, ListMusic, UserRound, BarChart3, X, FileSearch, Download } from "lucide-react";
 import { Badge } from "@/components/ui/badge";
 import { Input } from "@/components/ui/input";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle } from "@/components/ui/dialog";
 import { Pagination, PaginationContent, PaginationEllipsis, PaginationItem, PaginationLink, PaginationNext, PaginationPrevious } from "@/components/ui/pagination";
-import { GetDownloadHistory, ClearDownloadHistory, GetPreviewURL, GetFetchHistory, DeleteDownloadHistoryItem, DeleteFetchHistoryItem, ClearFetchHistoryByType } from "../../wailsjs/go/main/App";
+import { ClearDownloadHistory, GetPreviewURL, GetFetchHistory, DeleteDownloadHistoryItem, DeleteFetchHistoryItem, ClearFetchHistoryByType } from "../../wailsjs/go/main/App";
 import { Tooltip, TooltipContent, TooltipProvider, TooltipTrigger } from "@/components/ui/tooltip";
 import { openExternal } from "@/lib/utils";
+import { Spinner } from "@/components/ui/spinner";
+import { toastWithSound as toast } from "@/lib/toast-with-sound";
+import type { TrackMetadata } from "@/types/api";
 const formatDate = (timestamp: number) => {
     const date = new Date(timestamp * 1000);
     const year = date.getFullYear();
@@ -28,10 +31,93 @@ interface DownloadHistoryItem {
     duration_str: string;
     cover_url: string;
     quality: string;
+    codec?: string;
+    lossy?: boolean;
     format: string;
     path: string;
     timestamp: number;
+    verdict?: string;
+    verdict_note?: string;
+    suspicious?: boolean;
+    provider?: string;
+    album_artist?: string;
+    isrc?: string;
+    file_size?: number;
+    status?: string;
+    error?: string;
+    missing?: boolean;
 }
+interface HistoryQuery {
+    artist?: string;
+    album?: string;
+    format?: string;
+    quality?: string;
+    provider?: string;
+    status?: string;
+    missing?: boolean;
+    from?: number;
+    to?: number;
+    text?: string;
+    sort?: string;
+    offset: number;
+    limit: number;
+}
+interface HistoryQueryResult {
+    items: DownloadHistoryItem[];
+    total: number;
+    offset: number;
+    limit: number;
+}
+interface HistoryStats {
+    total: number;
+    completed: number;
+    failed: number;
+    failure_rate: number;
+    total_bytes: number;
+    hi_res: number;
+    hi_res_share: number;
+    months: {
+        month: string;
+        tracks: number;
+        bytes: number;
+        gb: number;
+    }[];
+    providers: {
+        provider: string;
+        tracks: number;
+        failed: number;
+        bytes: number;
+    }[];
+}
+interface HistoryReconcileResult {
+    checked: number;
+    present: number;
+    scanned: number;
+    relocated: {
+        id: string;
+        title: string;
+        artists: string;
+        old_path: string;
+        new_path: string;
+        matched_by: string;
+    }[];
+    missing: DownloadHistoryItem[];
+}
+const ReconcileDownloadHistory = (extraRoots: string[]): Promise<HistoryReconcileResult> => (window as any)["go"]["main"]["App"]["ReconcileDownloadHistory"](extraRoots);
+const GetMissingHistoryTracks = (ids: string[]): Promise<TrackMetadata[]> => (window as any)["go"]["main"]["App"]["GetMissingHistoryTracks"](ids);
+const QueryDownloadHistory = (query: HistoryQuery): Promise<HistoryQueryResult> => (window as any)["go"]["main"]["App"]["QueryDownloadHistory"](query);
+const GetDownloadHistoryStats = (query: HistoryQuery): Promise<HistoryStats> => (window as any)["go"]["main"]["App"]["GetDownloadHistoryStats"](query);
+const DATE_RANGES: Record<string, number> = {
+    "7d": 7,
+    "30d": 30,
+    "365d": 365,
+};
+const formatBytes = (bytes: number) => {
+    if (bytes >= 1024 * 1024 * 1024)
+        return `${(bytes / (1024 * 1024 * 1024)).toFixed(2)} GB`;
+    return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+};
+const formatPercent = (value: number) => `${(value * 100).toFixed(1)}%`;
 interface FetchHistoryItem {
     id: string;
     url: string;
@@ -44,11 +130,18 @@ interface FetchHistoryItem {
 }
 interface HistoryPageProps {
     onHistorySelect?: (cachedData: string) => void;
+    onRedownload?: (tracks: TrackMetadata[]) => void;
 }
-export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
+export function HistoryPage({ onHistorySelect, onRedownload }: HistoryPageProps) {
     const [activeTab, setActiveTab] = useState("downloads");
     const [downloadHistory, setDownloadHistory] = useState<DownloadHistoryItem[]>([]);
-    const [filteredDownloadHistory, setFilteredDownloadHistory] = useState<DownloadHistoryItem[]>([]);
+    const [downloadTotal, setDownloadTotal] = useState(0);
+    const [downloadStats, setDownloadStats] = useState<HistoryStats | null>(null);
+    const [showDownloadStats, setShowDownloadStats] = useState(false);
+    const [reconciling, setReconciling] = useState(false);
+    const [redownloading, setRedownloading] = useState(false);
+    const [debouncedDownloadQuery, setDebouncedDownloadQuery] = useState("");
+    const [downloadFilters, setDownloadFilters] = useState({ artist: "", album: "", provider: "all", format: "all", quality: "all", status: "all", range: "all" });
     const [showClearDownloadConfirm, setShowClearDownloadConfirm] = useState(false);
     const [downloadSearchQuery, setDownloadSearchQuery] = useState("");
     const [downloadSortBy, setDownloadSortBy] = useState("default");
@@ -62,10 +155,32 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
     const [fetchSearchQuery, setFetchSearchQuery] = useState("");
     const [fetchCurrentPage, setFetchCurrentPage] = useState(1);
     const ITEMS_PER_PAGE = 50;
+    const buildDownloadQuery = (): HistoryQuery => {
+        const days = DATE_RANGES[downloadFilters.range];
+        const value = (v: string) => (v === "all" ? "" : v);
+        return {
+            artist: downloadFilters.artist,
+            album: downloadFilters.album,
+            provider: value(downloadFilters.provider),
+            format: value(downloadFilters.format),
+            quality: value(downloadFilters.quality),
+            status: downloadFilters.status === "missing" ? "" : value(downloadFilters.status),
+            missing: downloadFilters.status === "missing",
+            from: days ? Math.floor(Date.now() / 1000) - days * 86400 : 0,
+            text: debouncedDownloadQuery,
+            sort: downloadSortBy,
+            offset: (downloadCurrentPage - 1) * ITEMS_PER_PAGE,
+            limit: ITEMS_PER_PAGE,
+        };
+    };
     const fetchDownloadHistory = async () => {
         try {
-            const items = await GetDownloadHistory();
-            setDownloadHistory(items || []);
+            const query = buildDownloadQuery();
+            const result = await QueryDownloadHistory(query);
+            setDownloadHistory(result.items || []);
+            setDownloadTotal(result.total);
+            if (showDownloadStats)
+                setDownloadStats(await GetDownloadHistoryStats(query));
         }
         catch (err) {
             console.error("Failed to fetch download history:", err);
@@ -91,7 +206,11 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
             const interval = setInterval(fetchFetchHistory, 5000);
             return () => clearInterval(interval);
         }
-    }, [activeTab]);
+    }, [activeTab, downloadCurrentPage, downloadSortBy, debouncedDownloadQuery, downloadFilters, showDownloadStats]);
+    useEffect(() => {
+        const timer = setTimeout(() => setDebouncedDownloadQuery(downloadSearchQuery.trim()), 300);
+        return () => clearTimeout(timer);
+    }, [downloadSearchQuery]);
     useEffect(() => {
         return () => {
             if (audioRef.current) {
@@ -99,41 +218,9 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
             }
         };
     }, []);
-    useEffect(() => {
-        let result = [...downloadHistory];
-        if (downloadSearchQuery) {
-            const query = downloadSearchQuery.toLowerCase();
-            result = result.filter(item => item.title.toLowerCase().includes(query) ||
-                item.artists.toLowerCase().includes(query) ||
-                item.album.toLowerCase().includes(query));
-        }
-        const parseDuration = (str: string) => {
-            const parts = str.split(':').map(Number);
-            if (parts.length === 2)
-                return parts[0] * 60 + parts[1];
-            if (parts.length === 3)
-                return parts[0] * 3600 + parts[1] * 60 + parts[2];
-            return 0;
-        };
-        result.sort((a, b) => {
-            switch (downloadSortBy) {
-                case "default":
-                case "date_desc": return b.timestamp - a.timestamp;
-                case "date_asc": return a.timestamp - b.timestamp;
-                case "title_asc": return a.title.localeCompare(b.title);
-                case "title_desc": return b.title.localeCompare(a.title);
-                case "artist_asc": return a.artists.localeCompare(b.artists);
-                case "artist_desc": return b.artists.localeCompare(a.artists);
-                case "duration_asc": return parseDuration(a.duration_str) - parseDuration(b.duration_str);
-                case "duration_desc": return parseDuration(b.duration_str) - parseDuration(a.duration_str);
-                default: return 0;
-            }
-        });
-        setFilteredDownloadHistory(result);
-    }, [downloadHistory, downloadSearchQuery, downloadSortBy]);
     useEffect(() => {
         setDownloadCurrentPage(1);
-    }, [downloadSearchQuery, downloadSortBy]);
+    }, [debouncedDownloadQuery, downloadSortBy, downloadFilters]);
     useEffect(() => {
         let result = [...fetchHistory];
         if (activeFetchTab !== "all") {
@@ -181,7 +268,7 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
     };
     const handleDeleteDownloadItem = async (id: string) => {
         await DeleteDownloadHistoryItem(id);
-        setDownloadHistory(prev => prev.filter(item => item.id !== id));
+        fetchDownloadHistory();
     };
     const handleClearFetchHistory = async () => {
         await ClearFetchHistoryByType(activeFetchTab);
@@ -219,24 +306,120 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
         }
         return pages;
     };
+    const handleReconcile = async () => {
+        setReconciling(true);
+        try {
+            const result = await ReconcileDownloadHistory([]);
+            const relocated = result.relocated.length;
+            const missing = result.missing.length;
+            if (relocated === 0 && missing === 0)
+                toast.success("History Up to Date", { description: `All ${result.present.toLocaleString('en-US')} file(s) were found` });
+            else
+                toast.info("History Reconciled", { description: `${relocated} relocated, ${missing} missing${result.scanned > 0 ? ` (${result.scanned} library file(s) scanned)` : ""}` });
+            fetchDownloadHistory();
+        }
+        catch (err) {
+            toast.error("Reconcile Failed", { description: err instanceof Error ? err.message : String(err) });
+        }
+        finally {
+            setReconciling(false);
+        }
+    };
+    const handleRedownloadMissing = async () => {
+        if (!onRedownload)
+            return;
+        setRedownloading(true);
+        try {
+            const tracks = await GetMissingHistoryTracks([]);
+            if (!tracks || tracks.length === 0) {
+                toast.info("Nothing to Re-download", { description: "Run Check Files to find missing downloads" });
+                return;
+            }
+            toast.success("Re-download Started", { description: `${tracks.length} missing track(s) queued` });
+            onRedownload(tracks);
+        }
+        catch (err) {
+            toast.error("Re-download Failed", { description: err instanceof Error ? err.message : String(err) });
+        }
+        finally {
+            setRedownloading(false);
+        }
+    };
+    const setDownloadFilter = (key: keyof typeof downloadFilters, value: string) => {
+        setDownloadFilters(prev => ({ ...prev, [key]: value }));
+    };
+    const renderDownloadStats = () => {
+        if (!downloadStats)
+            return null;
+        const months = downloadStats.months.slice(-12);
+        const maxGB = Math.max(...months.map(m => m.gb), 0.001);
+        return (<div className="rounded-md border p-4 space-y-4">
+                <div className="grid grid-cols-2 md:grid-cols-4 gap-4">
+                    <div>
+                        <p className="text-xs text-muted-foreground uppercase">Downloaded</p>
+                        <p className="text-lg font-semibold">{formatBytes(downloadStats.total_bytes)}</p>
+                        <p className="text-xs text-muted-foreground">{downloadStats.completed.toLocaleString('en-US')} tracks</p>
+                    </div>
+                    <div>
+                        <p className="text-xs text-muted-foreground uppercase">Hi-Res Share</p>
+                        <p className="text-lg font-semibold">{formatPercent(downloadStats.hi_res_share)}</p>
+                        <p className="text-xs text-muted-foreground">{downloadStats.hi_res.toLocaleString('en-US')} hi-res tracks</p>
+                    </div>
+                    <div>
+                        <p className="text-xs text-muted-foreground uppercase">Failure Rate</p>
+                        <p className="text-lg font-semibold">{formatPercent(downloadStats.failure_rate)}</p>
+                        <p className="text-xs text-muted-foreground">{downloadStats.failed.toLocaleString('en-US')} failed</p>
+                    </div>
+                    <div>
+                        <p className="text-xs text-muted-foreground uppercase">Providers</p>
+                        {downloadStats.providers.map(p => (<p key={p.provider} className="text-xs">
+                                <span className="capitalize font-medium">{p.provider}</span>: {p.tracks.toLocaleString('en-US')}{p.failed > 0 && <span className="text-muted-foreground"> ({p.failed} failed)</span>}
+                            </p>))}
+                    </div>
+                </div>
+                {months.length > 0 && (<div className="space-y-1">
+                        <p className="text-xs text-muted-foreground uppercase">GB per Month</p>
+                        {months.map(m => (<div key={m.month} className="flex items-center gap-2 text-xs">
+                                <span className="w-16 font-mono text-muted-foreground">{m.month}</span>
+                                <div className="flex-1 h-2 rounded bg-muted overflow-hidden">
+                                    <div className="h-full bg-primary" style={{ width: `${(m.gb / maxGB) * 100}%` }}/>
+                                </div>
+                                <span className="w-24 text-right font-mono">{m.gb.toFixed(2)} GB</span>
+                            </div>))}
+                    </div>)}
+            </div>);
+    };
     const renderDownloadHistory = () => {
-        const totalPages = Math.ceil(filteredDownloadHistory.length / ITEMS_PER_PAGE);
+        const totalPages = Math.ceil(downloadTotal / ITEMS_PER_PAGE);
         const startIndex = (downloadCurrentPage - 1) * ITEMS_PER_PAGE;
-        const paginated = filteredDownloadHistory.slice(startIndex, startIndex + ITEMS_PER_PAGE);
+        const paginated = downloadHistory;
         return (<div className="space-y-6">
                 <div className="flex flex-col gap-4">
                      <div className="flex items-center justify-between">
                         <div className="flex items-center gap-2">
                              <h2 className="text-xl font-bold tracking-tight">Downloads</h2>
-                             {downloadHistory.length > 0 && (<Badge variant="secondary" className="font-mono">
-                                    {downloadHistory.length.toLocaleString('en-US')}
+                             {downloadTotal > 0 && (<Badge variant="secondary" className="font-mono">
+                                    {downloadTotal.toLocaleString('en-US')}
                                 </Badge>)}
                         </div>
-                        <Button variant="outline" size="sm" onClick={() => setShowClearDownloadConfirm(true)} disabled={downloadHistory.length === 0} className="cursor-pointer gap-2">
-                             <Trash2 className="h-4 w-4"/> Clear All
-                        </Button>
+                        <div className="flex items-center gap-2">
+                            <Button variant="outline" size="sm" onClick={handleReconcile} disabled={reconciling || downloadTotal === 0} className="cursor-pointer gap-2">
+                                {reconciling ? <Spinner className="h-4 w-4"/> : <FileSearch className="h-4 w-4"/>} Check Files
+                            </Button>
+                            {onRedownload && (<Button variant="outline" size="sm" onClick={handleRedownloadMissing} disabled={redownloading} className="cursor-pointer gap-2">
+                                    {redownloading ? <Spinner className="h-4 w-4"/> : <Download className="h-4 w-4"/>} Re-download Missing
+                                </Button>)}
+                            <Button variant={showDownloadStats ? "secondary" : "outline"} size="sm" onClick={() => setShowDownloadStats(!showDownloadStats)} className="cursor-pointer gap-2">
+                                <BarChart3 className="h-4 w-4"/> Stats
+                            </Button>
+                            <Button variant="outline" size="sm" onClick={() => setShowClearDownloadConfirm(true)} disabled={downloadTotal === 0} className="cursor-pointer gap-2">
+                                <Trash2 className="h-4 w-4"/> Clear All
+                            </Button>
+                        </div>
                     </div>
 
+                    {showDownloadStats && renderDownloadStats()}
+
                      <div className="flex items-center gap-2">
                         <div className="relative flex-1">
                             <Search className="absolute left-2 top-2.5 h-4 w-4 text-muted-foreground"/>
@@ -260,6 +443,61 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                             </SelectContent>
                         </Select>
                     </div>
+
+                    <div className="flex flex-wrap items-center gap-2">
+                        <Select value={downloadFilters.provider} onValueChange={(v) => setDownloadFilter("provider", v)}>
+                            <SelectTrigger className="w-[130px] h-8 text-xs"><SelectValue /></SelectTrigger>
+                            <SelectContent>
+                                <SelectItem value="all">All Providers</SelectItem>
+                                <SelectItem value="tidal">Tidal</SelectItem>
+                                <SelectItem value="qobuz">Qobuz</SelectItem>
+                                <SelectItem value="amazon">Amazon</SelectItem>
+                            </SelectContent>
+                        </Select>
+                        <Select value={downloadFilters.format} onValueChange={(v) => setDownloadFilter("format", v)}>
+                            <SelectTrigger className="w-[120px] h-8 text-xs"><SelectValue /></SelectTrigger>
+                            <SelectContent>
+                                <SelectItem value="all">All Formats</SelectItem>
+                                <SelectItem value="flac">FLAC</SelectItem>
+                                <SelectItem value="mp3">MP3</SelectItem>
+                                <SelectItem value="m4a">M4A</SelectItem>
+                            </SelectContent>
+                        </Select>
+                        <Select value={downloadFilters.quality} onValueChange={(v) => setDownloadFilter("quality", v)}>
+                            <SelectTrigger className="w-[130px] h-8 text-xs"><SelectValue /></SelectTrigger>
+                            <SelectContent>
+                                <SelectItem value="all">All Qualities</SelectItem>
+                                <SelectItem value="hi_res">Hi-Res</SelectItem>
+                                <SelectItem value="cd">CD Quality</SelectItem>
+                                <SelectItem value="lossy">Lossy</SelectItem>
+                                <SelectItem value="unknown">Unknown</SelectItem>
+                            </SelectContent>
+                        </Select>
+                        <Select value={downloadFilters.status} onValueChange={(v) => setDownloadFilter("status", v)}>
+                            <SelectTrigger className="w-[130px] h-8 text-xs"><SelectValue /></SelectTrigger>
+                            <SelectContent>
+                                <SelectItem value="all">All Statuses</SelectItem>
+                                <SelectItem value="completed">Completed</SelectItem>
+                                <SelectItem value="failed">Failed</SelectItem>
+                                <SelectItem value="missing">Missing Files</SelectItem>
+                            </SelectContent>
+                        </Select>
+                        <Select value={downloadFilters.range} onValueChange={(v) => setDownloadFilter("range", v)}>
+                            <SelectTrigger className="w-[130px] h-8 text-xs"><SelectValue /></SelectTrigger>
+                            <SelectContent>
+                                <SelectItem value="all">All Time</SelectItem>
+                                <SelectItem value="7d">Last 7 Days</SelectItem>
+                                <SelectItem value="30d">Last 30 Days</SelectItem>
+                                <SelectItem value="365d">Last Year</SelectItem>
+                            </SelectContent>
+                        </Select>
+                        {downloadFilters.artist && (<Badge variant="secondary" className="gap-1 cursor-pointer" onClick={() => setDownloadFilter("artist", "")}>
+                                Artist: {downloadFilters.artist} <X className="h-3 w-3"/>
+                            </Badge>)}
+                        {downloadFilters.album && (<Badge variant="secondary" className="gap-1 cursor-pointer" onClick={() => setDownloadFilter("album", "")}>
+                                Album: {downloadFilters.album} <X className="h-3 w-3"/>
+                            </Badge>)}
+                    </div>
                 </div>
 
                  <div className="rounded-md border overflow-hidden">
@@ -293,12 +531,12 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                                                 <img src={item.cover_url || "https://placehold.co/300?text=No+Cover"} alt={item.album} className="h-10 w-10 rounded shrink-0 bg-secondary object-cover" onError={(e) => { (e.target as HTMLImageElement).src = "https://placehold.co/300?text=No+Cover"; }}/>
                                                 <div className="flex flex-col min-w-0 flex-1">
                                                     <span className="font-medium text-sm truncate">{item.title}</span>
-                                                    <span className="text-xs text-muted-foreground truncate">{item.artists}</span>
+                                                    <span className="text-xs text-muted-foreground truncate cursor-pointer hover:underline" onClick={() => setDownloadFilter("artist", item.artists.split(/[,;]/)[0].trim())}>{item.artists}</span>
                                                 </div>
                                             </div>
                                         </td>
                                         <td className="p-3 align-middle text-sm text-muted-foreground hidden md:table-cell">
-                                            <div className="truncate">{item.album}</div>
+                                            <div className="truncate cursor-pointer hover:underline" onClick={() => setDownloadFilter("album", item.album)}>{item.album}</div>
                                         </td>
                                          <td className="p-3 align-middle text-left hidden lg:table-cell">
                                             <div className="flex flex-col items-start gap-1">
@@ -306,6 +544,36 @@ export function HistoryPage({ onHistorySelect }: HistoryPageProps) {
                                                     {['HI_RES_LOSSLESS', 'LOSSLESS'].includes(item.format) ? 'FLAC' : item.format}
                                                 </span>
                                                 {item.quality && <span className="text-[11px] text-muted-foreground leading-none whitespace-nowrap">{item.quality}</span>}
+                                                {item.missing && (<TooltipProvider>
+                                                    <Tooltip>
+                                                        <TooltipTrigger asChild>
+                                                            <Badge variant="outline" className="text-[10px] px-1.5 py-0 h-4 border-destructive/50 text-destructive">Missing</Badge>
+                                                        </TooltipTrigger>
+                                                        <TooltipContent>
+                                                            <p>{item.path}</p>
+                                                        </TooltipContent>
+                                                    </Tooltip>
+                                                </TooltipProvider>)}
+                                                {item.status === "failed" && (<TooltipProvider>
+                                                    <Tooltip>
+                                                        <TooltipTrigger asChild>
+                                                            <Badge variant="destructive" className="text-[10px] px-1.5 py-0 h-4">Failed</Badge>
+                                                        </TooltipTrigger>
+                                                        <TooltipContent>
+                                                            <p>{item.error}</p>
+                                                        </TooltipContent>
+                                                    </Tooltip>
+                                                </TooltipProvider>)}
+                                                {item.suspicious && (<TooltipProvider>
+                                                    <Tooltip>
+                                                        <TooltipTrigger asChild>
+                                                            <Badge variant="destructive" className="text-[10px] px-1.5 py-0 h-4">{item.verdict === "likely_fake" ? "Likely fake" : "Suspicious"}</Badge>
+                                                        </TooltipTrigger>
+                                                        <TooltipContent>
+                                                            <p>{item.verdict_note}</p>
+                                                        </TooltipContent>
+                                                    </Tooltip>
+                                                </TooltipProvider>)}
                                             </div>
                                         </td>
                                         <td className="p-3 align-middle text-sm text-muted-foreground text-left hidden xl:table-cell font-mono">
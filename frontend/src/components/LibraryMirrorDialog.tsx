@@ -0,0 +1,139 @@
+import { useState } from "react";
+import { Button } from "@/components/ui/button";
+import { Label } from "@/components/ui/label";
+import { InputWithContext } from "@/components/ui/input-with-context";
+import { Spinner } from "@/components/ui/spinner";
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue, } from "@/components/ui/select";
+import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
+import { FolderOpen } from "lucide-react";
+import { SelectFolder } from "../../wailsjs/go/main/App";
+import { getSettings, updateSettings } from "@/lib/settings";
+import { toastWithSound as toast } from "@/lib/toast-with-sound";
+interface MirrorResult {
+    converted: number;
+    copied: number;
+    unchanged: number;
+    sidecars: number;
+    playlists: number;
+    deleted: number;
+    failed?: {
+        input_file: string;
+        error?: string;
+    }[];
+    errors?: string[];
+    cancelled: boolean;
+}
+const MirrorLibrary = (req: {
+    source_root: string;
+    dest_root: string;
+    preset: string;
+}): Promise<MirrorResult> => (window as any)["go"]["main"]["App"]["MirrorLibrary"](req);
+const CancelConversion = (): Promise<void> => (window as any)["go"]["main"]["App"]["CancelConversion"]();
+interface LibraryMirrorDialogProps {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    presets: {
+        id: string;
+        name: string;
+    }[];
+}
+export function LibraryMirrorDialog({ open, onOpenChange, presets }: LibraryMirrorDialogProps) {
+    const [sourceRoot, setSourceRoot] = useState(() => getSettings().mirrorSourceRoot || "");
+    const [destRoot, setDestRoot] = useState(() => getSettings().mirrorDestRoot || "");
+    const [preset, setPreset] = useState(() => getSettings().mirrorPreset || "opus-128-vbr");
+    const [running, setRunning] = useState(false);
+    const [result, setResult] = useState<MirrorResult | null>(null);
+    const pickFolder = async (current: string, set: (path: string) => void) => {
+        try {
+            const path = await SelectFolder(current);
+            if (path)
+                set(path);
+        }
+        catch (err) {
+            toast.error("Folder Selection Failed", {
+                description: err instanceof Error ? err.message : "Failed to select folder",
+            });
+        }
+    };
+    const handleRun = async () => {
+        if (!sourceRoot || !destRoot) {
+            toast.error("Folders Required", { description: "Select both a source and a destination folder" });
+            return;
+        }
+        setRunning(true);
+        setResult(null);
+        try {
+            await updateSettings({ mirrorSourceRoot: sourceRoot, mirrorDestRoot: destRoot, mirrorPreset: preset });
+            const res = await MirrorLibrary({ source_root: sourceRoot, dest_root: destRoot, preset });
+            setResult(res);
+            const failCount = (res.failed?.length || 0) + (res.errors?.length || 0);
+            if (res.cancelled) {
+                toast.info("Mirror Cancelled", { description: `${res.converted} file(s) converted before cancelling` });
+            }
+            else if (failCount > 0) {
+                toast.error("Mirror Finished With Errors", { description: `${failCount} problem(s), ${res.converted} file(s) converted` });
+            }
+            else {
+                toast.success("Mirror Complete", { description: `${res.converted} converted, ${res.unchanged} unchanged, ${res.deleted} removed` });
+            }
+        }
+        catch (err) {
+            toast.error("Mirror Failed", { description: err instanceof Error ? err.message : "Unknown error" });
+        }
+        finally {
+            setRunning(false);
+        }
+    };
+    return (<Dialog open={open} onOpenChange={(value) => !running && onOpenChange(value)}>
+      <DialogContent className="max-w-xl [&>button]:hidden">
+        <DialogHeader>
+          <DialogTitle>Mirror Library</DialogTitle>
+          <DialogDescription>Keep a transcoded copy of a library in sync. Only new or changed files are converted, and files removed from the source are removed from the mirror.</DialogDescription>
+        </DialogHeader>
+        <div className="space-y-4">
+          <div className="space-y-2">
+            <Label>Source</Label>
+            <div className="flex gap-2">
+              <InputWithContext value={sourceRoot} onChange={(e) => setSourceRoot(e.target.value)} placeholder="Lossless library folder" className="flex-1"/>
+              <Button variant="outline" size="icon" onClick={() => pickFolder(sourceRoot, setSourceRoot)} disabled={running}>
+                <FolderOpen className="h-4 w-4"/>
+              </Button>
+            </div>
+          </div>
+          <div className="space-y-2">
+            <Label>Destination</Label>
+            <div className="flex gap-2">
+              <InputWithContext value={destRoot} onChange={(e) => setDestRoot(e.target.value)} placeholder="Portable copy folder" className="flex-1"/>
+              <Button variant="outline" size="icon" onClick={() => pickFolder(destRoot, setDestRoot)} disabled={running}>
+                <FolderOpen className="h-4 w-4"/>
+              </Button>
+            </div>
+          </div>
+          <div className="space-y-2">
+            <Label>Preset</Label>
+            <Select value={preset} onValueChange={setPreset} disabled={running}>
+              <SelectTrigger className="w-full">
+                <SelectValue placeholder="Select preset"/>
+              </SelectTrigger>
+              <SelectContent>
+                {presets.map((p) => (<SelectItem key={p.id} value={p.id}>{p.name}</SelectItem>))}
+              </SelectContent>
+            </Select>
+          </div>
+          {result && (<div className="rounded-lg border p-3 text-sm space-y-1">
+              <p>{result.converted} converted • {result.copied} copied • {result.unchanged} unchanged</p>
+              <p className="text-muted-foreground">{result.sidecars} sidecar(s) • {result.playlists} playlist(s) • {result.deleted} orphan(s) removed</p>
+              {result.failed?.map((f) => (<p key={f.input_file} className="truncate text-xs text-destructive">{f.input_file}: {f.error}</p>))}
+              {result.errors?.map((e) => (<p key={e} className="truncate text-xs text-destructive">{e}</p>))}
+            </div>)}
+        </div>
+        <DialogFooter>
+          {running ? (<Button variant="outline" onClick={() => CancelConversion()}>Cancel</Button>) : (<Button variant="outline" onClick={() => onOpenChange(false)}>Close</Button>)}
+          <Button onClick={handleRun} disabled={running}>
+            {running && <Spinner className="h-4 w-4"/>}
+            {running ? "Mirroring..." : "Start Mirror"}
+          </Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>);
+}
@@ -0,0 +1,185 @@
+import { useEffect, useState } from "react";
+import { Button } from "@/components/ui/button";
+import { Label } from "@/components/ui/label";
+import { Checkbox } from "@/components/ui/checkbox";
+import { Badge } from "@/components/ui/badge";
+import { Spinner } from "@/components/ui/spinner";
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue, } from "@/components/ui/select";
+import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
+import { toastWithSound as toast } from "@/lib/toast-with-sound";
+interface RetagCandidate {
+    spotify_id: string;
+    title: string;
+    artists: string;
+    album: string;
+    album_artist?: string;
+    release_date?: string;
+    track_number?: number;
+    total_tracks?: number;
+    disc_number?: number;
+    total_discs?: number;
+    duration_ms: number;
+    cover_url?: string;
+    copyright?: string;
+    publisher?: string;
+    score: number;
+    title_score: number;
+    artist_score: number;
+    duration_score: number;
+}
+interface RetagProposal {
+    file: string;
+    current?: Record<string, string>;
+    duration_ms: number;
+    source: string;
+    candidates?: RetagCandidate[];
+    matched: boolean;
+    error?: string;
+}
+interface RetagApplyResult {
+    file: string;
+    success: boolean;
+    error?: string;
+}
+const MatchFilesForRetag = (files: string[]): Promise<RetagProposal[]> => (window as any)["go"]["main"]["App"]["MatchFilesForRetag"](files);
+const ApplyRetag = (items: {
+    file: string;
+    candidate: RetagCandidate;
+    cover: boolean;
+}[]): Promise<RetagApplyResult[]> => (window as any)["go"]["main"]["App"]["ApplyRetag"](items);
+const formatDuration = (ms: number) => {
+    if (!ms)
+        return "--:--";
+    const seconds = Math.round(ms / 1000);
+    return `${Math.floor(seconds / 60)}:${String(seconds % 60).padStart(2, "0")}`;
+};
+interface RetagDialogProps {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    files: string[];
+    onApplied: () => void;
+}
+export function RetagDialog({ open, onOpenChange, files, onApplied }: RetagDialogProps) {
+    const [proposals, setProposals] = useState<RetagProposal[]>([]);
+    const [selection, setSelection] = useState<Record<string, number>>({});
+    const [included, setIncluded] = useState<Set<string>>(new Set());
+    const [embedCover, setEmbedCover] = useState(true);
+    const [matching, setMatching] = useState(false);
+    const [applying, setApplying] = useState(false);
+    useEffect(() => {
+        if (!open || files.length === 0)
+            return;
+        let cancelled = false;
+        setProposals([]);
+        setMatching(true);
+        MatchFilesForRetag(files)
+            .then((result) => {
+            if (cancelled)
+                return;
+            setProposals(result);
+            setSelection(Object.fromEntries(result.map((p) => [p.file, 0])));
+            setIncluded(new Set(result.filter((p) => p.matched).map((p) => p.file)));
+        })
+            .catch((err) => {
+            if (!cancelled)
+                toast.error("Matching Failed", { description: err instanceof Error ? err.message : String(err) });
+        })
+            .finally(() => {
+            if (!cancelled)
+                setMatching(false);
+        });
+        return () => {
+            cancelled = true;
+        };
+    }, [open, files.join("\n")]);
+    const toggleIncluded = (file: string, checked: boolean) => {
+        setIncluded((prev) => {
+            const next = new Set(prev);
+            if (checked)
+                next.add(file);
+            else
+                next.delete(file);
+            return next;
+        });
+    };
+    const handleApply = async () => {
+        const items = proposals
+            .filter((p) => included.has(p.file) && p.candidates && p.candidates.length > 0)
+            .map((p) => ({ file: p.file, candidate: p.candidates![selection[p.file] || 0], cover: embedCover }));
+        if (items.length === 0)
+            return;
+        setApplying(true);
+        try {
+            const results = await ApplyRetag(items);
+            const success = results.filter((r) => r.success).length;
+            const failed = results.length - success;
+            if (success > 0)
+                toast.success("Files Re-tagged", { description: `${success} file(s) updated${failed > 0 ? `, ${failed} failed` : ""}` });
+            else
+                toast.error("Re-tag Failed", { description: results.find((r) => r.error)?.error || "No files were updated" });
+            onOpenChange(false);
+            onApplied();
+        }
+        catch (err) {
+            toast.error("Re-tag Failed", { description: err instanceof Error ? err.message : String(err) });
+        }
+        finally {
+            setApplying(false);
+        }
+    };
+    const includedCount = proposals.filter((p) => included.has(p.file) && p.candidates && p.candidates.length > 0).length;
+    return (<Dialog open={open} onOpenChange={(value) => !applying && onOpenChange(value)}>
+      <DialogContent className="max-w-3xl max-h-[80vh] overflow-hidden flex flex-col [&>button]:hidden">
+        <DialogHeader>
+          <DialogTitle>Re-tag from Spotify</DialogTitle>
+          <DialogDescription>Match files by title, artist and duration, then review the proposed tags before writing them.</DialogDescription>
+        </DialogHeader>
+        <div className="flex items-center gap-2">
+          <Checkbox id="retag-embed-cover" checked={embedCover} onCheckedChange={(checked) => setEmbedCover(checked === true)}/>
+          <Label htmlFor="retag-embed-cover" className="text-sm font-normal cursor-pointer">Replace embedded cover art</Label>
+        </div>
+        <div className="flex-1 overflow-y-auto space-y-2 py-2">
+          {matching ? (<div className="flex items-center justify-center gap-2 py-8 text-sm text-muted-foreground">
+              <Spinner className="h-5 w-5"/>
+              Matching {files.length} file(s)...
+            </div>) : proposals.map((proposal) => {
+            const candidates = proposal.candidates || [];
+            const candidate = candidates[selection[proposal.file] || 0];
+            return (<div key={proposal.file} className={`p-3 rounded-lg border text-sm ${proposal.error ? "border-destructive/50 bg-destructive/5" : "border-border"}`}>
+                <div className="flex items-start gap-2">
+                  <Checkbox checked={included.has(proposal.file)} disabled={candidates.length === 0} onCheckedChange={(checked) => toggleIncluded(proposal.file, checked === true)} className="mt-0.5"/>
+                  <div className="flex-1 min-w-0 space-y-1">
+                    <div className="text-muted-foreground break-all">{proposal.file.split(/[/\\]/).pop()}</div>
+                    <div className="text-xs text-muted-foreground">
+                      {proposal.current?.artist || "Unknown artist"} - {proposal.current?.title || "Untitled"} ({formatDuration(proposal.duration_ms)})
+                    </div>
+                    {proposal.error && <div className="text-destructive text-xs">{proposal.error}</div>}
+                    {candidates.length > 0 && (<Select value={String(selection[proposal.file] || 0)} onValueChange={(value) => setSelection((prev) => ({ ...prev, [proposal.file]: Number(value) }))}>
+                        <SelectTrigger className="h-8 text-xs"><SelectValue /></SelectTrigger>
+                        <SelectContent>
+                          {candidates.map((c, index) => (<SelectItem key={`${c.spotify_id}-${index}`} value={String(index)} className="text-xs">
+                              {c.artists} - {c.title} · {c.album} ({formatDuration(c.duration_ms)}) · {Math.round(c.score * 100)}%
+                            </SelectItem>))}
+                        </SelectContent>
+                      </Select>)}
+                    {candidate && (<div className="flex flex-wrap items-center gap-1">
+                        <Badge variant={proposal.matched ? "secondary" : "outline"} className="text-xs">{Math.round(candidate.score * 100)}% match</Badge>
+                        {proposal.source === "album" && <Badge variant="outline" className="text-xs">Album match</Badge>}
+                        {candidate.track_number ? <Badge variant="outline" className="text-xs">Track {candidate.track_number}{candidate.total_tracks ? `/${candidate.total_tracks}` : ""}</Badge> : null}
+                        {candidate.release_date && <Badge variant="outline" className="text-xs">{candidate.release_date}</Badge>}
+                      </div>)}
+                  </div>
+                </div>
+              </div>);
+        })}
+        </div>
+        <DialogFooter>
+          <Button variant="outline" onClick={() => onOpenChange(false)} disabled={applying}>Cancel</Button>
+          <Button onClick={handleApply} disabled={matching || applying || includedCount === 0}>
+            {applying && <Spinner className="h-4 w-4"/>}
+            Re-tag {includedCount} File(s)
+          </Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>);
+}
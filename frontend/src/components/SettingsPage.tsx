@@ -8,10 +8,12 @@ import { Tooltip, TooltipContent, TooltipTrigger, } from "@/components/ui/toolti
 import { FolderOpen, Save, RotateCcw, Info, ArrowRight, Settings, FolderCog, } from "lucide-react";
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
 import { Switch } from "@/components/ui/switch";
-import { getSettings, getSettingsWithDefaults, saveSettings, resetToDefaultSettings, applyThemeMode, applyFont, FONT_OPTIONS, FOLDER_PRESETS, FILENAME_PRESETS, TEMPLATE_VARIABLES, type Settings as SettingsType, type FontFamily, type FolderPreset, type FilenamePreset, } from "@/lib/settings";
+import { getSettings, getSettingsWithDefaults, saveSettings, resetToDefaultSettings, applyThemeMode, applyFont, FONT_OPTIONS, FOLDER_PRESETS, FILENAME_PRESETS, TEMPLATE_VARIABLES, TEMPLATE_SYNTAX, type Settings as SettingsType, type FontFamily, type FolderPreset, type FilenamePreset, } from "@/lib/settings";
 import { themes, applyTheme } from "@/lib/themes";
 import { SelectFolder } from "../../wailsjs/go/main/App";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
+const PreviewFilenameTemplate = (template: string): Promise<string> => (window as any)["go"]["main"]["App"]["PreviewFilenameTemplate"](template);
+const PreviewFolderTemplate = (template: string, discSubfolders: boolean, discFolderFormat: string, compilationFolder: boolean): Promise<string> => (window as any)["go"]["main"]["App"]["PreviewFolderTemplate"](template, discSubfolders, discFolderFormat, compilationFolder);
 const TidalIcon = ({ className }: {
     className?: string;
 }) => (<svg viewBox="0 0 24 24" className={`inline-block w-[1.1em] h-[1.1em] mr-2 ${className || "fill-muted-foreground"}`}>
@@ -39,6 +41,8 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
     const [tempSettings, setTempSettings] = useState<SettingsType>(savedSettings);
     const [isDark, setIsDark] = useState(document.documentElement.classList.contains("dark"));
     const [showResetConfirm, setShowResetConfirm] = useState(false);
+    const [filenamePreview, setFilenamePreview] = useState("");
+    const [folderPreview, setFolderPreview] = useState("");
     const hasUnsavedChanges = JSON.stringify(savedSettings) !== JSON.stringify(tempSettings);
     const resetToSaved = useCallback(() => {
         const freshSavedSettings = getSettings();
@@ -76,6 +80,30 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
             setIsDark(document.documentElement.classList.contains("dark"));
         }, 0);
     }, [tempSettings.themeMode, tempSettings.theme, tempSettings.fontFamily]);
+    useEffect(() => {
+        if (!tempSettings.filenameTemplate) {
+            setFilenamePreview("");
+            return;
+        }
+        const timer = setTimeout(() => {
+            PreviewFilenameTemplate(tempSettings.filenameTemplate)
+                .then(setFilenamePreview)
+                .catch(() => setFilenamePreview(""));
+        }, 200);
+        return () => clearTimeout(timer);
+    }, [tempSettings.filenameTemplate]);
+    useEffect(() => {
+        if (!tempSettings.folderTemplate) {
+            setFolderPreview("");
+            return;
+        }
+        const timer = setTimeout(() => {
+            PreviewFolderTemplate(tempSettings.folderTemplate, tempSettings.discSubfolders, tempSettings.discFolderFormat, tempSettings.compilationFolder)
+                .then(setFolderPreview)
+                .catch(() => setFolderPreview(""));
+        }, 200);
+        return () => clearTimeout(timer);
+    }, [tempSettings.folderTemplate, tempSettings.discSubfolders, tempSettings.discFolderFormat, tempSettings.compilationFolder]);
     useEffect(() => {
         const loadDefaults = async () => {
             if (!savedSettings.downloadPath) {
@@ -451,6 +479,130 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
                     Embed Max Quality Cover
                   </Label>
                 </div>
+                <div className="flex items-center gap-3">
+                  <Label className="text-sm font-normal">Cover Size</Label>
+                  <Select value={String(tempSettings.coverMaxSize)} onValueChange={(value) => setTempSettings((prev) => ({
+                ...prev,
+                coverMaxSize: Number(value),
+            }))}>
+                    <SelectTrigger className="h-9 w-fit">
+                      <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="0">Original</SelectItem>
+                      <SelectItem value="500">500px</SelectItem>
+                      <SelectItem value="800">800px</SelectItem>
+                      <SelectItem value="1000">1000px</SelectItem>
+                      <SelectItem value="1400">1400px</SelectItem>
+                      <SelectItem value="3000">3000px</SelectItem>
+                    </SelectContent>
+                  </Select>
+                  <Select value={tempSettings.coverFormat} onValueChange={(value: "original" | "jpeg" | "png") => setTempSettings((prev) => ({
+                ...prev,
+                coverFormat: value,
+            }))}>
+                    <SelectTrigger className="h-9 w-fit">
+                      <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="original">Original Format</SelectItem>
+                      <SelectItem value="jpeg">JPEG</SelectItem>
+                      <SelectItem value="png">PNG</SelectItem>
+                    </SelectContent>
+                  </Select>
+                  {tempSettings.coverFormat === "jpeg" && (<Select value={String(tempSettings.coverQuality)} onValueChange={(value) => setTempSettings((prev) => ({
+                    ...prev,
+                    coverQuality: Number(value),
+                }))}>
+                      <SelectTrigger className="h-9 w-fit">
+                        <SelectValue />
+                      </SelectTrigger>
+                      <SelectContent>
+                        <SelectItem value="75">Quality 75</SelectItem>
+                        <SelectItem value="85">Quality 85</SelectItem>
+                        <SelectItem value="90">Quality 90</SelectItem>
+                        <SelectItem value="95">Quality 95</SelectItem>
+                        <SelectItem value="100">Quality 100</SelectItem>
+                      </SelectContent>
+                    </Select>)}
+                </div>
+                <div className="flex items-center gap-3">
+                  <Switch id="save-album-cover" checked={tempSettings.saveAlbumCover} onCheckedChange={(checked) => setTempSettings((prev) => ({
+                ...prev,
+                saveAlbumCover: checked,
+            }))}/>
+                  <Label htmlFor="save-album-cover" className="cursor-pointer text-sm font-normal">
+                    Save cover.jpg to Album Folder
+                  </Label>
+                </div>
+                <div className="flex items-center gap-3">
+                  <Switch id="write-artwork-set" checked={tempSettings.writeArtworkSet} onCheckedChange={(checked) => setTempSettings((prev) => ({
+                ...prev,
+                writeArtworkSet: checked,
+            }))}/>
+                  <Label htmlFor="write-artwork-set" className="cursor-pointer text-sm font-normal">
+                    Write Artwork Set (folder.jpg, artist.jpg, backdrop.jpg)
+                  </Label>
+                </div>
+                <div className="flex items-center gap-3">
+                  <Switch id="auto-quality-check" checked={tempSettings.autoQualityCheck} onCheckedChange={(checked) => setTempSettings((prev) => ({
+                ...prev,
+                autoQualityCheck: checked,
+            }))}/>
+                  <Label htmlFor="auto-quality-check" className="cursor-pointer text-sm font-normal">
+                    Check FLAC Quality After Download
+                  </Label>
+                </div>
+                <div className="space-y-2">
+                  <div className="flex items-center gap-2">
+                    <Label className="text-sm font-normal">Cover Source</Label>
+                    <Tooltip>
+                      <TooltipTrigger asChild>
+                        <Info className="h-3.5 w-3.5 text-muted-foreground cursor-help"/>
+                      </TooltipTrigger>
+                      <TooltipContent side="top">
+                        <p className="text-xs max-w-xs">
+                          Other than Spotify, every track download makes 4 extra API calls (Qobuz, Tidal, iTunes, MusicBrainz/Cover Art Archive) plus a range request per candidate image to read its size.
+                        </p>
+                      </TooltipContent>
+                    </Tooltip>
+                  </div>
+                  <Select value={tempSettings.coverSourcePolicy} onValueChange={(value: "spotify" | "largest_square" | "largest") => setTempSettings((prev) => ({
+                ...prev,
+                coverSourcePolicy: value,
+            }))}>
+                    <SelectTrigger className="h-9 w-fit">
+                      <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="spotify">Spotify</SelectItem>
+                      <SelectItem value="largest_square">Largest Square</SelectItem>
+                      <SelectItem value="largest">Largest</SelectItem>
+                    </SelectContent>
+                  </Select>
+                  {tempSettings.coverSourcePolicy !== "spotify" && (<div className="space-y-2">
+                      <InputWithContext value={tempSettings.coverQobuzApiUrl} onChange={(e) => setTempSettings((prev) => ({
+                    ...prev,
+                    coverQobuzApiUrl: e.target.value,
+                }))} placeholder="Qobuz API URL"/>
+                      <InputWithContext value={tempSettings.coverTidalResourcesUrl} onChange={(e) => setTempSettings((prev) => ({
+                    ...prev,
+                    coverTidalResourcesUrl: e.target.value,
+                }))} placeholder="Tidal Resources URL"/>
+                      <InputWithContext value={tempSettings.coverItunesApiUrl} onChange={(e) => setTempSettings((prev) => ({
+                    ...prev,
+                    coverItunesApiUrl: e.target.value,
+                }))} placeholder="iTunes API URL"/>
+                      <InputWithContext value={tempSettings.coverMusicBrainzApiUrl} onChange={(e) => setTempSettings((prev) => ({
+                    ...prev,
+                    coverMusicBrainzApiUrl: e.target.value,
+                }))} placeholder="MusicBrainz API URL"/>
+                      <InputWithContext value={tempSettings.coverArtArchiveUrl} onChange={(e) => setTempSettings((prev) => ({
+                    ...prev,
+                    coverArtArchiveUrl: e.target.value,
+                }))} placeholder="Cover Art Archive URL"/>
+                    </div>)}
+                </div>
               </div>
             </div>
           </div>)}
@@ -500,16 +652,37 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
                 {tempSettings.folderTemplate && (<p className="text-xs text-muted-foreground">
                     Preview:{" "}
                     <span className="font-mono">
-                      {tempSettings.folderTemplate
-                    .replace(/\{artist\}/g, "Kendrick Lamar, SZA")
-                    .replace(/\{album\}/g, "Black Panther")
-                    .replace(/\{album_artist\}/g, "Kendrick Lamar")
-                    .replace(/\{year\}/g, "2018")}
-                      /
+                      {folderPreview}/
                     </span>
                   </p>)}
               </div>
 
+              <div className="space-y-2">
+                <div className="flex items-center gap-3">
+                  <Switch id="disc-subfolders" checked={tempSettings.discSubfolders} onCheckedChange={(checked) => setTempSettings((prev) => ({
+                ...prev,
+                discSubfolders: checked,
+            }))}/>
+                  <Label htmlFor="disc-subfolders" className="text-sm cursor-pointer font-normal">
+                    Disc Subfolders for Multi-Disc Albums
+                  </Label>
+                </div>
+                {tempSettings.discSubfolders && (<InputWithContext value={tempSettings.discFolderFormat} onChange={(e) => setTempSettings((prev) => ({
+                    ...prev,
+                    discFolderFormat: e.target.value,
+                }))} placeholder="CD{disc}" className="h-9 text-sm"/>)}
+              </div>
+
+              <div className="flex items-center gap-3">
+                <Switch id="compilation-folder" checked={tempSettings.compilationFolder} onCheckedChange={(checked) => setTempSettings((prev) => ({
+                ...prev,
+                compilationFolder: checked,
+            }))}/>
+                <Label htmlFor="compilation-folder" className="text-sm cursor-pointer font-normal">
+                  Group Compilations Under Various Artists
+                </Label>
+              </div>
+
               <div className="flex items-center gap-3">
                 <Switch id="create-playlist-folder" checked={tempSettings.createPlaylistFolder} onCheckedChange={(checked) => setTempSettings((prev) => ({
                 ...prev,
@@ -553,6 +726,9 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
                       Variables:{" "}
                       {TEMPLATE_VARIABLES.map((v) => v.key).join(", ")}
                     </p>
+                    {TEMPLATE_SYNTAX.map((v) => (<p key={v.key} className="text-xs whitespace-nowrap">
+                        <span className="font-mono">{v.key}</span> {v.description}
+                      </p>))}
                   </TooltipContent>
                 </Tooltip>
               </div>
@@ -583,16 +759,7 @@ export function SettingsPage({ onUnsavedChangesChange, onResetRequest, }: Settin
               </div>
               {tempSettings.filenameTemplate && (<p className="text-xs text-muted-foreground">
                   Preview:{" "}
-                  <span className="font-mono">
-                    {tempSettings.filenameTemplate
-                    .replace(/\{artist\}/g, "Kendrick Lamar, SZA")
-                    .replace(/\{album_artist\}/g, "Kendrick Lamar")
-                    .replace(/\{title\}/g, "All The Stars")
-                    .replace(/\{track\}/g, "01")
-                    .replace(/\{disc\}/g, "1")
-                    .replace(/\{year\}/g, "2018")}
-                    .flac
-                  </span>
+                  <span className="font-mono">{filenamePreview}</span>
                 </p>)}
             </div>
           </div>)}
@@ -0,0 +1,108 @@
+import { useState } from "react";
+import { Button } from "@/components/ui/button";
+import { Label } from "@/components/ui/label";
+import { InputWithContext } from "@/components/ui/input-with-context";
+import { Checkbox } from "@/components/ui/checkbox";
+import { Spinner } from "@/components/ui/spinner";
+import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
+import { toastWithSound as toast } from "@/lib/toast-with-sound";
+interface TagEditResult {
+    file: string;
+    before?: Record<string, string>;
+    after?: Record<string, string>;
+    changed?: string[];
+    success: boolean;
+    error?: string;
+}
+const PreviewTagsFromFilename = (files: string[], pattern: string, onlyEmpty: boolean): Promise<TagEditResult[]> => (window as any)["go"]["main"]["App"]["PreviewTagsFromFilename"](files, pattern, onlyEmpty);
+const ApplyTagsFromFilename = (files: string[], pattern: string, onlyEmpty: boolean): Promise<TagEditResult[]> => (window as any)["go"]["main"]["App"]["ApplyTagsFromFilename"](files, pattern, onlyEmpty);
+const PATTERN_STORAGE_KEY = "spotiflac_tag_from_filename_pattern";
+interface TagFromFilenameDialogProps {
+    open: boolean;
+    onOpenChange: (open: boolean) => void;
+    files: string[];
+    onApplied: () => void;
+}
+export function TagFromFilenameDialog({ open, onOpenChange, files, onApplied }: TagFromFilenameDialogProps) {
+    const [pattern, setPattern] = useState(() => localStorage.getItem(PATTERN_STORAGE_KEY) || "{track}. {artist} - {title}");
+    const [onlyEmpty, setOnlyEmpty] = useState(true);
+    const [preview, setPreview] = useState<TagEditResult[]>([]);
+    const [loading, setLoading] = useState(false);
+    const [applying, setApplying] = useState(false);
+    const handlePreview = async () => {
+        setLoading(true);
+        try {
+            localStorage.setItem(PATTERN_STORAGE_KEY, pattern);
+            setPreview(await PreviewTagsFromFilename(files, pattern, onlyEmpty));
+        }
+        catch (err) {
+            toast.error("Preview Failed", { description: err instanceof Error ? err.message : String(err) });
+        }
+        finally {
+            setLoading(false);
+        }
+    };
+    const handleApply = async () => {
+        setApplying(true);
+        try {
+            const results = await ApplyTagsFromFilename(files, pattern, onlyEmpty);
+            const tagged = results.filter((r) => r.success && r.changed && r.changed.length > 0).length;
+            const failed = results.filter((r) => !r.success).length;
+            if (tagged > 0)
+                toast.success("Tags Written", { description: `${tagged} file(s) tagged${failed > 0 ? `, ${failed} skipped` : ""}` });
+            else
+                toast.info("No Changes", { description: `${failed} file(s) did not match the pattern` });
+            setPreview([]);
+            onOpenChange(false);
+            onApplied();
+        }
+        catch (err) {
+            toast.error("Tagging Failed", { description: err instanceof Error ? err.message : String(err) });
+        }
+        finally {
+            setApplying(false);
+        }
+    };
+    const changedCount = preview.filter((r) => r.success && r.changed && r.changed.length > 0).length;
+    return (<Dialog open={open} onOpenChange={(value) => !applying && onOpenChange(value)}>
+      <DialogContent className="max-w-2xl max-h-[80vh] overflow-hidden flex flex-col [&>button]:hidden">
+        <DialogHeader>
+          <DialogTitle>Tags from Filename</DialogTitle>
+          <DialogDescription>Read tags out of file and folder names. Use / to match parent folders and {"{*}"} to skip text.</DialogDescription>
+        </DialogHeader>
+        <div className="space-y-3">
+          <div className="space-y-2">
+            <Label>Pattern</Label>
+            <div className="flex gap-2">
+              <InputWithContext value={pattern} onChange={(e) => setPattern(e.target.value)} placeholder="{track}. {artist} - {title}" className="flex-1 font-mono text-sm"/>
+              <Button variant="outline" onClick={handlePreview} disabled={loading || !pattern.trim()}>
+                {loading && <Spinner className="h-4 w-4"/>}
+                Preview
+              </Button>
+            </div>
+          </div>
+          <div className="flex items-center gap-2">
+            <Checkbox id="only-empty-tags" checked={onlyEmpty} onCheckedChange={(checked) => setOnlyEmpty(checked === true)}/>
+            <Label htmlFor="only-empty-tags" className="text-sm font-normal cursor-pointer">Only fill empty tags</Label>
+          </div>
+        </div>
+        <div className="flex-1 overflow-y-auto space-y-2 py-2">
+          {preview.map((item) => (<div key={item.file} className={`p-3 rounded-lg border text-sm ${item.error ? "border-destructive/50 bg-destructive/5" : "border-border"}`}>
+              <div className="text-muted-foreground break-all">{item.file.split(/[/\\]/).pop()}</div>
+              {item.error ? (<div className="text-destructive text-xs mt-1">{item.error}</div>) : item.changed && item.changed.length > 0 ? (<div className="mt-1 space-y-0.5">
+                  {item.changed.map((field) => (<div key={field} className="text-xs">
+                      <span className="font-mono text-muted-foreground">{field}</span>: <span className="text-primary">{item.after?.[field] || "(cleared)"}</span>
+                    </div>))}
+                </div>) : (<div className="text-xs mt-1 text-muted-foreground">No changes</div>)}
+            </div>))}
+        </div>
+        <DialogFooter>
+          <Button variant="outline" onClick={() => onOpenChange(false)} disabled={applying}>Cancel</Button>
+          <Button onClick={handleApply} disabled={applying || changedCount === 0}>
+            {applying && <Spinner className="h-4 w-4"/>}
+            Apply to {changedCount} File(s)
+          </Button>
+        </DialogFooter>
+      </DialogContent>
+    </Dialog>);
+}
@@ -1,6 +1,6 @@
 import { useState, useRef } from "react";
 import { downloadCover } from "@/lib/api";
-import { getSettings, parseTemplate, type TemplateData } from "@/lib/settings";
+import { getSettings } from "@/lib/settings";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { joinPath, sanitizePath } from "@/lib/utils";
 import { logger } from "@/lib/logger";
@@ -14,7 +14,7 @@ export function useCover() {
     const [isBulkDownloadingCovers, setIsBulkDownloadingCovers] = useState(false);
     const [coverDownloadProgress, setCoverDownloadProgress] = useState(0);
     const stopBulkDownloadRef = useRef(false);
-    const handleDownloadCover = async (coverUrl: string, trackName: string, artistName: string, albumName?: string, playlistName?: string, position?: number, trackId?: string, albumArtist?: string, releaseDate?: string, discNumber?: number, isAlbum?: boolean) => {
+    const handleDownloadCover = async (coverUrl: string, trackName: string, artistName: string, albumName?: string, playlistName?: string, position?: number, trackId?: string, albumArtist?: string, releaseDate?: string, discNumber?: number, isAlbum?: boolean, totalDiscs?: number) => {
         if (!coverUrl) {
             toast.error("No cover URL found for this track");
             return;
@@ -27,31 +27,11 @@ export function useCover() {
         try {
             const os = settings.operatingSystem;
             let outputDir = settings.downloadPath;
-            const placeholder = "__SLASH_PLACEHOLDER__";
-            const yearValue = releaseDate?.substring(0, 4);
-            const templateData: TemplateData = {
-                artist: artistName?.replace(/\//g, placeholder),
-                album: albumName?.replace(/\//g, placeholder),
-                title: trackName?.replace(/\//g, placeholder),
-                track: position,
-                year: yearValue,
-                playlist: playlistName?.replace(/\//g, placeholder),
-            };
             const folderTemplate = settings.folderTemplate || "";
             const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
             if (playlistName && (!isAlbum || !useAlbumSubfolder)) {
                 outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
             }
-            if (settings.folderTemplate) {
-                const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                if (folderPath) {
-                    const parts = folderPath.split("/").filter((p: string) => p.trim());
-                    for (const part of parts) {
-                        const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                        outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                    }
-                }
-            }
             const response = await downloadCover({
                 cover_url: coverUrl,
                 track_name: trackName,
@@ -64,6 +44,8 @@ export function useCover() {
                 track_number: settings.trackNumber,
                 position: position || 0,
                 disc_number: discNumber || 0,
+                total_discs: totalDiscs || 0,
+                playlist_name: playlistName,
             });
             if (response.success) {
                 if (response.already_exists) {
@@ -123,33 +105,13 @@ export function useCover() {
             try {
                 const os = settings.operatingSystem;
                 let outputDir = settings.downloadPath;
-                const placeholder = "__SLASH_PLACEHOLDER__";
                 const useAlbumTrackNumber = settings.folderTemplate?.includes("{album}") || false;
                 const trackPosition = useAlbumTrackNumber ? (track.track_number || i + 1) : (i + 1);
-                const yearValue = track.release_date?.substring(0, 4);
-                const templateData: TemplateData = {
-                    artist: track.artists?.replace(/\//g, placeholder),
-                    album: track.album_name?.replace(/\//g, placeholder),
-                    title: track.name?.replace(/\//g, placeholder),
-                    track: trackPosition,
-                    year: yearValue,
-                    playlist: playlistName?.replace(/\//g, placeholder),
-                };
                 const folderTemplate = settings.folderTemplate || "";
                 const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
                 if (playlistName && (!isAlbum || !useAlbumSubfolder)) {
                     outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
                 }
-                if (settings.folderTemplate) {
-                    const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                    if (folderPath) {
-                        const parts = folderPath.split("/").filter((p: string) => p.trim());
-                        for (const part of parts) {
-                            const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                            outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                        }
-                    }
-                }
                 const response = await downloadCover({
                     cover_url: track.images,
                     track_name: track.name,
@@ -162,6 +124,8 @@ export function useCover() {
                     track_number: settings.trackNumber,
                     position: trackPosition,
                     disc_number: track.disc_number,
+                    total_discs: track.total_discs || 0,
+                    playlist_name: playlistName,
                 });
                 if (response.success) {
                     if (response.already_exists) {
@@ -1,6 +1,6 @@
 import { useState, useRef } from "react";
 import { downloadTrack, fetchSpotifyMetadata } from "@/lib/api";
-import { getSettings, parseTemplate, type TemplateData } from "@/lib/settings";
+import { getSettings } from "@/lib/settings";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { joinPath, sanitizePath } from "@/lib/utils";
 import { logger } from "@/lib/logger";
@@ -21,12 +21,16 @@ interface CheckFileExistenceRequest {
     release_date?: string;
     track_number?: number;
     disc_number?: number;
+    total_tracks?: number;
+    total_discs?: number;
+    playlist_name?: string;
     position?: number;
     use_album_track_number?: boolean;
     filename_format?: string;
     include_track_number?: boolean;
     audio_format?: string;
     relative_path?: string;
+    use_first_artist_only?: boolean;
 }
 interface FileExistenceResult {
     spotify_id: string;
@@ -38,6 +42,30 @@ interface FileExistenceResult {
 const CheckFilesExistence = (outputDir: string, rootDir: string, tracks: CheckFileExistenceRequest[]): Promise<FileExistenceResult[]> => (window as any)["go"]["main"]["App"]["CheckFilesExistence"](outputDir, rootDir, tracks);
 const SkipDownloadItem = (itemID: string, filePath: string): Promise<void> => (window as any)["go"]["main"]["App"]["SkipDownloadItem"](itemID, filePath);
 const CreateM3U8File = (playlistName: string, outputDir: string, filePaths: string[]): Promise<void> => (window as any)["go"]["main"]["App"]["CreateM3U8File"](playlistName, outputDir, filePaths);
+interface ArtworkTrack {
+    file_path: string;
+    cover_url: string;
+    artist_id: string;
+}
+const WriteArtworkSet = (req: {
+    tracks: ArtworkTrack[];
+    root_dir: string;
+    embed_max_quality_cover: boolean;
+}): Promise<any> => (window as any)["go"]["main"]["App"]["WriteArtworkSet"](req);
+async function writeArtworkSet(settings: any, tracks: TrackMetadata[], filePaths: string[]) {
+    const artworkTracks = tracks
+        .map((track, i) => ({ file_path: filePaths[i] || "", cover_url: track.images || "", artist_id: track.artist_id || "" }))
+        .filter((t) => t.file_path !== "");
+    if (artworkTracks.length === 0)
+        return;
+    try {
+        logger.info(`writing artwork set for ${artworkTracks.length} tracks`);
+        await WriteArtworkSet({ tracks: artworkTracks, root_dir: settings.downloadPath, embed_max_quality_cover: settings.embedMaxQualityCover || false });
+    }
+    catch (err) {
+        logger.error(`failed to write artwork set: ${err}`);
+    }
+}
 export function useDownload(region: string) {
     const [downloadProgress, setDownloadProgress] = useState<number>(0);
     const [isDownloading, setIsDownloading] = useState(false);
@@ -57,8 +85,7 @@ export function useDownload(region: string) {
         const os = settings.operatingSystem;
         let outputDir = settings.downloadPath;
         let useAlbumTrackNumber = false;
-        const placeholder = "__SLASH_PLACEHOLDER__";
-        let finalReleaseDate = releaseDate;
+        let finalReleaseDate = releaseDate || releaseYear;
         let finalTrackNumber = spotifyTrackNumber || 0;
         if (spotifyId) {
             try {
@@ -76,7 +103,6 @@ export function useDownload(region: string) {
             catch (err) {
             }
         }
-        const yearValue = releaseYear || finalReleaseDate?.substring(0, 4);
         const hasSubfolder = settings.folderTemplate && settings.folderTemplate.trim() !== "";
         const trackNumberForTemplate = (hasSubfolder && finalTrackNumber > 0) ? finalTrackNumber : (position || 0);
         if (hasSubfolder) {
@@ -88,30 +114,11 @@ export function useDownload(region: string) {
         const displayAlbumArtist = settings.useFirstArtistOnly && albumArtist
             ? getFirstArtist(albumArtist)
             : albumArtist;
-        const templateData: TemplateData = {
-            artist: displayArtist?.replace(/\//g, placeholder),
-            album: albumName?.replace(/\//g, placeholder),
-            album_artist: displayAlbumArtist?.replace(/\//g, placeholder) || displayArtist?.replace(/\//g, placeholder),
-            title: trackName?.replace(/\//g, placeholder),
-            track: trackNumberForTemplate,
-            year: yearValue,
-            playlist: playlistName?.replace(/\//g, placeholder),
-        };
         const folderTemplate = settings.folderTemplate || "";
         const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
         if (settings.createPlaylistFolder && playlistName && !useAlbumSubfolder) {
             outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
         }
-        if (settings.folderTemplate) {
-            const folderPath = parseTemplate(settings.folderTemplate, templateData);
-            if (folderPath) {
-                const parts = folderPath.split("/").filter((p: string) => p.trim());
-                for (const part of parts) {
-                    const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                    outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                }
-            }
-        }
         const serviceForCheck = service === "auto" ? "flac" : (service === "tidal" ? "flac" : (service === "qobuz" ? "flac" : "flac"));
         let fileExists = false;
         if (trackName && artistName) {
@@ -125,11 +132,15 @@ export function useDownload(region: string) {
                     release_date: finalReleaseDate || releaseDate,
                     track_number: finalTrackNumber || spotifyTrackNumber || 0,
                     disc_number: spotifyDiscNumber || 0,
+                    total_tracks: spotifyTotalTracks || 0,
+                    total_discs: spotifyTotalDiscs || 0,
+                    playlist_name: playlistName,
                     position: trackNumberForTemplate,
                     use_album_track_number: useAlbumTrackNumber,
                     filename_format: settings.filenameTemplate || "",
                     include_track_number: settings.trackNumber || false,
                     audio_format: serviceForCheck,
+                    use_first_artist_only: settings.useFirstArtistOnly || false,
                 };
                 const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, [checkRequest]);
                 if (existenceResults.length > 0 && existenceResults[0].exists) {
@@ -169,12 +180,15 @@ export function useDownload(region: string) {
             const is24Bit = (settings.autoQuality || "24") === "24";
             const tidalQuality = is24Bit ? "HI_RES_LOSSLESS" : "LOSSLESS";
             const qobuzQuality = is24Bit ? "7" : "6";
+            const attempts = order.filter((s) => (s === "tidal" && streamingURLs?.tidal_url) || (s === "amazon" && streamingURLs?.amazon_url) || s === "qobuz");
+            const lastAttempt = attempts[attempts.length - 1];
             for (const s of order) {
                 if (s === "tidal" && streamingURLs?.tidal_url) {
                     try {
                         logger.debug(`trying tidal for: ${trackName} - ${artistName}`);
                         const response = await downloadTrack({
                             service: "tidal",
+                            fallback_pending: s !== lastAttempt,
                             query,
                             track_name: trackName,
                             artist_name: displayArtist,
@@ -198,6 +212,7 @@ export function useDownload(region: string) {
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
                             spotify_total_discs: spotifyTotalDiscs,
+                            playlist_name: playlistName,
                             copyright: copyright,
                             publisher: publisher,
                             use_first_artist_only: settings.useFirstArtistOnly,
@@ -219,6 +234,7 @@ export function useDownload(region: string) {
                         logger.debug(`trying amazon for: ${trackName} - ${artistName}`);
                         const response = await downloadTrack({
                             service: "amazon",
+                            fallback_pending: s !== lastAttempt,
                             query,
                             track_name: trackName,
                             artist_name: displayArtist,
@@ -240,6 +256,7 @@ export function useDownload(region: string) {
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
                             spotify_total_discs: spotifyTotalDiscs,
+                            playlist_name: playlistName,
                             copyright: copyright,
                             publisher: publisher,
                         });
@@ -260,6 +277,7 @@ export function useDownload(region: string) {
                         logger.debug(`trying qobuz for: ${trackName} - ${artistName}`);
                         const response = await downloadTrack({
                             service: "qobuz",
+                            fallback_pending: s !== lastAttempt,
                             query,
                             track_name: trackName,
                             artist_name: displayArtist,
@@ -281,6 +299,7 @@ export function useDownload(region: string) {
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
                             spotify_total_discs: spotifyTotalDiscs,
+                            playlist_name: playlistName,
                             copyright: copyright,
                             publisher: publisher,
                         });
@@ -335,6 +354,7 @@ export function useDownload(region: string) {
             spotify_disc_number: spotifyDiscNumber,
             spotify_total_tracks: spotifyTotalTracks,
             spotify_total_discs: spotifyTotalDiscs,
+            playlist_name: playlistName,
             copyright: copyright,
             publisher: publisher,
         });
@@ -350,8 +370,7 @@ export function useDownload(region: string) {
         const os = settings.operatingSystem;
         let outputDir = settings.downloadPath;
         let useAlbumTrackNumber = false;
-        const placeholder = "__SLASH_PLACEHOLDER__";
-        let finalReleaseDate = releaseDate;
+        let finalReleaseDate = releaseDate || releaseYear;
         let finalTrackNumber = spotifyTrackNumber || 0;
         if (spotifyId) {
             try {
@@ -369,7 +388,6 @@ export function useDownload(region: string) {
             catch (err) {
             }
         }
-        const yearValue = releaseYear || finalReleaseDate?.substring(0, 4);
         const hasSubfolder = settings.folderTemplate && settings.folderTemplate.trim() !== "";
         const trackNumberForTemplate = (hasSubfolder && finalTrackNumber > 0) ? finalTrackNumber : (position || 0);
         const displayArtist = settings.useFirstArtistOnly && artistName
@@ -378,30 +396,11 @@ export function useDownload(region: string) {
         const displayAlbumArtist = settings.useFirstArtistOnly && albumArtist
             ? getFirstArtist(albumArtist)
             : albumArtist;
-        const templateData: TemplateData = {
-            artist: displayArtist?.replace(/\//g, placeholder),
-            album: albumName?.replace(/\//g, placeholder),
-            album_artist: displayAlbumArtist?.replace(/\//g, placeholder) || displayArtist?.replace(/\//g, placeholder),
-            title: trackName?.replace(/\//g, placeholder),
-            track: trackNumberForTemplate,
-            year: yearValue,
-            playlist: folderName?.replace(/\//g, placeholder),
-        };
         const folderTemplate = settings.folderTemplate || "";
         const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
         if (settings.createPlaylistFolder && folderName && (!isAlbum || !useAlbumSubfolder)) {
             outputDir = joinPath(os, outputDir, sanitizePath(folderName.replace(/\//g, " "), os));
         }
-        if (settings.folderTemplate) {
-            const folderPath = parseTemplate(settings.folderTemplate, templateData);
-            if (folderPath) {
-                const parts = folderPath.split("/").filter(p => p.trim());
-                for (const part of parts) {
-                    const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                    outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                }
-            }
-        }
         if (service === "auto") {
             let streamingURLs: any = null;
             if (spotifyId) {
@@ -420,11 +419,14 @@ export function useDownload(region: string) {
             const is24Bit = (settings.autoQuality || "24") === "24";
             const tidalQuality = is24Bit ? "HI_RES_LOSSLESS" : "LOSSLESS";
             const qobuzQuality = is24Bit ? "7" : "6";
+            const attempts = order.filter((s) => (s === "tidal" && streamingURLs?.tidal_url) || (s === "amazon" && streamingURLs?.amazon_url) || s === "qobuz");
+            const lastAttempt = attempts[attempts.length - 1];
             for (const s of order) {
                 if (s === "tidal" && streamingURLs?.tidal_url) {
                     try {
                         const response = await downloadTrack({
                             service: "tidal",
+                            fallback_pending: s !== lastAttempt,
                             query,
                             track_name: trackName,
                             artist_name: displayArtist,
@@ -448,6 +450,7 @@ export function useDownload(region: string) {
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
                             spotify_total_discs: spotifyTotalDiscs,
+                            playlist_name: folderName,
                             copyright: copyright,
                             publisher: publisher,
                             use_first_artist_only: settings.useFirstArtistOnly,
@@ -466,6 +469,7 @@ export function useDownload(region: string) {
                     try {
                         const response = await downloadTrack({
                             service: "amazon",
+                            fallback_pending: s !== lastAttempt,
                             query,
                             track_name: trackName,
                             artist_name: displayArtist,
@@ -487,6 +491,7 @@ export function useDownload(region: string) {
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
                             spotify_total_discs: spotifyTotalDiscs,
+                            playlist_name: folderName,
                             copyright: copyright,
                             publisher: publisher,
                             use_first_artist_only: settings.useFirstArtistOnly,
@@ -505,6 +510,7 @@ export function useDownload(region: string) {
                     try {
                         const response = await downloadTrack({
                             service: "qobuz",
+                            fallback_pending: s !== lastAttempt,
                             query,
                             track_name: trackName,
                             artist_name: displayArtist,
@@ -527,6 +533,7 @@ export function useDownload(region: string) {
                             spotify_disc_number: spotifyDiscNumber,
                             spotify_total_tracks: spotifyTotalTracks,
                             spotify_total_discs: spotifyTotalDiscs,
+                            playlist_name: folderName,
                             copyright: copyright,
                             publisher: publisher,
                             use_first_artist_only: settings.useFirstArtistOnly,
@@ -580,6 +587,7 @@ export function useDownload(region: string) {
             spotify_disc_number: spotifyDiscNumber,
             spotify_total_tracks: spotifyTotalTracks,
             spotify_total_discs: spotifyTotalDiscs,
+            playlist_name: folderName,
             copyright: copyright,
             publisher: publisher,
         });
@@ -663,11 +671,15 @@ export function useDownload(region: string) {
                 release_date: track.release_date || "",
                 track_number: track.track_number || 0,
                 disc_number: track.disc_number || 0,
+                total_tracks: track.total_tracks || 0,
+                total_discs: track.total_discs || 0,
+                playlist_name: folderName,
                 position: index + 1,
                 use_album_track_number: useAlbumTrackNumber,
                 filename_format: settings.filenameTemplate || "",
                 include_track_number: settings.trackNumber || false,
                 audio_format: audioFormat,
+                use_first_artist_only: settings.useFirstArtistOnly || false,
             };
         });
         const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, existenceChecks);
@@ -783,6 +795,9 @@ export function useDownload(region: string) {
                 }
             }
         }
+        if (settings.writeArtworkSet) {
+            await writeArtworkSet(settings, selectedTrackObjects, selectedTrackObjects.map((t) => finalFilePaths.get(t.spotify_id || "") || ""));
+        }
         logger.info(`batch complete: ${successCount} downloaded, ${skippedCount} skipped, ${errorCount} failed`);
         if (errorCount === 0 && skippedCount === 0) {
             toast.success(`Downloaded ${successCount} tracks successfully`);
@@ -835,11 +850,15 @@ export function useDownload(region: string) {
                 release_date: track.release_date || "",
                 track_number: track.track_number || 0,
                 disc_number: track.disc_number || 0,
+                total_tracks: track.total_tracks || 0,
+                total_discs: track.total_discs || 0,
+                playlist_name: folderName,
                 position: index + 1,
                 use_album_track_number: useAlbumTrackNumber,
                 filename_format: settings.filenameTemplate || "",
                 include_track_number: settings.trackNumber || false,
                 audio_format: audioFormat,
+                use_first_artist_only: settings.useFirstArtistOnly || false,
             };
         });
         const existenceResults = await CheckFilesExistence(outputDir, settings.downloadPath, existenceChecks);
@@ -947,6 +966,9 @@ export function useDownload(region: string) {
                 toast.error(`Failed to create M3U8 playlist: ${err}`);
             }
         }
+        if (settings.writeArtworkSet) {
+            await writeArtworkSet(settings, tracksWithId, finalFilePaths);
+        }
         logger.info(`batch complete: ${successCount} downloaded, ${skippedCount} skipped, ${errorCount} failed`);
         if (errorCount === 0 && skippedCount === 0) {
             toast.success(`Downloaded ${successCount} tracks successfully`);
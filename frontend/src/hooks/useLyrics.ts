@@ -1,6 +1,6 @@
 import { useState, useRef } from "react";
 import { downloadLyrics } from "@/lib/api";
-import { getSettings, parseTemplate, type TemplateData } from "@/lib/settings";
+import { getSettings } from "@/lib/settings";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { joinPath, sanitizePath } from "@/lib/utils";
 import { logger } from "@/lib/logger";
@@ -13,7 +13,7 @@ export function useLyrics() {
     const [isBulkDownloadingLyrics, setIsBulkDownloadingLyrics] = useState(false);
     const [lyricsDownloadProgress, setLyricsDownloadProgress] = useState(0);
     const stopBulkDownloadRef = useRef(false);
-    const handleDownloadLyrics = async (spotifyId: string, trackName: string, artistName: string, albumName?: string, playlistName?: string, position?: number, albumArtist?: string, releaseDate?: string, discNumber?: number, isAlbum?: boolean) => {
+    const handleDownloadLyrics = async (spotifyId: string, trackName: string, artistName: string, albumName?: string, playlistName?: string, position?: number, albumArtist?: string, releaseDate?: string, discNumber?: number, isAlbum?: boolean, totalDiscs?: number) => {
         if (!spotifyId) {
             toast.error("No Spotify ID found for this track");
             return;
@@ -24,31 +24,11 @@ export function useLyrics() {
         try {
             const os = settings.operatingSystem;
             let outputDir = settings.downloadPath;
-            const placeholder = "__SLASH_PLACEHOLDER__";
-            const yearValue = releaseDate?.substring(0, 4);
-            const templateData: TemplateData = {
-                artist: artistName?.replace(/\//g, placeholder),
-                album: albumName?.replace(/\//g, placeholder),
-                title: trackName?.replace(/\//g, placeholder),
-                track: position,
-                year: yearValue,
-                playlist: playlistName?.replace(/\//g, placeholder),
-            };
             const folderTemplate = settings.folderTemplate || "";
             const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
             if (playlistName && (!isAlbum || !useAlbumSubfolder)) {
                 outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
             }
-            if (settings.folderTemplate) {
-                const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                if (folderPath) {
-                    const parts = folderPath.split("/").filter((p: string) => p.trim());
-                    for (const part of parts) {
-                        const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                        outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                    }
-                }
-            }
             const useAlbumTrackNumber = settings.folderTemplate?.includes("{album}") || false;
             const response = await downloadLyrics({
                 spotify_id: spotifyId,
@@ -63,6 +43,8 @@ export function useLyrics() {
                 position: position || 0,
                 use_album_track_number: useAlbumTrackNumber,
                 disc_number: discNumber,
+                total_discs: totalDiscs || 0,
+                playlist_name: playlistName,
             });
             if (response.success) {
                 if (response.already_exists) {
@@ -119,33 +101,13 @@ export function useLyrics() {
             try {
                 const os = settings.operatingSystem;
                 let outputDir = settings.downloadPath;
-                const placeholder = "__SLASH_PLACEHOLDER__";
                 const useAlbumTrackNumber = settings.folderTemplate?.includes("{album}") || false;
                 const trackPosition = useAlbumTrackNumber ? (track.track_number || i + 1) : (i + 1);
-                const yearValue = track.release_date?.substring(0, 4);
-                const templateData: TemplateData = {
-                    artist: track.artists?.replace(/\//g, placeholder),
-                    album: track.album_name?.replace(/\//g, placeholder),
-                    title: track.name?.replace(/\//g, placeholder),
-                    track: trackPosition,
-                    year: yearValue,
-                    playlist: playlistName?.replace(/\//g, placeholder),
-                };
                 const folderTemplate = settings.folderTemplate || "";
                 const useAlbumSubfolder = folderTemplate.includes("{album}") || folderTemplate.includes("{album_artist}") || folderTemplate.includes("{playlist}");
                 if (playlistName && (!isAlbum || !useAlbumSubfolder)) {
                     outputDir = joinPath(os, outputDir, sanitizePath(playlistName.replace(/\//g, " "), os));
                 }
-                if (settings.folderTemplate) {
-                    const folderPath = parseTemplate(settings.folderTemplate, templateData);
-                    if (folderPath) {
-                        const parts = folderPath.split("/").filter((p: string) => p.trim());
-                        for (const part of parts) {
-                            const sanitizedPart = part.replace(new RegExp(placeholder, "g"), " ");
-                            outputDir = joinPath(os, outputDir, sanitizePath(sanitizedPart, os));
-                        }
-                    }
-                }
                 const response = await downloadLyrics({
                     spotify_id: id,
                     track_name: track.name,
@@ -159,6 +121,8 @@ export function useLyrics() {
                     position: trackPosition,
                     use_album_track_number: useAlbumTrackNumber,
                     disc_number: track.disc_number,
+                    total_discs: track.total_discs || 0,
+                    playlist_name: playlistName,
                 });
                 if (response.success) {
                     if (response.already_exists) {
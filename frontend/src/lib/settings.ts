@@ -1,6 +1,6 @@
 import { GetDefaults, LoadSettings, SaveSettings as SaveToBackend } from "../../wailsjs/go/main/App";
 export type FontFamily = "google-sans" | "inter" | "poppins" | "roboto" | "dm-sans" | "plus-jakarta-sans" | "manrope" | "space-grotesk" | "noto-sans" | "nunito-sans" | "figtree" | "raleway" | "public-sans" | "outfit" | "jetbrains-mono" | "geist-sans" | "bricolage-grotesque";
-export type FolderPreset = "none" | "artist" | "album" | "year-album" | "year-artist-album" | "artist-album" | "artist-year-album" | "artist-year-nested-album" | "album-artist" | "album-artist-album" | "album-artist-year-album" | "album-artist-year-nested-album" | "year" | "year-artist" | "custom";
+export type FolderPreset = "none" | "artist" | "album" | "year-album" | "year-artist-album" | "artist-album" | "artist-year-album" | "artist-year-nested-album" | "album-artist" | "album-artist-album" | "album-artist-year-album" | "album-artist-year-nested-album" | "album-artist-year-dash-album" | "year" | "year-artist" | "custom";
 export type FilenamePreset = "title" | "title-artist" | "artist-title" | "track-title" | "track-title-artist" | "track-artist-title" | "title-album-artist" | "track-title-album-artist" | "artist-album-title" | "track-dash-title" | "disc-track-title" | "disc-track-title-artist" | "custom";
 export interface Settings {
     downloadPath: string;
@@ -31,6 +31,27 @@ export interface Settings {
     createPlaylistFolder: boolean;
     createM3u8File: boolean;
     useFirstArtistOnly: boolean;
+    coverMaxSize: number;
+    coverFormat: "original" | "jpeg" | "png";
+    coverQuality: number;
+    saveAlbumCover: boolean;
+    coverSourcePolicy: "spotify" | "largest_square" | "largest";
+    coverQobuzApiUrl: string;
+    coverTidalResourcesUrl: string;
+    coverItunesApiUrl: string;
+    coverMusicBrainzApiUrl: string;
+    coverArtArchiveUrl: string;
+    writeArtworkSet: boolean;
+    autoQualityCheck: boolean;
+    replayGainAfterDownload: boolean;
+    convertOutputTemplate: string;
+    convertWorkers: number;
+    mirrorSourceRoot: string;
+    mirrorDestRoot: string;
+    mirrorPreset: string;
+    discSubfolders: boolean;
+    discFolderFormat: string;
+    compilationFolder: boolean;
 }
 export const FOLDER_PRESETS: Record<FolderPreset, {
     label: string;
@@ -48,6 +69,7 @@ export const FOLDER_PRESETS: Record<FolderPreset, {
     "album-artist-album": { label: "Album Artist / Album", template: "{album_artist}/{album}" },
     "album-artist-year-album": { label: "Album Artist / [Year] Album", template: "{album_artist}/[{year}] {album}" },
     "album-artist-year-nested-album": { label: "Album Artist / Year / Album", template: "{album_artist}/{year}/{album}" },
+    "album-artist-year-dash-album": { label: "Album Artist / Year - Album", template: "{album_artist}/{year} - {album}" },
     "year": { label: "Year", template: "{year}" },
     "year-artist": { label: "Year / Artist", template: "{year}/{artist}" },
     "custom": { label: "Custom...", template: "{artist}/{album}" },
@@ -78,6 +100,18 @@ export const TEMPLATE_VARIABLES = [
     { key: "{track}", description: "Track number", example: "01" },
     { key: "{disc}", description: "Disc number", example: "1" },
     { key: "{year}", description: "Release year", example: "2014" },
+    { key: "{date}", description: "Release date", example: "2014-10-27" },
+    { key: "{playlist}", description: "Playlist name", example: "Road Trip" },
+    { key: "{creator}", description: "Playlist creator", example: "Spotify" },
+    { key: "{total_tracks}", description: "Tracks on the album", example: "13" },
+    { key: "{total_discs}", description: "Discs in the album", example: "1" },
+];
+export const TEMPLATE_SYNTAX = [
+    { key: "{track:03}", description: "zero-pad to 3 digits" },
+    { key: "{disc:Disc %d/}", description: "only when set, %d is the value" },
+    { key: "{artist|first}", description: "first artist (also upper, lower, caps, initial)" },
+    { key: "{album_artist|artist}", description: "fall back to artist when empty" },
+    { key: "/", description: "start a subfolder" },
 ];
 function detectOS(): "Windows" | "linux/MacOS" {
     const platform = window.navigator.platform.toLowerCase();
@@ -111,7 +145,28 @@ export const DEFAULT_SETTINGS: Settings = {
     spotFetchAPIUrl: "https://spotify.afkarxyz.fun/api",
     createPlaylistFolder: true,
     createM3u8File: false,
-    useFirstArtistOnly: false
+    useFirstArtistOnly: false,
+    coverMaxSize: 0,
+    coverFormat: "original",
+    coverQuality: 90,
+    saveAlbumCover: false,
+    coverSourcePolicy: "spotify",
+    coverQobuzApiUrl: "https://www.qobuz.com/api.json/0.2",
+    coverTidalResourcesUrl: "https://resources.tidal.com/images",
+    coverItunesApiUrl: "https://itunes.apple.com",
+    coverMusicBrainzApiUrl: "https://musicbrainz.org/ws/2",
+    coverArtArchiveUrl: "https://coverartarchive.org",
+    writeArtworkSet: false,
+    autoQualityCheck: true,
+    replayGainAfterDownload: false,
+    convertOutputTemplate: "",
+    convertWorkers: 0,
+    mirrorSourceRoot: "",
+    mirrorDestRoot: "",
+    mirrorPreset: "opus-128-vbr",
+    discSubfolders: false,
+    discFolderFormat: "CD{disc}",
+    compilationFolder: false
 };
 export const FONT_OPTIONS: {
     value: FontFamily;
@@ -326,30 +381,6 @@ export async function loadSettings(): Promise<Settings> {
     }
     return local;
 }
-export interface TemplateData {
-    artist?: string;
-    album?: string;
-    album_artist?: string;
-    title?: string;
-    track?: number;
-    disc?: number;
-    year?: string;
-    playlist?: string;
-}
-export function parseTemplate(template: string, data: TemplateData): string {
-    if (!template)
-        return "";
-    let result = template;
-    result = result.replace(/\{title\}/g, data.title || "Unknown Title");
-    result = result.replace(/\{artist\}/g, data.artist || "Unknown Artist");
-    result = result.replace(/\{album\}/g, data.album || "Unknown Album");
-    result = result.replace(/\{album_artist\}/g, data.album_artist || data.artist || "Unknown Artist");
-    result = result.replace(/\{track\}/g, data.track ? String(data.track).padStart(2, "0") : "00");
-    result = result.replace(/\{disc\}/g, data.disc ? String(data.disc) : "1");
-    result = result.replace(/\{year\}/g, data.year || "0000");
-    result = result.replace(/\{playlist\}/g, data.playlist || "");
-    return result;
-}
 export async function getSettingsWithDefaults(): Promise<Settings> {
     const settings = await loadSettings();
     if (!settings.downloadPath) {
@@ -120,6 +120,7 @@ export interface DownloadRequest {
     output_dir?: string;
     audio_format?: string;
     folder_name?: string;
+    playlist_name?: string;
     filename_format?: string;
     track_number?: boolean;
     position?: number;
@@ -138,6 +139,7 @@ export interface DownloadRequest {
     publisher?: string;
     spotify_url?: string;
     use_first_artist_only?: boolean;
+    fallback_pending?: boolean;
 }
 export interface DownloadResponse {
     success: boolean;
@@ -171,10 +173,19 @@ export interface AnalysisResult {
     total_samples: number;
     duration: number;
     bit_depth: string;
+    lossy?: boolean;
     dynamic_range: number;
     peak_amplitude: number;
     rms_level: number;
     spectrum?: SpectrumData;
+    dr?: DynamicRangeResult;
+    codec?: string;
+}
+export interface DynamicRangeResult {
+    value: number;
+    raw: number;
+    channels: number[];
+    blocks: number;
 }
 export interface LyricsDownloadRequest {
     spotify_id: string;
@@ -189,6 +200,8 @@ export interface LyricsDownloadRequest {
     position?: number;
     use_album_track_number?: boolean;
     disc_number?: number;
+    total_discs?: number;
+    playlist_name?: string;
 }
 export interface LyricsDownloadResponse {
     success: boolean;
@@ -218,6 +231,8 @@ export interface CoverDownloadRequest {
     track_number?: boolean;
     position?: number;
     disc_number?: number;
+    total_discs?: number;
+    playlist_name?: string;
 }
 export interface CoverDownloadResponse {
     success: boolean;